@@ -0,0 +1,198 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines shown around the changes in a hunk of a diff.
+const diffContext = 3
+
+// edit is a line of a diff, which is kept (' '), removed ('-') or added ('+').
+type edit struct {
+	kind byte
+	line string
+}
+
+// unifiedDiff returns a unified diff of the changes from the old text to the new, under a header naming them.
+func unifiedDiff(oldName, newName, oldText, newText string) string {
+	edits := lineEdits(splitLines(oldText), splitLines(newText))
+	// oldLines and newLines hold the number of old and new lines before each edit.
+	oldLines, newLines := make([]int, len(edits)+1), make([]int, len(edits)+1)
+	for i, e := range edits {
+		oldLines[i+1], newLines[i+1] = oldLines[i], newLines[i]
+		if e.kind != '+' {
+			oldLines[i+1]++
+		}
+		if e.kind != '-' {
+			newLines[i+1]++
+		}
+	}
+	out := new(strings.Builder)
+	_, _ = fmt.Fprintf(out, "--- %s\n+++ %s\n", oldName, newName)
+	for next := 0; ; {
+		first := nextChange(edits, next)
+		if first < 0 {
+			break
+		}
+		// A hunk takes in any change close enough to the last that their context would meet.
+		last := first
+		for change := nextChange(edits, last+1); change >= 0 && change-last <= 2*diffContext; {
+			last = change
+			change = nextChange(edits, last+1)
+		}
+		start, end := max(next, first-diffContext), min(len(edits), last+1+diffContext)
+		_, _ = fmt.Fprintf(
+			out,
+			"@@ -%s +%s @@\n",
+			hunkRange(oldLines[start], oldLines[end]),
+			hunkRange(newLines[start], newLines[end]),
+		)
+		for _, e := range edits[start:end] {
+			out.WriteByte(e.kind)
+			out.WriteString(e.line)
+			if !strings.HasSuffix(e.line, "\n") {
+				out.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		next = end
+	}
+
+	return out.String()
+}
+
+// splitLines returns the lines of the text, each with its newline (which the last may not have).
+func splitLines(text string) []string {
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	return lines
+}
+
+// lineEdits returns the edits that change the old lines to the new, keeping the longest common subsequence of them.
+//
+// The edits are found with the linear space refinement of Myers' algorithm, so the memory used is proportional to the
+// number of lines rather than their product.
+func lineEdits(oldLines, newLines []string) []edit {
+	edits := make([]edit, 0, len(oldLines)+len(newLines))
+
+	return appendLineEdits(edits, oldLines, newLines)
+}
+
+// appendLineEdits appends the edits that change the old lines to the new to edits.
+func appendLineEdits(edits []edit, oldLines, newLines []string) []edit {
+	prefix := 0
+	for prefix < len(oldLines) && prefix < len(newLines) && oldLines[prefix] == newLines[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(oldLines)-prefix && suffix < len(newLines)-prefix &&
+		oldLines[len(oldLines)-1-suffix] == newLines[len(newLines)-1-suffix] {
+		suffix++
+	}
+	for _, line := range oldLines[:prefix] {
+		edits = append(edits, edit{kind: ' ', line: line})
+	}
+	oldMiddle, newMiddle := oldLines[prefix:len(oldLines)-suffix], newLines[prefix:len(newLines)-suffix]
+	switch {
+	case len(oldMiddle) == 0:
+		for _, line := range newMiddle {
+			edits = append(edits, edit{kind: '+', line: line})
+		}
+	case len(newMiddle) == 0:
+		for _, line := range oldMiddle {
+			edits = append(edits, edit{kind: '-', line: line})
+		}
+	default:
+		// With the common prefix and suffix removed at least two edits are needed, so the middle snake splits the lines
+		// into two smaller problems.
+		x, y, u, v := middleSnake(oldMiddle, newMiddle)
+		edits = appendLineEdits(edits, oldMiddle[:x], newMiddle[:y])
+		for _, line := range oldMiddle[x:u] {
+			edits = append(edits, edit{kind: ' ', line: line})
+		}
+		edits = appendLineEdits(edits, oldMiddle[u:], newMiddle[v:])
+	}
+	for _, line := range oldLines[len(oldLines)-suffix:] {
+		edits = append(edits, edit{kind: ' ', line: line})
+	}
+
+	return edits
+}
+
+// middleSnake returns the middle snake of a shortest edit script from the old lines to the new, which is the run of
+// common lines from (x, y) to (u, v) where the paths searched forwards from the start and backwards from the end meet.
+func middleSnake(oldLines, newLines []string) (x, y, u, v int) {
+	n, m := len(oldLines), len(newLines)
+	delta := n - m
+	odd := delta%2 != 0
+	maxD := (n + m + 1) / 2
+	// forward and backward hold the furthest x reached on each diagonal k (at index k+offset), with the backward x
+	// counted from the end of the lines.
+	offset := maxD + 1
+	forward, backward := make([]int, 2*offset+1), make([]int, 2*offset+1)
+	for d := 0; d <= maxD; d++ {
+		for k := -d; k <= d; k += 2 {
+			x, y, u, v := furthestPath(forward, offset, d, k, func(x, y int) bool {
+				return x < n && y < m && oldLines[x] == newLines[y]
+			})
+			if odd && delta-k >= -(d-1) && delta-k <= d-1 && u+backward[offset+delta-k] >= n {
+				return x, y, u, v
+			}
+		}
+		for k := -d; k <= d; k += 2 {
+			x, y, u, v := furthestPath(backward, offset, d, k, func(x, y int) bool {
+				return x < n && y < m && oldLines[n-1-x] == newLines[m-1-y]
+			})
+			if !odd && delta-k >= -d && delta-k <= d && u+forward[offset+delta-k] >= n {
+				return n - u, m - v, n - x, m - y
+			}
+		}
+	}
+
+	// The paths always meet by maxD.
+	return 0, 0, 0, 0
+}
+
+// furthestPath extends the furthest path on diagonal k with d edits by one edit from a neighbouring diagonal and then
+// along the following snake of lines for which same is true, recording its end in furthest and returning the start
+// (x, y) and end (u, v) of the snake.
+func furthestPath(furthest []int, offset, d, k int, same func(x, y int) bool) (x, y, u, v int) {
+	if k == -d || k != d && furthest[offset+k-1] < furthest[offset+k+1] {
+		x = furthest[offset+k+1]
+	} else {
+		x = furthest[offset+k-1] + 1
+	}
+	y = x - k
+	u, v = x, y
+	for same(u, v) {
+		u++
+		v++
+	}
+	furthest[offset+k] = u
+
+	return x, y, u, v
+}
+
+// nextChange returns the index of the first edit from start that is not kept, or -1 if there is none.
+func nextChange(edits []edit, start int) int {
+	for i := start; i < len(edits); i++ {
+		if edits[i].kind != ' ' {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// hunkRange returns the range of lines of a hunk from the number of lines before it and before its end, which is
+// given as the line before it if the hunk has no lines.
+func hunkRange(before, end int) string {
+	if end == before {
+		return fmt.Sprintf("%d,0", before)
+	}
+
+	return fmt.Sprintf("%d,%d", before+1, end-before)
+}
@@ -0,0 +1,282 @@
+// Ebnffmt formats ISO 14977 and W3C grammar files.
+//
+// Usage:
+//
+//	ebnffmt [flags] [path ...]
+//
+// Given a file, ebnffmt formats it, and given a directory, it formats every .ebnf file within it, recursively. Without
+// a path, it formats standard input. By default the formatted grammars are written to standard output.
+//
+// The flags are:
+//
+//	-d
+//		Do not print formatted grammars, print a unified diff of the changes to each instead.
+//	-l
+//		Do not print formatted grammars, print the name of each file whose formatting differs instead.
+//	-w
+//		Do not print formatted grammars, write them back to their files instead.
+//	-dialect name
+//		The dialect of the grammars, "iso", "w3c" or "auto" (the default) to detect the dialect of each.
+//	-width n
+//		The line width to break long rules at, where 0 never breaks them.
+//	-align
+//		Align the defining symbols of the rules of a grammar.
+//
+// Like gofmt, ebnffmt exits with status 2 if any grammar could not be formatted, so a check that the grammars in a
+// repository are formatted is:
+//
+//	test -z "$(ebnffmt -l .)"
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	ebnf "github.com/alec-w/ebnf-go"
+	"github.com/alec-w/ebnf-go/diag"
+	"github.com/alec-w/ebnf-go/internal/terminal"
+	"github.com/alec-w/ebnf-go/iso"
+	"github.com/alec-w/ebnf-go/w3c"
+)
+
+const (
+	// extension is the extension of the files formatted when walking a directory.
+	extension = ".ebnf"
+	// stdinName is the name standard input is reported as.
+	stdinName = "<standard input>"
+	// exitError is the exit status if any grammar could not be formatted, or the flags are invalid.
+	exitError = 2
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// formatter formats grammars according to the command's flags, recording whether any could not be formatted.
+type formatter struct {
+	dialect    string
+	diff       bool
+	list       bool
+	write      bool
+	isoPrinter *iso.Printer
+	w3cPrinter *w3c.Printer
+	renderer   *diag.Renderer
+	stdout     io.Writer
+	stderr     io.Writer
+	failed     bool
+}
+
+// run runs the command with the given arguments (excluding the program name), returning its exit status.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("ebnffmt", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		_, _ = fmt.Fprintln(stderr, "usage: ebnffmt [flags] [path ...]")
+		flags.PrintDefaults()
+	}
+	diff := flags.Bool("d", false, "display diffs instead of rewriting files")
+	list := flags.Bool("l", false, "list files whose formatting differs from ebnffmt's")
+	write := flags.Bool("w", false, "write result to (source) file instead of stdout")
+	dialect := flags.String("dialect", ebnf.DialectAuto, `dialect of the grammars, "iso", "w3c" or "auto" to detect it`)
+	width := flags.Int("width", iso.DefaultLineWidth, "line width to break long rules at, or 0 to never break them")
+	align := flags.Bool("align", false, "align the defining symbols of rules")
+	if err := flags.Parse(args); err != nil {
+		return exitError
+	}
+	switch *dialect {
+	case ebnf.DialectAuto, ebnf.DialectISO, ebnf.DialectW3C:
+	default:
+		_, _ = fmt.Fprintf(stderr, "ebnffmt: %s\n", &ebnf.UnknownDialectError{Dialect: *dialect})
+
+		return exitError
+	}
+	isoOpts := []iso.PrinterOption{iso.WithLineWidth(*width)}
+	w3cOpts := []w3c.PrinterOption{w3c.WithLineWidth(*width)}
+	if *align {
+		isoOpts = append(isoOpts, iso.WithAlignedDefiningSymbols())
+		w3cOpts = append(w3cOpts, w3c.WithAlignedDefiningSymbols())
+	}
+	var renderOpts []diag.Option
+	if file, ok := stderr.(*os.File); ok && terminal.IsTerminal(file) {
+		renderOpts = append(renderOpts, diag.WithColour())
+	}
+	f := &formatter{
+		dialect:    *dialect,
+		diff:       *diff,
+		list:       *list,
+		write:      *write,
+		isoPrinter: iso.NewPrinter(isoOpts...),
+		w3cPrinter: w3c.NewPrinter(w3cOpts...),
+		renderer:   diag.NewRenderer(renderOpts...),
+		stdout:     stdout,
+		stderr:     stderr,
+	}
+	if flags.NArg() == 0 {
+		if f.write {
+			_, _ = fmt.Fprintln(stderr, "ebnffmt: cannot use -w with standard input")
+
+			return exitError
+		}
+		f.formatReader(stdinName, stdin)
+	}
+	for _, path := range flags.Args() {
+		f.formatPath(path)
+	}
+	if f.failed {
+		return exitError
+	}
+
+	return 0
+}
+
+// formatPath formats the file at path, or every grammar file within it if it is a directory.
+func (f *formatter) formatPath(path string) {
+	info, err := os.Stat(path)
+	if err != nil {
+		f.report(path, "", err)
+
+		return
+	}
+	if !info.IsDir() {
+		f.formatFile(path)
+
+		return
+	}
+	err = filepath.WalkDir(path, func(walked string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			f.report(walked, "", err)
+
+			return nil
+		}
+		if !entry.IsDir() && isGrammarFile(entry.Name()) {
+			f.formatFile(walked)
+		}
+
+		return nil
+	})
+	if err != nil {
+		f.report(path, "", err)
+	}
+}
+
+// isGrammarFile returns whether a file found walking a directory should be formatted, which it should be if it has
+// the grammar file extension and is not hidden.
+func isGrammarFile(name string) bool {
+	return filepath.Ext(name) == extension && !strings.HasPrefix(name, ".")
+}
+
+// formatFile formats the file at path.
+func (f *formatter) formatFile(path string) {
+	file, err := os.Open(path)
+	if err != nil {
+		f.report(path, "", err)
+
+		return
+	}
+	defer func() { _ = file.Close() }()
+	f.formatReader(path, file)
+}
+
+// formatReader formats the grammar read from in, which is named name, handling the result according to the flags.
+func (f *formatter) formatReader(name string, in io.Reader) {
+	content, err := io.ReadAll(in)
+	if err != nil {
+		f.report(name, "", err)
+
+		return
+	}
+	src := string(content)
+	formatted, err := f.format(name, src)
+	if err != nil {
+		f.report(name, src, err)
+
+		return
+	}
+	if !f.list && !f.write && !f.diff {
+		_, _ = io.WriteString(f.stdout, formatted)
+
+		return
+	}
+	if formatted == src {
+		return
+	}
+	if f.list {
+		_, _ = fmt.Fprintln(f.stdout, name)
+	}
+	if f.write {
+		if err := writeFile(name, formatted); err != nil {
+			f.report(name, "", err)
+
+			return
+		}
+	}
+	if f.diff {
+		_, _ = fmt.Fprintf(f.stdout, "diff %s.orig %s\n", name, name)
+		_, _ = io.WriteString(f.stdout, unifiedDiff(name+".orig", name, src, formatted))
+	}
+}
+
+// format returns the grammar src, named name, formatted in its dialect.
+func (f *formatter) format(name, src string) (string, error) {
+	dialect := f.dialect
+	if dialect == ebnf.DialectAuto {
+		detection := ebnf.Detect(src)
+		if detection.Dialect == "" {
+			return "", &ebnf.UndetectedDialectError{Detection: detection}
+		}
+		dialect = detection.Dialect
+	}
+	switch dialect {
+	case ebnf.DialectISO:
+		// The concrete syntax tree lets the printer keep the whitespace within meta identifiers.
+		syntax, err := iso.New(iso.WithFilename(name), iso.WithCST()).Parse(src)
+		if err != nil {
+			return "", err //nolint:wrapcheck // parse errors are rendered with their diagnostics
+		}
+
+		return f.isoPrinter.Format(syntax), nil
+	case ebnf.DialectW3C:
+		syntax, err := w3c.New(w3c.WithFilename(name)).Parse(src)
+		if err != nil {
+			return "", err //nolint:wrapcheck // parse errors are rendered with their diagnostics
+		}
+
+		return f.w3cPrinter.Format(syntax), nil
+	default:
+		return "", &ebnf.UnknownDialectError{Dialect: dialect}
+	}
+}
+
+// writeFile replaces the content of the file at path, keeping its permissions.
+func writeFile(path, content string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err //nolint:wrapcheck // errors are reported by the command, not returned
+	}
+
+	//nolint:wrapcheck // errors are reported by the command, not returned
+	return os.WriteFile(path, []byte(content), info.Mode().Perm())
+}
+
+// report writes an error formatting the grammar src, named name, to stderr, rendering its diagnostics if it has any.
+func (f *formatter) report(name, src string, err error) {
+	f.failed = true
+	var undetected *ebnf.UndetectedDialectError
+	if errors.As(err, &undetected) {
+		_, _ = fmt.Fprintf(f.stderr, "%s: %s (set it with -dialect)\n", name, err)
+
+		return
+	}
+	if len(diag.Diagnostics(err)) == 0 {
+		_, _ = fmt.Fprintf(f.stderr, "%s: %s\n", name, err)
+
+		return
+	}
+	_ = f.renderer.Render(f.stderr, src, err)
+}
@@ -0,0 +1,309 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/alec-w/ebnf-go"
+	"github.com/alec-w/ebnf-go/cst"
+	"github.com/alec-w/ebnf-go/iso"
+	"github.com/alec-w/ebnf-go/w3c"
+)
+
+const (
+	isoUnformatted = "a=b|c;\n"
+	isoFormatted   = "a = b | c ;\n"
+	w3cUnformatted = "a::=b|c\n"
+	w3cFormatted   = "a ::= b | c\n"
+)
+
+func TestRun(t *testing.T) {
+	t.Parallel()
+	testCases := map[string]struct {
+		args     []string
+		stdin    string
+		status   int
+		stdout   string
+		stderr   string
+		expected map[string]string
+	}{
+		"standard input": {
+			stdin:  isoUnformatted,
+			stdout: isoFormatted,
+		},
+		"meta identifiers with whitespace": {
+			stdin:  `non zero digit = "1" ;`,
+			stdout: "non zero digit = '1' ;\n",
+		},
+		"standard input with dialect": {
+			args:   []string{"-dialect", "w3c"},
+			stdin:  "a::='='",
+			stdout: "a ::= '='\n",
+		},
+		"printer options": {
+			args:   []string{"-align", "-width", "0"},
+			stdin:  "a::=b\nlong::=c",
+			stdout: "a    ::= b\nlong ::= c\n",
+		},
+		"files": {
+			args:   []string{"iso.ebnf", "w3c.ebnf"},
+			stdout: isoFormatted + w3cFormatted,
+		},
+		"list": {
+			args:   []string{"-l", "."},
+			stdout: "iso.ebnf\nnested/w3c.ebnf\nw3c.ebnf\n",
+		},
+		"write": {
+			args: []string{"-w", "."},
+			expected: map[string]string{
+				"iso.ebnf":        isoFormatted,
+				"w3c.ebnf":        w3cFormatted,
+				"formatted.ebnf":  isoFormatted,
+				"nested/w3c.ebnf": w3cFormatted,
+				"other.txt":       isoUnformatted,
+				".hidden.ebnf":    isoUnformatted,
+			},
+		},
+		"list and write": {
+			args:     []string{"-l", "-w", "iso.ebnf", "formatted.ebnf"},
+			stdout:   "iso.ebnf\n",
+			expected: map[string]string{"iso.ebnf": isoFormatted},
+		},
+		"diff": {
+			args:   []string{"-d", "iso.ebnf", "formatted.ebnf"},
+			stdout: "diff iso.ebnf.orig iso.ebnf\n--- iso.ebnf.orig\n+++ iso.ebnf\n@@ -1,1 +1,1 @@\n-a=b|c;\n+a = b | c ;\n",
+		},
+		"explicit file without the extension": {
+			args:   []string{"-l", "other.txt"},
+			stdout: "other.txt\n",
+		},
+		"parse error": {
+			args:   []string{"-l", "invalid.txt", "iso.ebnf"},
+			status: exitError,
+			stdout: "iso.ebnf\n",
+			stderr: "--> invalid.txt:1:5",
+		},
+		"undetected dialect": {
+			stdin:  "abc",
+			status: exitError,
+			stderr: "<standard input>: could not detect the dialect of the grammar (set it with -dialect)",
+		},
+		"unknown dialect": {
+			args:   []string{"-dialect", "abnf"},
+			status: exitError,
+			stderr: `ebnffmt: unknown dialect "abnf"`,
+		},
+		"missing file": {
+			args:   []string{"missing.ebnf"},
+			status: exitError,
+			stderr: "missing.ebnf: ",
+		},
+		"write standard input": {
+			args:   []string{"-w"},
+			status: exitError,
+			stderr: "ebnffmt: cannot use -w with standard input",
+		},
+		"invalid flag": {
+			args:   []string{"-x"},
+			status: exitError,
+			stderr: "usage: ebnffmt",
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			dir := writeTree(t, map[string]string{
+				"iso.ebnf":        isoUnformatted,
+				"w3c.ebnf":        w3cUnformatted,
+				"formatted.ebnf":  isoFormatted,
+				"nested/w3c.ebnf": w3cUnformatted,
+				"other.txt":       isoUnformatted,
+				".hidden.ebnf":    isoUnformatted,
+				"invalid.txt":     "a = (b ;\n",
+			})
+			// Paths are relative to the tree, which is given as the directory of each argument that is a path.
+			args := make([]string, 0, len(tc.args))
+			for _, arg := range tc.args {
+				if arg == "." || strings.Contains(arg, ".ebnf") || strings.HasSuffix(arg, ".txt") {
+					arg = filepath.Join(dir, arg)
+				}
+				args = append(args, arg)
+			}
+			stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+			status := run(args, strings.NewReader(tc.stdin), stdout, stderr)
+			if status != tc.status {
+				t.Errorf("Expected exit status %d. Got %d (stderr %q).", tc.status, status, stderr.String())
+			}
+			if actual := strings.ReplaceAll(stdout.String(), dir+string(filepath.Separator), ""); actual != tc.stdout {
+				t.Errorf("Expected stdout:\n%s\nGot:\n%s", tc.stdout, actual)
+			}
+			actual := strings.ReplaceAll(stderr.String(), dir+string(filepath.Separator), "")
+			if tc.stderr == "" && actual != "" || !strings.Contains(actual, tc.stderr) {
+				t.Errorf("Expected stderr to contain %q. Got %q.", tc.stderr, actual)
+			}
+			for path, expected := range tc.expected {
+				content, err := os.ReadFile(filepath.Join(dir, path))
+				if err != nil {
+					t.Fatalf("Expected no error reading %s but got %s.", path, err)
+				}
+				if string(content) != expected {
+					t.Errorf("Expected %s to contain %q. Got %q.", path, expected, string(content))
+				}
+			}
+		})
+	}
+}
+
+// writeTree writes files, keyed by their paths, to a temporary directory, which is returned.
+func writeTree(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for path, content := range files {
+		path = filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+			t.Fatalf("Expected no error creating directory but got %s.", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("Expected no error writing file but got %s.", err)
+		}
+	}
+
+	return dir
+}
+
+func TestFormatCorpus(t *testing.T) {
+	t.Parallel()
+	paths, err := filepath.Glob(filepath.Join("..", "..", "*", "testdata", "corpus", "*.ebnf"))
+	if err != nil || len(paths) == 0 {
+		t.Fatalf("Expected corpus grammars but got %v (%v).", paths, err)
+	}
+	f := &formatter{dialect: ebnf.DialectAuto, isoPrinter: iso.NewPrinter(), w3cPrinter: w3c.NewPrinter()}
+	for _, path := range paths {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			t.Parallel()
+			src, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("Expected no error reading %s but got %s.", path, err)
+			}
+			formatted, err := f.format(path, string(src))
+			if err != nil {
+				t.Fatalf("Expected no error formatting but got %s.", err)
+			}
+			reformatted, err := f.format(path, formatted)
+			if err != nil {
+				t.Fatalf("Expected no error reformatting but got %s.", err)
+			}
+			if reformatted != formatted {
+				t.Errorf("Expected formatting to be stable but got the diff:\n%s", unifiedDiff(
+					"formatted", "reformatted", formatted, reformatted,
+				))
+			}
+			expected, actual := identifiers(t, string(src)), identifiers(t, formatted)
+			if !slices.Equal(expected, actual) {
+				t.Errorf("Expected identifiers %q but got %q.", expected, actual)
+			}
+		})
+	}
+}
+
+// identifiers returns the meta identifiers or symbols of a grammar, including references to rules, as they are spelled
+// in it.
+func identifiers(t *testing.T, grammar string) []string {
+	t.Helper()
+	var tree *cst.Node
+	kind := iso.KindMetaIdentifier
+	if ebnf.Detect(grammar).Dialect == ebnf.DialectW3C {
+		syntax, err := w3c.New(w3c.WithCST()).Parse(grammar)
+		if err != nil {
+			t.Fatalf("Expected no error parsing but got %s.", err)
+		}
+		tree, kind = syntax.CST, w3c.KindSymbol
+	} else {
+		syntax, err := iso.New(iso.WithCST()).Parse(grammar)
+		if err != nil {
+			t.Fatalf("Expected no error parsing but got %s.", err)
+		}
+		tree = syntax.CST
+	}
+	var identifiers []string
+	for _, token := range tree.Tokens() {
+		if token.Kind == kind {
+			identifiers = append(identifiers, token.Text)
+		}
+	}
+
+	return identifiers
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	t.Parallel()
+	testCases := map[string]struct {
+		old      string
+		new      string
+		expected string
+	}{
+		"no changes": {
+			old:      "a\nb\n",
+			new:      "a\nb\n",
+			expected: "--- old\n+++ new\n",
+		},
+		"separate hunks": {
+			old: "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n",
+			new: "0\n1\n2\n3\n4\n5\n6\n7\n8\n9\n11\n12\n",
+			expected: "--- old\n+++ new\n" +
+				"@@ -1,3 +1,4 @@\n+0\n 1\n 2\n 3\n" +
+				"@@ -7,6 +8,5 @@\n 7\n 8\n 9\n-10\n 11\n 12\n",
+		},
+		"merged hunks": {
+			old: "1\n2\n3\n4\n5\n6\n7\n8\n",
+			new: "1\nb\n3\n4\n5\n6\n7\nh\n",
+			expected: "--- old\n+++ new\n" +
+				"@@ -1,8 +1,8 @@\n 1\n-2\n+b\n 3\n 4\n 5\n 6\n 7\n-8\n+h\n",
+		},
+		"no newline at end of file": {
+			old:      "a\nb",
+			new:      "a\nb\n",
+			expected: "--- old\n+++ new\n@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+b\n",
+		},
+		"empty": {
+			old:      "",
+			new:      "a\n",
+			expected: "--- old\n+++ new\n@@ -0,0 +1,1 @@\n+a\n",
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			if actual := unifiedDiff("old", "new", tc.old, tc.new); actual != tc.expected {
+				t.Errorf("Expected diff:\n%s\nGot:\n%s", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestLineEditsLongInput(t *testing.T) {
+	t.Parallel()
+	// Every tenth of 20000 lines is changed, which a table of the longest common subsequences would need gigabytes for.
+	oldLines, newLines := make([]string, 20000), make([]string, 20000)
+	for i := range oldLines {
+		oldLines[i] = strconv.Itoa(i) + "\n"
+		newLines[i] = oldLines[i]
+		if i%10 == 0 {
+			newLines[i] = "changed " + oldLines[i]
+		}
+	}
+	kept := 0
+	for _, e := range lineEdits(oldLines, newLines) {
+		if e.kind == ' ' {
+			kept++
+		}
+	}
+	if kept != 18000 {
+		t.Errorf("Expected 18000 lines kept. Got %d.", kept)
+	}
+}
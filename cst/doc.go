@@ -0,0 +1,3 @@
+// Package cst provides lossless concrete syntax trees of EBNF grammars, shared by the grammar parsers, which keep every
+// token of a grammar along with the whitespace and comments between them so that its source can be reproduced exactly.
+package cst
@@ -0,0 +1,146 @@
+package cst
+
+import (
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/alec-w/ebnf-go/source"
+)
+
+// Kind is the kind of a Node. The kinds of the nodes of a grammar are defined by its dialect, apart from the kinds of
+// trivia and errors which are shared by every dialect.
+type Kind string
+
+const (
+	// KindWhitespace is the kind of a token of whitespace.
+	KindWhitespace Kind = "whitespace"
+	// KindComment is the kind of a token of a comment, including its comment symbols.
+	KindComment Kind = "comment"
+	// KindByteOrderMark is the kind of the token of a byte order mark at the start of a grammar.
+	KindByteOrderMark Kind = "byte-order-mark"
+	// KindError is the kind of a token of text that is not a token of the dialect, which only appears in the tree of a
+	// grammar that could not be parsed.
+	KindError Kind = "error"
+)
+
+// Node is a node of a concrete syntax tree, which is either a token, which has the Text of the source it spans, or a
+// branch made up of the nodes within its span.
+//
+// The text of the tokens of a tree, in order, is the source it was built from.
+type Node struct {
+	Kind     Kind        `json:"kind"`
+	Text     string      `json:"text,omitempty"`
+	Children []*Node     `json:"children,omitempty"`
+	Span     source.Span `json:"-"`
+}
+
+// IsToken returns true if the node is a token rather than a branch.
+func (n *Node) IsToken() bool {
+	return n.Text != ""
+}
+
+// IsTrivia returns true if the node is a token of whitespace, a comment or a byte order mark, which do not change the
+// meaning of a grammar.
+func (n *Node) IsTrivia() bool {
+	return n.Kind == KindWhitespace || n.Kind == KindComment || n.Kind == KindByteOrderMark
+}
+
+// Tokens returns the tokens of the node in order, which is just the node itself if it is a token.
+func (n *Node) Tokens() []*Node {
+	if n.IsToken() {
+		return []*Node{n}
+	}
+	var tokens []*Node
+	for _, child := range n.Children {
+		tokens = append(tokens, child.Tokens()...)
+	}
+
+	return tokens
+}
+
+// String returns the source of the node, which is the text of its tokens.
+func (n *Node) String() string {
+	out := new(strings.Builder)
+	for _, token := range n.Tokens() {
+		out.WriteString(token.Text)
+	}
+
+	return out.String()
+}
+
+// Build assembles a tree from its root, the branches within it (without children) and the tokens that cover its
+// source, by adding each branch, then each token, to the innermost branch whose span contains it.
+//
+// A branch that partly overlaps another branch is left out of the tree. No token is left out, so the tree reproduces
+// the source regardless of the branches, which only give it structure.
+func Build(root *Node, branches, tokens []*Node) *Node {
+	for _, branch := range branches {
+		insert(root, branch)
+	}
+	for _, token := range tokens {
+		insert(root, token)
+	}
+
+	return root
+}
+
+// insert adds a node to the innermost branch within parent whose span contains it, keeping the children of each branch
+// in source order. A branch that contains children of that branch takes them as its own children.
+func insert(parent, node *Node) {
+	for {
+		// The only child that may contain the node is the last to start at or before it, which is the one with the
+		// greatest span of those starting with it.
+		i := sort.Search(len(parent.Children), func(i int) bool {
+			return parent.Children[i].Span.Start.Offset > node.Span.Start.Offset
+		})
+		if i > 0 && contains(parent.Children[i-1], node) {
+			parent = parent.Children[i-1]
+
+			continue
+		}
+		// Children starting with the node may be ordered before it, but are contained by it if it is a branch.
+		i = sort.Search(len(parent.Children), func(i int) bool {
+			return parent.Children[i].Span.Start.Offset >= node.Span.Start.Offset
+		})
+		for i < len(parent.Children) && compare(parent.Children[i], node) <= 0 && !contains(node, parent.Children[i]) {
+			i++
+		}
+		end := i
+		for end < len(parent.Children) && contains(node, parent.Children[end]) {
+			end++
+		}
+		if !node.IsToken() && (i > 0 && overlaps(parent.Children[i-1], node) ||
+			end < len(parent.Children) && overlaps(node, parent.Children[end])) {
+			return
+		}
+		if !node.IsToken() {
+			node.Children = append(node.Children, parent.Children[i:end]...)
+			parent.Children = slices.Delete(parent.Children, i, end)
+		}
+		parent.Children = slices.Insert(parent.Children, i, node)
+
+		return
+	}
+}
+
+// compare orders nodes by their start, then by their end, so an empty node comes before any other at the same offset.
+func compare(a, b *Node) int {
+	if a.Span.Start.Offset != b.Span.Start.Offset {
+		return a.Span.Start.Offset - b.Span.Start.Offset
+	}
+
+	return a.Span.End.Offset - b.Span.End.Offset
+}
+
+// contains returns true if the node is a branch whose span contains the span of the other node, where an empty branch
+// only contains other branches.
+func contains(node, other *Node) bool {
+	return !node.IsToken() && (node.Span.Start.Offset < node.Span.End.Offset || !other.IsToken()) &&
+		node.Span.Start.Offset <= other.Span.Start.Offset && other.Span.End.Offset <= node.Span.End.Offset
+}
+
+// overlaps returns true if the first node ends after the second, which starts after it, starts.
+func overlaps(first, second *Node) bool {
+	return first.Span.End.Offset > second.Span.Start.Offset
+}
@@ -0,0 +1,133 @@
+package cst_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/alec-w/ebnf-go/cst"
+	"github.com/alec-w/ebnf-go/source"
+)
+
+// testSource is tokenised by splitting it at spaces, which are whitespace tokens, while "#" starts a comment token and
+// anything else is an error token.
+const testSource = "ab cd #e fg"
+
+func TestBuild(t *testing.T) {
+	t.Parallel()
+	testCases := map[string]struct {
+		branches []*cst.Node
+		expected string
+	}{
+		"no branches": {
+			expected: `(root error:"ab" whitespace:" " error:"cd" whitespace:" " comment:"#e" whitespace:" " error:"fg")`,
+		},
+		"nested branches": {
+			branches: []*cst.Node{branch("outer", 0, 5), branch("inner", 3, 5), branch("last", 9, 11)},
+			expected: `(root (outer error:"ab" whitespace:" " (inner error:"cd")) whitespace:" " comment:"#e" ` +
+				`whitespace:" " (last error:"fg"))`,
+		},
+		"branch with the same span": {
+			branches: []*cst.Node{branch("outer", 0, 2), branch("inner", 0, 2)},
+			expected: `(root (outer (inner error:"ab")) whitespace:" " error:"cd" whitespace:" " comment:"#e" ` +
+				`whitespace:" " error:"fg")`,
+		},
+		"branch added after its children": {
+			branches: []*cst.Node{branch("first", 0, 2), branch("second", 3, 5), branch("outer", 0, 5)},
+			expected: `(root (outer (first error:"ab") whitespace:" " (second error:"cd")) whitespace:" " ` +
+				`comment:"#e" whitespace:" " error:"fg")`,
+		},
+		"overlapping branch": {
+			branches: []*cst.Node{branch("first", 0, 5), branch("overlapping", 3, 8)},
+			expected: `(root (first error:"ab" whitespace:" " error:"cd") whitespace:" " comment:"#e" ` +
+				`whitespace:" " error:"fg")`,
+		},
+		"empty branches": {
+			branches: []*cst.Node{branch("outer", 3, 3), branch("inner", 3, 3)},
+			expected: `(root error:"ab" whitespace:" " (outer (inner)) error:"cd" whitespace:" " comment:"#e" ` +
+				`whitespace:" " error:"fg")`,
+		},
+		"branch straddling tokens": {
+			branches: []*cst.Node{branch("straddling", 1, 4)},
+			expected: `(root error:"ab" (straddling whitespace:" ") error:"cd" whitespace:" " comment:"#e" ` +
+				`whitespace:" " error:"fg")`,
+		},
+		"branch splitting a token": {
+			branches: []*cst.Node{branch("split", 1, 2)},
+			expected: `(root error:"ab" (split) whitespace:" " error:"cd" whitespace:" " comment:"#e" ` +
+				`whitespace:" " error:"fg")`,
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			root := cst.Build(branch("root", 0, len(testSource)), tc.branches, testTokens())
+			if actual := formatTree(root); actual != tc.expected {
+				t.Errorf("Expected tree:\n%s\nGot:\n%s", tc.expected, actual)
+			}
+			if root.String() != testSource {
+				t.Errorf("Expected tree to reproduce %q. Got %q.", testSource, root.String())
+			}
+		})
+	}
+}
+
+func TestNode(t *testing.T) {
+	t.Parallel()
+	tokens := testTokens()
+	root := cst.Build(branch("root", 0, len(testSource)), []*cst.Node{branch("branch", 3, 8)}, tokens)
+	if root.IsToken() || !tokens[0].IsToken() {
+		t.Errorf("Expected only tokens to be tokens.")
+	}
+	var trivia []string
+	for _, token := range root.Tokens() {
+		if token.IsTrivia() {
+			trivia = append(trivia, token.Text)
+		}
+	}
+	if expected := []string{" ", " ", "#e", " "}; fmt.Sprint(trivia) != fmt.Sprint(expected) {
+		t.Errorf("Expected trivia %q. Got %q.", expected, trivia)
+	}
+	if expected := "cd #e"; root.Children[2].String() != expected {
+		t.Errorf("Expected branch to reproduce %q. Got %q.", expected, root.Children[2].String())
+	}
+}
+
+// testTokens returns the tokens of testSource.
+func testTokens() []*cst.Node {
+	file := source.NewFile(testSource)
+	var tokens []*cst.Node
+	offset := 0
+	for i, text := range strings.SplitAfter(testSource, " ") {
+		if i > 0 {
+			tokens = append(tokens, &cst.Node{Kind: cst.KindWhitespace, Text: " ", Span: file.Span(offset-1, offset)})
+		}
+		text = strings.TrimSuffix(text, " ")
+		kind := cst.KindError
+		if strings.HasPrefix(text, "#") {
+			kind = cst.KindComment
+		}
+		tokens = append(tokens, &cst.Node{Kind: kind, Text: text, Span: file.Span(offset, offset+len(text))})
+		offset += len(text) + 1
+	}
+
+	return tokens
+}
+
+// branch returns a branch of testSource without children.
+func branch(kind cst.Kind, start, end int) *cst.Node {
+	return &cst.Node{Kind: kind, Span: source.NewFile(testSource).Span(start, end)}
+}
+
+// formatTree returns the tree as an s-expression, with each token as its kind and quoted text.
+func formatTree(node *cst.Node) string {
+	if node.IsToken() {
+		return fmt.Sprintf("%s:%q", node.Kind, node.Text)
+	}
+	parts := []string{string(node.Kind)}
+	for _, child := range node.Children {
+		parts = append(parts, formatTree(child))
+	}
+
+	return "(" + strings.Join(parts, " ") + ")"
+}
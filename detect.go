@@ -0,0 +1,159 @@
+package ebnf
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/alec-w/ebnf-go/source"
+)
+
+// DialectAuto is the name that parses a grammar in whichever dialect Detect finds it to be written in. It is reserved,
+// so no dialect can be registered with it.
+const DialectAuto = "auto"
+
+// Detection is the result of detecting the dialect of a grammar.
+type Detection struct {
+	// Dialect is the name of the dialect the grammar looks to be written in, or empty if it looks like neither ISO
+	// 14977 nor the W3C notation (or equally like both).
+	Dialect string `json:"dialect"`
+	// Confidence is the proportion, between 0 and 1, of the weight of the evidence that is for the detected dialect.
+	Confidence float64    `json:"confidence"`
+	Evidence   []Evidence `json:"evidence"`
+}
+
+// Evidence is a kind of token found in a grammar that is characteristic of a dialect.
+type Evidence struct {
+	Dialect     string `json:"dialect"`
+	Description string `json:"description"`
+	// Weight is how characteristic of the dialect a single occurrence of the token is.
+	Weight int `json:"weight"`
+	// Count is the number of occurrences of the token, the first of which is at Position.
+	Count    int             `json:"count"`
+	Position source.Position `json:"position"`
+}
+
+func (e Evidence) String() string {
+	return fmt.Sprintf("%s (%s) found %d times from %s", e.Description, e.Dialect, e.Count, e.Position)
+}
+
+// clue is a token that is evidence of a dialect.
+type clue struct {
+	token       string
+	dialect     string
+	description string
+	weight      int
+	// closing ends a comment started by the token, whose contents are not inspected.
+	closing string
+}
+
+// clues are checked in order at each offset of a grammar, so a token must come before any other token it starts with.
+//
+//nolint:gochecknoglobals // the clues are fixed, a global avoids rebuilding them for every detection
+var clues = []clue{
+	{token: "::=", dialect: DialectW3C, description: `"::=" defining symbol`, weight: 4},
+	{token: "#x", dialect: DialectW3C, description: `"#x" character reference`, weight: 3},
+	{token: "/*", dialect: DialectW3C, description: `"/*" comment`, weight: 1, closing: "*/"},
+	{token: "=", dialect: DialectISO, description: `"=" defining symbol`, weight: 3},
+	{token: ";", dialect: DialectISO, description: `";" terminator symbol`, weight: 2},
+	{token: ",", dialect: DialectISO, description: `"," concatenate symbol`, weight: 1},
+	{token: "{", dialect: DialectISO, description: `"{" repeated sequence`, weight: 1},
+	{token: "(*", dialect: DialectISO, description: `"(*" comment`, weight: 1, closing: "*)"},
+}
+
+// Detect inspects a grammar to report which dialect it looks to be written in, with the evidence for that.
+//
+// The tokens that are characteristic of each dialect (e.g. "::=" and "#x" for the W3C notation, and "=" and ";" for
+// ISO 14977) are counted, ignoring the contents of quoted strings and comments, and the dialect with the greatest
+// weight of evidence is detected.
+func Detect(grammar string) Detection {
+	file := source.NewFile(grammar)
+	var evidence []Evidence
+	// found holds the index in evidence of each clue that has been found.
+	found := map[int]int{}
+	offset := 0
+	for offset < len(grammar) {
+		rest := grammar[offset:]
+		if skipped := skipQuoted(rest); skipped > 0 {
+			offset += skipped
+
+			continue
+		}
+		matched := false
+		for i, c := range clues {
+			if !strings.HasPrefix(rest, c.token) {
+				continue
+			}
+			if j, ok := found[i]; ok {
+				evidence[j].Count++
+			} else {
+				found[i] = len(evidence)
+				evidence = append(evidence, Evidence{
+					Dialect:     c.dialect,
+					Description: c.description,
+					Weight:      c.weight,
+					Count:       1,
+					Position:    file.Position(offset),
+				})
+			}
+			offset += len(c.token)
+			if c.closing != "" {
+				offset += skipComment(grammar[offset:], c.closing)
+			}
+			matched = true
+
+			break
+		}
+		if !matched {
+			offset++
+		}
+	}
+
+	return detection(evidence)
+}
+
+// skipQuoted returns the length of the quoted string at the start of rest, which is 0 if there is none. An
+// unterminated quoted string is taken to end at the end of its line.
+func skipQuoted(rest string) int {
+	if !strings.HasPrefix(rest, "'") && !strings.HasPrefix(rest, `"`) {
+		return 0
+	}
+	end := strings.IndexAny(rest[1:], rest[:1]+"\n")
+	if end < 0 {
+		return len(rest)
+	}
+
+	// The length includes both quotes.
+	return end + len(`""`)
+}
+
+// skipComment returns the length of the rest of a comment up to and including its closing symbol, or of all of rest if
+// the comment is not terminated.
+func skipComment(rest, closing string) int {
+	end := strings.Index(rest, closing)
+	if end < 0 {
+		return len(rest)
+	}
+
+	return end + len(closing)
+}
+
+// detection weighs the evidence found for each dialect.
+func detection(evidence []Evidence) Detection {
+	weights := map[string]int{}
+	total := 0
+	for _, e := range evidence {
+		weights[e.Dialect] += e.Weight * e.Count
+		total += e.Weight * e.Count
+	}
+	result := Detection{Evidence: evidence}
+	if total == 0 || weights[DialectISO] == weights[DialectW3C] {
+		return result
+	}
+	result.Dialect = DialectISO
+	if weights[DialectW3C] > weights[DialectISO] {
+		result.Dialect = DialectW3C
+	}
+	result.Confidence = float64(weights[result.Dialect]) / float64(total)
+
+	return result
+}
@@ -0,0 +1,190 @@
+package ebnf_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alec-w/ebnf-go"
+	"github.com/alec-w/ebnf-go/source"
+)
+
+func TestDetect(t *testing.T) {
+	t.Parallel()
+	testCases := map[string]struct {
+		grammar            string
+		expectedDialect    string
+		expectedConfidence float64
+	}{
+		"iso": {
+			grammar:            "digit = '0' | '1' ;\nnumber = digit, { digit } ;",
+			expectedDialect:    ebnf.DialectISO,
+			expectedConfidence: 1,
+		},
+		"w3c": {
+			grammar:            "digit ::= [#x30-#x31]\nnumber ::= digit+",
+			expectedDialect:    ebnf.DialectW3C,
+			expectedConfidence: 1,
+		},
+		"tokens in literals are ignored": {
+			grammar:            `assign ::= name "=" value ';'`,
+			expectedDialect:    ebnf.DialectW3C,
+			expectedConfidence: 1,
+		},
+		"tokens in comments are ignored": {
+			grammar:            "(* a ::= #x30 *) a = 'a' ;",
+			expectedDialect:    ebnf.DialectISO,
+			expectedConfidence: 1,
+		},
+		"mixed evidence": {
+			grammar:            "a ::= [^=]",
+			expectedDialect:    ebnf.DialectW3C,
+			expectedConfidence: 4.0 / 7.0,
+		},
+		"neither":       {grammar: "just some text"},
+		"empty":         {grammar: ""},
+		"equally both":  {grammar: "/* comment */ (* comment *)"},
+		"only literals": {grammar: "'a = b ;' \"c ::= d\""},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			detection := ebnf.Detect(tc.grammar)
+			if detection.Dialect != tc.expectedDialect {
+				t.Errorf("Expected dialect %q. Got %q (evidence %v).", tc.expectedDialect, detection.Dialect, detection.Evidence)
+			}
+			if detection.Confidence != tc.expectedConfidence {
+				t.Errorf("Expected confidence %v. Got %v.", tc.expectedConfidence, detection.Confidence)
+			}
+		})
+	}
+}
+
+func TestDetectEvidence(t *testing.T) {
+	t.Parallel()
+	detection := ebnf.Detect("a ::= b\n/* c */ d ::= #x20")
+	expected := []ebnf.Evidence{
+		{
+			Dialect:     ebnf.DialectW3C,
+			Description: `"::=" defining symbol`,
+			Weight:      4,
+			Count:       2,
+			Position:    source.Position{Offset: 2, Line: 1, Column: 3},
+		},
+		{
+			Dialect:     ebnf.DialectW3C,
+			Description: `"/*" comment`,
+			Weight:      1,
+			Count:       1,
+			Position:    source.Position{Offset: 8, Line: 2, Column: 1},
+		},
+		{
+			Dialect:     ebnf.DialectW3C,
+			Description: `"#x" character reference`,
+			Weight:      3,
+			Count:       1,
+			Position:    source.Position{Offset: 22, Line: 2, Column: 15},
+		},
+	}
+	if len(detection.Evidence) != len(expected) {
+		t.Fatalf("Expected %d pieces of evidence. Got %v.", len(expected), detection.Evidence)
+	}
+	for i := range expected {
+		if detection.Evidence[i] != expected[i] {
+			t.Errorf("Expected evidence %#v. Got %#v.", expected[i], detection.Evidence[i])
+		}
+	}
+}
+
+func TestDetectCorpus(t *testing.T) {
+	t.Parallel()
+	for dialect, dir := range map[string]string{
+		ebnf.DialectISO: filepath.Join("iso", "testdata", "corpus"),
+		ebnf.DialectW3C: filepath.Join("w3c", "testdata", "corpus"),
+	} {
+		grammars, err := filepath.Glob(filepath.Join(dir, "*.ebnf"))
+		if err != nil {
+			t.Fatalf("Expected no error finding corpus grammars but got %s.", err)
+		}
+		if len(grammars) == 0 {
+			t.Fatalf("Expected corpus grammars in %s but found none.", dir)
+		}
+		for _, grammar := range grammars {
+			content, err := os.ReadFile(grammar)
+			if err != nil {
+				t.Fatalf("Expected no error reading grammar but got %s.", err)
+			}
+			if detection := ebnf.Detect(string(content)); detection.Dialect != dialect {
+				t.Errorf("Expected %s to be detected as %q. Got %q.", grammar, dialect, detection.Dialect)
+			}
+		}
+	}
+}
+
+func TestParseAuto(t *testing.T) {
+	t.Parallel()
+	testCases := map[string]struct {
+		grammar         string
+		expectedDialect string
+	}{
+		"iso": {grammar: "a = 'a' ;", expectedDialect: ebnf.DialectISO},
+		"w3c": {grammar: "a ::= 'a'", expectedDialect: ebnf.DialectW3C},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			grammar, err := ebnf.Parse(ebnf.DialectAuto, tc.grammar)
+			if err != nil {
+				t.Fatalf("Expected no error but got %s.", err)
+			}
+			if grammar.Dialect != tc.expectedDialect {
+				t.Errorf("Expected dialect %q. Got %q.", tc.expectedDialect, grammar.Dialect)
+			}
+		})
+	}
+}
+
+func TestParseAutoUndetected(t *testing.T) {
+	t.Parallel()
+	_, err := ebnf.Parse(ebnf.DialectAuto, "just some text")
+	var undetectedErr *ebnf.UndetectedDialectError
+	if !errors.As(err, &undetectedErr) {
+		t.Fatalf("Expected an undetected dialect error. Got %v.", err)
+	}
+	if undetectedErr.Detection.Dialect != "" {
+		t.Errorf("Expected no dialect to be detected. Got %q.", undetectedErr.Detection.Dialect)
+	}
+}
+
+// autoDialect is a dialect named like the reserved auto dialect.
+type autoDialect struct {
+	wordsDialect
+}
+
+func (autoDialect) Name() string {
+	return ebnf.DialectAuto
+}
+
+func TestRegisterAuto(t *testing.T) {
+	t.Parallel()
+	registry, err := ebnf.NewRegistry()
+	if err != nil {
+		t.Fatalf("Expected no error but got %s.", err)
+	}
+	err = registry.Register(autoDialect{})
+	var reservedErr *ebnf.ReservedDialectError
+	if !errors.As(err, &reservedErr) {
+		t.Fatalf("Expected a reserved dialect error. Got %v.", err)
+	}
+	if reservedErr.Dialect != ebnf.DialectAuto {
+		t.Errorf("Expected reserved dialect %q. Got %q.", ebnf.DialectAuto, reservedErr.Dialect)
+	}
+	var duplicateErr *ebnf.DuplicateDialectError
+	if errors.As(err, &duplicateErr) {
+		t.Errorf("Expected no duplicate dialect error. Got %v.", err)
+	}
+	if names := registry.Names(); len(names) != 0 {
+		t.Errorf("Expected no dialects to be registered. Got %q.", names)
+	}
+}
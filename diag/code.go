@@ -0,0 +1,77 @@
+package diag
+
+// Code identifies the kind of a parse error, and is stable so that it can be relied upon by tools (e.g. to link to
+// documentation), unlike the messages of errors.
+//
+// Codes are shared by every dialect, and are errors themselves so that they can be used as sentinel errors, e.g.
+//
+//	if errors.Is(err, diag.CodeUnterminatedLiteral) {
+//		...
+//	}
+//
+//nolint:errname // codes are named as an enumeration rather than as errors
+type Code string
+
+// Codes of parse errors.
+const (
+	// CodeMissingRuleName is used when a rule does not start with its name (a meta identifier or symbol).
+	CodeMissingRuleName Code = "missing-rule-name"
+	// CodeMissingDefiningSymbol is used when a rule name is not followed by a defining symbol ("=" or "::=").
+	CodeMissingDefiningSymbol Code = "missing-defining-symbol"
+	// CodeMissingTerminator is used when a rule is not ended by a terminator symbol (";" or ".").
+	CodeMissingTerminator Code = "missing-terminator"
+	// CodeMissingRepetitionSymbol is used when a number of repetitions is not followed by a repetition symbol ("*").
+	CodeMissingRepetitionSymbol Code = "missing-repetition-symbol"
+	// CodeMissingExpression is used when an expression is expected but not found.
+	CodeMissingExpression Code = "missing-expression"
+	// CodeUnexpectedToken is used when a token that cannot continue an expression is found.
+	CodeUnexpectedToken Code = "unexpected-token"
+	// CodeUnterminatedLiteral is used when a quoted terminal string or literal is not closed.
+	CodeUnterminatedLiteral Code = "unterminated-literal"
+	// CodeUnterminatedSpecialSequence is used when a special sequence is not closed.
+	CodeUnterminatedSpecialSequence Code = "unterminated-special-sequence"
+	// CodeUnterminatedComment is used when a comment is not closed.
+	CodeUnterminatedComment Code = "unterminated-comment"
+	// CodeUnterminatedSequence is used when a bracketed (optional, repeated, grouped or parenthesised) sequence is not
+	// closed.
+	CodeUnterminatedSequence Code = "unterminated-sequence"
+	// CodeUnterminatedCharacterSet is used when a character set is not closed.
+	CodeUnterminatedCharacterSet Code = "unterminated-character-set"
+	// CodeUnterminatedConstraint is used when a constraint annotation is not closed.
+	CodeUnterminatedConstraint Code = "unterminated-constraint"
+	// CodeUnterminatedExternalReference is used when a reference to a production in another specification is not
+	// closed.
+	CodeUnterminatedExternalReference Code = "unterminated-external-reference"
+	// CodeInvalidInteger is used when a number of repetitions cannot be parsed.
+	CodeInvalidInteger Code = "invalid-integer"
+	// CodeInvalidHexCharacter is used when a hexadecimal character reference cannot be parsed.
+	CodeInvalidHexCharacter Code = "invalid-hex-character"
+	// CodeDepthLimitExceeded is used when sequences or expressions are nested deeper than allowed (see limit.Limits).
+	CodeDepthLimitExceeded Code = "depth-limit-exceeded"
+	// CodeRuleLimitExceeded is used when a grammar has more rules than allowed (see limit.Limits).
+	CodeRuleLimitExceeded Code = "rule-limit-exceeded"
+	// CodeLiteralLengthLimitExceeded is used when a terminal string or literal is longer than allowed (see
+	// limit.Limits).
+	CodeLiteralLengthLimitExceeded Code = "literal-length-limit-exceeded"
+	// CodeInputSizeLimitExceeded is used when a grammar is larger than allowed (see limit.Limits), which is reported
+	// before any of it is parsed.
+	CodeInputSizeLimitExceeded Code = "input-size-limit-exceeded"
+	// CodeCancelled is used when parsing is stopped because its context is cancelled or its deadline passes.
+	CodeCancelled Code = "cancelled"
+	// CodeNonStandardSyntax is used when a strict parser finds syntax that is accepted as an extension of its dialect's
+	// standard.
+	CodeNonStandardSyntax Code = "non-standard-syntax"
+)
+
+// Codes of errors merging the syntaxes of a grammar split across several files.
+const (
+	// CodeDuplicateRule is used when a rule is defined in more than one file.
+	CodeDuplicateRule Code = "duplicate-rule"
+	// CodeUndefinedReference is used when a rule references a rule that is not defined in any file.
+	CodeUndefinedReference Code = "undefined-reference"
+)
+
+// Error fulfills the error interface.
+func (c Code) Error() string {
+	return string(c)
+}
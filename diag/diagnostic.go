@@ -0,0 +1,48 @@
+package diag
+
+import "github.com/alec-w/ebnf-go/source"
+
+// Diagnostic describes a problem at a position within the source of a grammar.
+type Diagnostic struct {
+	// Code identifies the kind of problem, if known.
+	Code     Code
+	Message  string
+	Position source.Position
+	// Rule is the name of the rule enclosing the problem, if known.
+	Rule string
+	// Expected lists the tokens that would have been acceptable where a parse error occurred and Found is the token
+	// that was found instead, if known (see Literal and FoundToken).
+	Expected []string
+	Found    string
+}
+
+// Diagnoser is implemented by errors that can describe themselves as a Diagnostic, such as the parse errors of each
+// dialect.
+type Diagnoser interface {
+	Diagnostic() Diagnostic
+}
+
+// Diagnostics returns the diagnostics of every error in err's tree (see errors.Unwrap) that implements Diagnoser.
+//
+// The tree is searched depth first, and is not searched below an error implementing Diagnoser, so a parse error that
+// wraps another is only reported once.
+func Diagnostics(err error) []Diagnostic {
+	//nolint:errorlint // the tree is walked explicitly so that every diagnostic is found, not just the first
+	switch err := err.(type) {
+	case nil:
+		return nil
+	case Diagnoser:
+		return []Diagnostic{err.Diagnostic()}
+	case interface{ Unwrap() []error }:
+		var diagnostics []Diagnostic
+		for _, wrapped := range err.Unwrap() {
+			diagnostics = append(diagnostics, Diagnostics(wrapped)...)
+		}
+
+		return diagnostics
+	case interface{ Unwrap() error }:
+		return Diagnostics(err.Unwrap())
+	default:
+		return nil
+	}
+}
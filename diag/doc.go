@@ -0,0 +1,3 @@
+// Package diag describes and renders problems found in the source of an EBNF grammar, such as parse errors, as
+// caret-annotated excerpts of the offending source.
+package diag
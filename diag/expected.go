@@ -0,0 +1,52 @@
+package diag
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// EndOfInput describes the end of the source of a grammar as a token.
+const EndOfInput = "end of input"
+
+// Literal describes a token with fixed text, such as a defining symbol.
+//
+// Literal tokens are quoted (see strconv.Quote) so that they can be distinguished from the names of token classes,
+// such as "meta identifier", in lists of expected tokens, and unquoted (see strconv.Unquote) to recover their text.
+func Literal(text string) string {
+	return strconv.Quote(text)
+}
+
+// FoundToken describes the token found at the start of text, where a parse error occurred, which is either EndOfInput
+// or the first character of text as a Literal.
+func FoundToken(text string) string {
+	if text == "" {
+		return EndOfInput
+	}
+	char, _ := utf8.DecodeRuneInString(text)
+
+	return Literal(string(char))
+}
+
+// ExpectedMessage returns the message for a parse error where one of the expected tokens was acceptable but the found
+// token was not, so that the messages of every dialect are consistent, e.g.
+//
+//	expected "=" or "::=", found end of input
+func ExpectedMessage(expected []string, found string) string {
+	msg := new(strings.Builder)
+	msg.WriteString("expected ")
+	for i, token := range expected {
+		switch {
+		case i == 0:
+		case i == len(expected)-1:
+			msg.WriteString(" or ")
+		default:
+			msg.WriteString(", ")
+		}
+		msg.WriteString(token)
+	}
+	msg.WriteString(", found ")
+	msg.WriteString(found)
+
+	return msg.String()
+}
@@ -0,0 +1,173 @@
+package diag
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/alec-w/ebnf-go/source"
+)
+
+// ANSI escape sequences used when rendering in colour.
+const (
+	ansiReset = "\x1b[0m"
+	ansiBold  = "\x1b[1m"
+	ansiRed   = "\x1b[1;31m"
+	ansiBlue  = "\x1b[1;34m"
+)
+
+// Renderer renders diagnostics as excerpts of the source of a grammar, with a caret under the offending column, e.g.
+//
+//	error[missing-defining-symbol]: expected "=", found "\""
+//	 --> 3:7
+//	  |
+//	3 | digit "0" | nonZeroDigit ;
+//	  |       ^
+//	  = in rule digit
+//
+// The position is prefixed with the name of the file containing the grammar, if it was parsed from a file.
+type Renderer struct {
+	colour bool
+}
+
+// Option configures a Renderer.
+type Option func(*Renderer)
+
+// WithColour configures a Renderer to highlight its output with ANSI colours.
+func WithColour() Option {
+	return func(r *Renderer) {
+		r.colour = true
+	}
+}
+
+// NewRenderer instantiates a Renderer.
+func NewRenderer(opts ...Option) *Renderer {
+	renderer := &Renderer{}
+	for _, opt := range opts {
+		opt(renderer)
+	}
+
+	return renderer
+}
+
+// Render writes the diagnostics of err (see Diagnostics) against the grammar src to w, separated by blank lines.
+//
+// The diagnostics must all be in the grammar for it to be excerpted. If they are in several files (as those of
+// merging syntaxes may be) the grammar cannot be the source of all of them, so they are rendered without excerpts (see
+// RenderFiles). An error without any diagnostics is rendered by its message alone.
+func (r *Renderer) Render(w io.Writer, src string, err error) error {
+	diagnostics := Diagnostics(err)
+	if len(diagnostics) == 0 {
+		return r.RenderFiles(w, nil, err)
+	}
+	name := diagnostics[0].Position.Filename
+	for _, diagnostic := range diagnostics[1:] {
+		if diagnostic.Position.Filename != name {
+			return r.RenderFiles(w, nil, err)
+		}
+	}
+
+	return r.RenderFiles(w, []*source.File{source.NewNamedFile(name, src)}, err)
+}
+
+// RenderFiles writes the diagnostics of err (see Diagnostics) to w, separated by blank lines, each against the one of
+// files named by its position.
+//
+// A diagnostic in a file that is not one of files is rendered without an excerpt. An error without any diagnostics is
+// rendered by its message alone.
+func (r *Renderer) RenderFiles(w io.Writer, files []*source.File, err error) error {
+	diagnostics := Diagnostics(err)
+	if len(diagnostics) == 0 {
+		if err == nil {
+			return nil
+		}
+		_, writeErr := io.WriteString(w, r.header("", err.Error()))
+
+		return writeErr
+	}
+	out := new(strings.Builder)
+	for i, diagnostic := range diagnostics {
+		if i > 0 {
+			out.WriteString("\n")
+		}
+		var file *source.File
+		for _, candidate := range files {
+			if candidate.Name() == diagnostic.Position.Filename {
+				file = candidate
+
+				break
+			}
+		}
+		r.render(out, file, diagnostic)
+	}
+	_, writeErr := io.WriteString(w, out.String())
+
+	return writeErr
+}
+
+// RenderDiagnostic writes a single diagnostic against the grammar in file to w.
+func (r *Renderer) RenderDiagnostic(w io.Writer, file *source.File, diagnostic Diagnostic) error {
+	out := new(strings.Builder)
+	r.render(out, file, diagnostic)
+	_, err := io.WriteString(w, out.String())
+
+	return err
+}
+
+// render writes a diagnostic against the grammar in file, without an excerpt of the grammar if file is nil.
+func (r *Renderer) render(out *strings.Builder, file *source.File, diagnostic Diagnostic) {
+	position := diagnostic.Position
+	number := strconv.Itoa(position.Line)
+	gutter := strings.Repeat(" ", len(number))
+	out.WriteString(r.header(diagnostic.Code, diagnostic.Message))
+	fmt.Fprintf(out, "%s%s %s\n", gutter, r.style(ansiBlue, "-->"), position)
+	if file != nil {
+		line := file.Line(position.Line)
+		fmt.Fprintf(out, "%s %s\n", gutter, r.style(ansiBlue, "|"))
+		fmt.Fprintf(out, "%s %s\n", r.style(ansiBlue, number+" |"), line)
+		padding := caretPadding(line, position.Column)
+		fmt.Fprintf(out, "%s %s%s\n", r.style(ansiBlue, gutter+" |"), padding, r.style(ansiRed, "^"))
+	}
+	if diagnostic.Rule != "" {
+		fmt.Fprintf(out, "%s %s in rule %s\n", gutter, r.style(ansiBlue, "="), diagnostic.Rule)
+	}
+}
+
+func (r *Renderer) header(code Code, msg string) string {
+	label := "error"
+	if code != "" {
+		label += "[" + string(code) + "]"
+	}
+
+	return r.style(ansiRed, label) + r.style(ansiBold, ": "+msg) + "\n"
+}
+
+func (r *Renderer) style(ansi, text string) string {
+	if !r.colour {
+		return text
+	}
+
+	return ansi + text + ansiReset
+}
+
+// caretPadding returns the padding needed to place a caret under the given column (counted in runes) of line, keeping
+// any tabs so that the caret lines up however tabs are displayed.
+func caretPadding(line string, column int) string {
+	padding := new(strings.Builder)
+	for _, char := range line {
+		if padding.Len() >= column-1 {
+			break
+		}
+		if char == '\t' {
+			padding.WriteRune('\t')
+		} else {
+			padding.WriteRune(' ')
+		}
+	}
+	for padding.Len() < column-1 {
+		padding.WriteRune(' ')
+	}
+
+	return padding.String()
+}
@@ -0,0 +1,208 @@
+package diag_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/alec-w/ebnf-go/diag"
+	"github.com/alec-w/ebnf-go/iso"
+	"github.com/alec-w/ebnf-go/source"
+	"github.com/alec-w/ebnf-go/w3c"
+)
+
+func TestRendererRender(t *testing.T) {
+	t.Parallel()
+	tcs := []struct {
+		name     string
+		grammar  string
+		parse    func(grammar string) error
+		opts     []diag.Option
+		expected string
+	}{
+		{
+			name:    "iso error with rule",
+			grammar: "digit = \"0\" ;\ninteger \"0\" ;\n",
+			parse: func(grammar string) error {
+				parser := iso.New()
+				_, err := parser.Parse(grammar)
+
+				return err
+			},
+			expected: "error[missing-defining-symbol]: expected \"=\", found \"\\\"\"\n" +
+				" --> 2:9\n" +
+				"  |\n" +
+				"2 | integer \"0\" ;\n" +
+				"  |         ^\n" +
+				"  = in rule integer\n",
+		},
+		{
+			name:    "iso errors when recovering",
+			grammar: "a = \"0\" \nb = ;\nc = 'x ;",
+			parse: func(grammar string) error {
+				parser := iso.New(iso.WithRecovery())
+				_, err := parser.Parse(grammar)
+
+				return err
+			},
+			expected: "error[missing-terminator]: expected \"-\", \",\", \"|\", \"/\", \"!\", \";\" or \".\", found \"b\"\n" +
+				" --> 2:1\n" +
+				"  |\n" +
+				"2 | b = ;\n" +
+				"  | ^\n" +
+				"  = in rule a\n" +
+				"\n" +
+				"error[unterminated-literal]: terminal string is not terminated (expected closing quote ('\\''))\n" +
+				" --> 3:5\n" +
+				"  |\n" +
+				"3 | c = 'x ;\n" +
+				"  |     ^\n" +
+				"  = in rule c\n",
+		},
+		{
+			name:    "w3c error with rule keeps tabs",
+			grammar: "a ::= 'a'\nb\t::=\t'b' |\t]\n",
+			parse: func(grammar string) error {
+				_, err := w3c.New().Parse(grammar)
+
+				return err
+			},
+			expected: "error[missing-expression]: expected symbol, literal, character set or \"(\", found \"]\"\n" +
+				" --> 2:13\n" +
+				"  |\n" +
+				"2 | b\t::=\t'b' |\t]\n" +
+				"  |  \t   \t     \t^\n" +
+				"  = in rule b\n",
+		},
+		{
+			name:    "w3c error with file name",
+			grammar: "/* ab */\nb := 'b'\n",
+			parse: func(grammar string) error {
+				_, err := w3c.New().ParseReader("grammars/ab.ebnf", strings.NewReader(grammar))
+
+				return err
+			},
+			expected: "error[missing-defining-symbol]: expected \":\", found \"=\"\n" +
+				" --> grammars/ab.ebnf:2:4\n" +
+				"  |\n" +
+				"2 | b := 'b'\n" +
+				"  |    ^\n" +
+				"  = in rule b\n",
+		},
+		{
+			name:    "w3c error with colour",
+			grammar: "a := 'a'",
+			parse: func(grammar string) error {
+				_, err := w3c.New().Parse(grammar)
+
+				return err
+			},
+			opts: []diag.Option{diag.WithColour()},
+			expected: "\x1b[1;31merror[missing-defining-symbol]\x1b[0m\x1b[1m: expected \":\", found \"=\"\x1b[0m\n" +
+				" \x1b[1;34m-->\x1b[0m 1:4\n" +
+				"  \x1b[1;34m|\x1b[0m\n" +
+				"\x1b[1;34m1 |\x1b[0m a := 'a'\n" +
+				"\x1b[1;34m  |\x1b[0m    \x1b[1;31m^\x1b[0m\n" +
+				"  \x1b[1;34m=\x1b[0m in rule a\n",
+		},
+		{
+			name:    "error without diagnostic",
+			grammar: "",
+			parse: func(string) error {
+				return errors.New("something went wrong")
+			},
+			expected: "error: something went wrong\n",
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			err := tc.parse(tc.grammar)
+			if err == nil {
+				t.Fatal("Expected an error but got nil.")
+			}
+			out := new(strings.Builder)
+			if err := diag.NewRenderer(tc.opts...).Render(out, tc.grammar, err); err != nil {
+				t.Fatalf("Expected no error rendering but got %s.", err)
+			}
+			if actual := out.String(); actual != tc.expected {
+				t.Errorf("Expected rendering\n%s\nbut got\n%s", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestRendererRenderMergeErrors(t *testing.T) {
+	t.Parallel()
+	files := map[string]string{
+		"lexical.ebnf":   "digit = '0' | one ;\n",
+		"syntactic.ebnf": "number = digit, { digit } ;\ndigit = '1' ;\n",
+	}
+	fsys := fstest.MapFS{}
+	for name, content := range files {
+		fsys[name] = &fstest.MapFile{Data: []byte(content)}
+	}
+	_, err := iso.New().ParseFiles(fsys, "lexical.ebnf", "syntactic.ebnf")
+	if err == nil {
+		t.Fatal("Expected an error but got nil.")
+	}
+	t.Run("render files", func(t *testing.T) {
+		t.Parallel()
+		out := new(strings.Builder)
+		sources := []*source.File{
+			source.NewNamedFile("lexical.ebnf", files["lexical.ebnf"]),
+			source.NewNamedFile("syntactic.ebnf", files["syntactic.ebnf"]),
+		}
+		if err := diag.NewRenderer().RenderFiles(out, sources, err); err != nil {
+			t.Fatalf("Expected no error rendering but got %s.", err)
+		}
+		expected := "error[duplicate-rule]: rule digit is already defined at lexical.ebnf:1:1\n" +
+			" --> syntactic.ebnf:2:1\n" +
+			"  |\n" +
+			"2 | digit = '1' ;\n" +
+			"  | ^\n" +
+			"  = in rule digit\n" +
+			"\n" +
+			"error[undefined-reference]: meta identifier one is not defined by any rule\n" +
+			" --> lexical.ebnf:1:15\n" +
+			"  |\n" +
+			"1 | digit = '0' | one ;\n" +
+			"  |               ^\n" +
+			"  = in rule digit\n"
+		if actual := out.String(); actual != expected {
+			t.Errorf("Expected rendering\n%s\nbut got\n%s", expected, actual)
+		}
+	})
+	t.Run("render without the source of a file", func(t *testing.T) {
+		t.Parallel()
+		out := new(strings.Builder)
+		sources := []*source.File{source.NewNamedFile("lexical.ebnf", files["lexical.ebnf"])}
+		if err := diag.NewRenderer().RenderFiles(out, sources, err); err != nil {
+			t.Fatalf("Expected no error rendering but got %s.", err)
+		}
+		expected := "error[duplicate-rule]: rule digit is already defined at lexical.ebnf:1:1\n" +
+			" --> syntactic.ebnf:2:1\n" +
+			"  = in rule digit\n" +
+			"\n" +
+			"error[undefined-reference]: meta identifier one is not defined by any rule\n" +
+			" --> lexical.ebnf:1:15\n" +
+			"  |\n" +
+			"1 | digit = '0' | one ;\n" +
+			"  |               ^\n" +
+			"  = in rule digit\n"
+		if actual := out.String(); actual != expected {
+			t.Errorf("Expected rendering\n%s\nbut got\n%s", expected, actual)
+		}
+	})
+	t.Run("render against a single file", func(t *testing.T) {
+		t.Parallel()
+		out := new(strings.Builder)
+		if err := diag.NewRenderer().Render(out, files["lexical.ebnf"], err); err != nil {
+			t.Fatalf("Expected no error rendering but got %s.", err)
+		}
+		if actual := out.String(); strings.Contains(actual, "|") {
+			t.Errorf("Expected rendering without excerpts but got\n%s", actual)
+		}
+	})
+}
@@ -0,0 +1,154 @@
+package ebnf
+
+import (
+	"context"
+	"slices"
+	"sync"
+)
+
+// Names of the dialects registered by default.
+const (
+	DialectISO = "iso"
+	DialectW3C = "w3c"
+)
+
+// Dialect is a notation for EBNF grammars, which parses grammars written in it into a Grammar.
+//
+// A Dialect must be safe for concurrent use by multiple goroutines.
+type Dialect interface {
+	// Name identifies the dialect in a Registry.
+	Name() string
+	// Parse parses the given grammar, stopping if the context is cancelled. Like the parsers of this module, it may
+	// return a partial Grammar along with an error when recovering from errors.
+	Parse(ctx context.Context, grammar string) (Grammar, error)
+}
+
+// Registry is a set of dialects looked up by name, which is safe for concurrent use by multiple goroutines.
+//
+// The zero value is an empty Registry ready to use.
+type Registry struct {
+	mu       sync.RWMutex
+	dialects map[string]Dialect
+}
+
+// NewRegistry instantiates a Registry with the given dialects registered, returning an error if any of them share a
+// name or have a reserved name (see Registry.Register).
+func NewRegistry(dialects ...Dialect) (*Registry, error) {
+	registry := &Registry{}
+	for _, dialect := range dialects {
+		if err := registry.Register(dialect); err != nil {
+			return nil, err
+		}
+	}
+
+	return registry, nil
+}
+
+// Register adds a dialect to the Registry, returning a DuplicateDialectError if one with the same name is already
+// registered, or a ReservedDialectError if it is named DialectAuto.
+func (r *Registry) Register(dialect Dialect) error {
+	name := dialect.Name()
+	if name == DialectAuto {
+		return &ReservedDialectError{Dialect: name}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.dialects[name]; ok {
+		return &DuplicateDialectError{Dialect: name}
+	}
+	if r.dialects == nil {
+		r.dialects = map[string]Dialect{}
+	}
+	r.dialects[name] = dialect
+
+	return nil
+}
+
+// Lookup returns the dialect registered with the given name, and whether there is one.
+func (r *Registry) Lookup(name string) (Dialect, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	dialect, ok := r.dialects[name]
+
+	return dialect, ok
+}
+
+// Names returns the names of the registered dialects in sorted order.
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.dialects))
+	for name := range r.dialects {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	return names
+}
+
+// Parse parses the given grammar with the named dialect, returning an UnknownDialectError if it is not registered.
+//
+// If the dialect is DialectAuto the grammar is parsed with the dialect detected by Detect, returning an
+// UndetectedDialectError if it looks like neither.
+func (r *Registry) Parse(dialect, grammar string) (Grammar, error) {
+	return r.ParseContext(context.Background(), dialect, grammar)
+}
+
+// ParseContext parses the given grammar with the named dialect, like Parse, but stops if the context is cancelled or
+// its deadline passes.
+func (r *Registry) ParseContext(ctx context.Context, dialect, grammar string) (Grammar, error) {
+	if dialect == DialectAuto {
+		detection := Detect(grammar)
+		if detection.Dialect == "" {
+			return Grammar{}, &UndetectedDialectError{Detection: detection}
+		}
+		dialect = detection.Dialect
+	}
+	d, ok := r.Lookup(dialect)
+	if !ok {
+		return Grammar{}, &UnknownDialectError{Dialect: dialect}
+	}
+
+	return d.Parse(ctx, grammar)
+}
+
+// defaultRegistry is the Registry used by the package level functions.
+//
+//nolint:gochecknoglobals // the default registry is shared so that registered dialects are available everywhere
+var defaultRegistry = newDefaultRegistry()
+
+// newDefaultRegistry instantiates a Registry with the dialects of this module registered, which cannot share a name.
+func newDefaultRegistry() *Registry {
+	registry, err := NewRegistry(ISO(), W3C())
+	if err != nil {
+		panic(err)
+	}
+
+	return registry
+}
+
+// Register adds a dialect to the default Registry (see Registry.Register).
+func Register(dialect Dialect) error {
+	return defaultRegistry.Register(dialect)
+}
+
+// Lookup returns the dialect registered in the default Registry with the given name (see Registry.Lookup).
+func Lookup(name string) (Dialect, bool) {
+	return defaultRegistry.Lookup(name)
+}
+
+// Dialects returns the names of the dialects registered in the default Registry in sorted order.
+func Dialects() []string {
+	return defaultRegistry.Names()
+}
+
+// Parse parses the given grammar with the named dialect from the default Registry (see Registry.Parse).
+func Parse(dialect, grammar string) (Grammar, error) {
+	return defaultRegistry.Parse(dialect, grammar)
+}
+
+// ParseContext parses the given grammar with the named dialect from the default Registry, like Parse, but stops if the
+// context is cancelled or its deadline passes.
+func ParseContext(ctx context.Context, dialect, grammar string) (Grammar, error) {
+	return defaultRegistry.ParseContext(ctx, dialect, grammar)
+}
@@ -0,0 +1,248 @@
+package ebnf_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/alec-w/ebnf-go"
+	"github.com/alec-w/ebnf-go/diag"
+	"github.com/alec-w/ebnf-go/iso"
+	"github.com/alec-w/ebnf-go/w3c"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+	testCases := map[string]struct {
+		dialect          string
+		grammar          string
+		expectedNames    []string
+		expectedComments []string
+	}{
+		"iso": {
+			dialect:          ebnf.DialectISO,
+			grammar:          "(* digits *) digit = '0' | '1' ;\nnumber = digit, { digit } ;",
+			expectedNames:    []string{"digit", "number"},
+			expectedComments: []string{"digits"},
+		},
+		"w3c": {
+			dialect:          ebnf.DialectW3C,
+			grammar:          "/* digits */ digit ::= [0-1]\nnumber ::= digit+",
+			expectedNames:    []string{"digit", "number"},
+			expectedComments: []string{"digits"},
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			grammar, err := ebnf.Parse(tc.dialect, tc.grammar)
+			if err != nil {
+				t.Fatalf("Expected no error but got %s.", err)
+			}
+			if grammar.Dialect != tc.dialect {
+				t.Errorf("Expected dialect %q. Got %q.", tc.dialect, grammar.Dialect)
+			}
+			names := make([]string, 0, len(grammar.Rules))
+			for _, rule := range grammar.Rules {
+				names = append(names, rule.Name)
+			}
+			if !slices.Equal(names, tc.expectedNames) {
+				t.Errorf("Expected rules %q. Got %q.", tc.expectedNames, names)
+			}
+			if !slices.Equal(grammar.Rules[0].Comments, tc.expectedComments) {
+				t.Errorf("Expected comments %q. Got %q.", tc.expectedComments, grammar.Rules[0].Comments)
+			}
+			number, ok := grammar.Rule("number")
+			if !ok {
+				t.Fatal("Expected a rule named number.")
+			}
+			if line := number.Span.Start.Line; line != 2 {
+				t.Errorf("Expected rule number to start on line 2. Got %d.", line)
+			}
+		})
+	}
+}
+
+func TestParseSyntax(t *testing.T) {
+	t.Parallel()
+	grammar, err := ebnf.Parse(ebnf.DialectISO, "a = 'a' ;")
+	if err != nil {
+		t.Fatalf("Expected no error but got %s.", err)
+	}
+	if _, ok := grammar.Syntax.(iso.Syntax); !ok {
+		t.Errorf("Expected syntax of type iso.Syntax. Got %T.", grammar.Syntax)
+	}
+	grammar, err = ebnf.Parse(ebnf.DialectW3C, "a ::= 'a'")
+	if err != nil {
+		t.Fatalf("Expected no error but got %s.", err)
+	}
+	if _, ok := grammar.Syntax.(w3c.Syntax); !ok {
+		t.Errorf("Expected syntax of type w3c.Syntax. Got %T.", grammar.Syntax)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	t.Parallel()
+	testCases := map[string]struct {
+		dialect      string
+		grammar      string
+		expectedCode diag.Code
+	}{
+		"iso": {dialect: ebnf.DialectISO, grammar: "a = 'a", expectedCode: diag.CodeUnterminatedLiteral},
+		"w3c": {dialect: ebnf.DialectW3C, grammar: "a ::= 'a", expectedCode: diag.CodeUnterminatedLiteral},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			_, err := ebnf.Parse(tc.dialect, tc.grammar)
+			if !errors.Is(err, tc.expectedCode) {
+				t.Errorf("Expected error with code %s. Got %v.", tc.expectedCode, err)
+			}
+		})
+	}
+}
+
+func TestParseUnknownDialect(t *testing.T) {
+	t.Parallel()
+	_, err := ebnf.Parse("abnf", "rule = %x61")
+	var unknownErr *ebnf.UnknownDialectError
+	if !errors.As(err, &unknownErr) {
+		t.Fatalf("Expected an unknown dialect error. Got %v.", err)
+	}
+	if unknownErr.Dialect != "abnf" {
+		t.Errorf("Expected unknown dialect %q. Got %q.", "abnf", unknownErr.Dialect)
+	}
+}
+
+func TestDialects(t *testing.T) {
+	t.Parallel()
+	dialects := ebnf.Dialects()
+	for _, expected := range []string{ebnf.DialectISO, ebnf.DialectW3C} {
+		if !slices.Contains(dialects, expected) {
+			t.Errorf("Expected dialect %q to be registered by default. Got %q.", expected, dialects)
+		}
+		dialect, ok := ebnf.Lookup(expected)
+		if !ok {
+			t.Fatalf("Expected to look up dialect %q.", expected)
+		}
+		if dialect.Name() != expected {
+			t.Errorf("Expected dialect named %q. Got %q.", expected, dialect.Name())
+		}
+	}
+}
+
+// wordsDialect is a toy dialect with a rule for each word of a grammar, to test registering further dialects.
+type wordsDialect struct{}
+
+func (wordsDialect) Name() string {
+	return "words"
+}
+
+func (wordsDialect) Parse(ctx context.Context, grammar string) (ebnf.Grammar, error) {
+	if err := ctx.Err(); err != nil {
+		return ebnf.Grammar{}, err
+	}
+	var rules []ebnf.Rule
+	for word := range strings.FieldsSeq(grammar) {
+		rules = append(rules, ebnf.Rule{Name: word})
+	}
+
+	return ebnf.Grammar{Dialect: "words", Rules: rules}, nil
+}
+
+func TestRegistry(t *testing.T) {
+	t.Parallel()
+	registry, err := ebnf.NewRegistry(ebnf.ISO(), wordsDialect{})
+	if err != nil {
+		t.Fatalf("Expected no error but got %s.", err)
+	}
+	if names := registry.Names(); !slices.Equal(names, []string{"iso", "words"}) {
+		t.Errorf("Expected dialects %q. Got %q.", []string{"iso", "words"}, names)
+	}
+	grammar, err := registry.Parse("words", "a b")
+	if err != nil {
+		t.Fatalf("Expected no error but got %s.", err)
+	}
+	if len(grammar.Rules) != 2 || grammar.Rules[1].Name != "b" {
+		t.Errorf("Expected rules a and b. Got %+v.", grammar.Rules)
+	}
+	_, err = registry.Parse(ebnf.DialectW3C, "a ::= 'a'")
+	var unknownErr *ebnf.UnknownDialectError
+	if !errors.As(err, &unknownErr) {
+		t.Errorf("Expected an unknown dialect error. Got %v.", err)
+	}
+	err = registry.Register(ebnf.W3C())
+	if err != nil {
+		t.Fatalf("Expected no error registering w3c but got %s.", err)
+	}
+	err = registry.Register(wordsDialect{})
+	var duplicateErr *ebnf.DuplicateDialectError
+	if !errors.As(err, &duplicateErr) {
+		t.Fatalf("Expected a duplicate dialect error. Got %v.", err)
+	}
+	if duplicateErr.Dialect != "words" {
+		t.Errorf("Expected duplicate dialect %q. Got %q.", "words", duplicateErr.Dialect)
+	}
+	_, err = ebnf.NewRegistry(wordsDialect{}, wordsDialect{})
+	if !errors.As(err, &duplicateErr) {
+		t.Errorf("Expected a duplicate dialect error. Got %v.", err)
+	}
+	if err := ebnf.Register(ebnf.ISO()); !errors.As(err, &duplicateErr) {
+		t.Errorf("Expected a duplicate dialect error registering iso again. Got %v.", err)
+	}
+}
+
+func TestRegistryZeroValue(t *testing.T) {
+	t.Parallel()
+	var registry ebnf.Registry
+	if _, ok := registry.Lookup("words"); ok {
+		t.Errorf("Expected no dialects in an empty registry.")
+	}
+	if err := registry.Register(wordsDialect{}); err != nil {
+		t.Fatalf("Expected no error but got %s.", err)
+	}
+	if names := registry.Names(); !slices.Equal(names, []string{"words"}) {
+		t.Errorf("Expected dialects %q. Got %q.", []string{"words"}, names)
+	}
+}
+
+func TestRegistryDialectOptions(t *testing.T) {
+	t.Parallel()
+	registry, err := ebnf.NewRegistry(ebnf.ISO(iso.WithRecovery()), ebnf.W3C(w3c.WithRecovery()))
+	if err != nil {
+		t.Fatalf("Expected no error but got %s.", err)
+	}
+	testCases := map[string]struct {
+		dialect string
+		grammar string
+	}{
+		"iso": {dialect: ebnf.DialectISO, grammar: "a = ( 'a' ; b = 'b' ;"},
+		"w3c": {dialect: ebnf.DialectW3C, grammar: "a ::= #xZZ\nb ::= 'b'"},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			grammar, err := registry.Parse(tc.dialect, tc.grammar)
+			if err == nil {
+				t.Error("Expected an error but got none.")
+			}
+			if len(grammar.Rules) != 1 || grammar.Rules[0].Name != "b" {
+				t.Errorf("Expected the partial grammar to have rule b. Got %+v.", grammar.Rules)
+			}
+		})
+	}
+}
+
+func TestParseContext(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	for _, dialect := range []string{ebnf.DialectISO, ebnf.DialectW3C} {
+		_, err := ebnf.ParseContext(ctx, dialect, "a")
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected error parsing %s to wrap %s. Got %v.", dialect, context.Canceled, err)
+		}
+	}
+}
@@ -0,0 +1,10 @@
+// Package ebnf provides a single entrypoint for parsing an EBNF grammar written in any of the supported dialects (e.g.
+// ISO 14977 or the W3C notation) into a common, dialect-neutral Grammar.
+//
+// The definition of each rule of a Grammar is a normalised Expression (see Sequence, Choice, Optional, Star, Plus,
+// Repeat, Exception, Reference, Literal, CharClass and Special), converted from the syntax of its dialect (see FromISO
+// and FromW3C) so that analysis and code generation need only be written once.
+//
+// Dialects are looked up by name in a Registry, which by default has the "iso" and "w3c" dialects registered, and
+// further dialects can be added with Register.
+package ebnf
@@ -0,0 +1,53 @@
+package ebnf
+
+import "fmt"
+
+// UnknownDialectError is returned when a grammar is parsed with a dialect that is not registered.
+type UnknownDialectError struct {
+	Dialect string
+}
+
+func (u *UnknownDialectError) Error() string {
+	return fmt.Sprintf("unknown dialect %q", u.Dialect)
+}
+
+// UndetectedDialectError is returned when parsing a grammar with DialectAuto that does not look to be written in any
+// dialect.
+type UndetectedDialectError struct {
+	Detection Detection
+}
+
+func (u *UndetectedDialectError) Error() string {
+	return "could not detect the dialect of the grammar"
+}
+
+// DuplicateDialectError is returned when registering a dialect with the same name as one that is already registered.
+type DuplicateDialectError struct {
+	Dialect string
+}
+
+func (d *DuplicateDialectError) Error() string {
+	return fmt.Sprintf("dialect %q is already registered", d.Dialect)
+}
+
+// ReservedDialectError is returned when registering a dialect with a name that is reserved, such as DialectAuto.
+type ReservedDialectError struct {
+	Dialect string
+}
+
+func (r *ReservedDialectError) Error() string {
+	return fmt.Sprintf("dialect name %q is reserved", r.Dialect)
+}
+
+// JSONError is returned if there is an error marshalling a Grammar as JSON.
+type JSONError struct {
+	wrapped error
+}
+
+func (j *JSONError) Error() string {
+	return j.wrapped.Error()
+}
+
+func (j *JSONError) Unwrap() error {
+	return j.wrapped
+}
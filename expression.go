@@ -0,0 +1,234 @@
+package ebnf
+
+import (
+	"encoding/json"
+
+	"github.com/alec-w/ebnf-go/source"
+)
+
+// Expression is fulfilled by every node of the normalised representation of a rule's definition, which is the same
+// whichever dialect the grammar was written in.
+//
+// Expressions are normalised so that there is one way of representing each construct, e.g. a group of a single
+// expression is the expression itself and a sequence of one item is the item itself.
+type Expression interface {
+	// Span is the span of the source the expression was converted from.
+	Span() source.Span
+	expression()
+	node() *Node
+}
+
+// Node is embedded in every Expression, recording the span of the source it was converted from and the comments
+// written within it (such as those before a factor of an ISO 14977 grammar).
+type Node struct {
+	Source   source.Span `json:"-"`
+	Comments []string    `json:"-"`
+}
+
+// Span fulfils the Expression interface.
+func (n Node) Span() source.Span {
+	return n.Source
+}
+
+func (Node) expression() {}
+
+func (n *Node) node() *Node {
+	return n
+}
+
+var _ Expression = &Sequence{}
+
+// Sequence is an expression matched by each of its items in turn. A Sequence of no items matches the empty string.
+type Sequence struct {
+	Node
+
+	Items []Expression
+}
+
+// MarshalJSON fulfils the json.Marshaller interface.
+func (s *Sequence) MarshalJSON() ([]byte, error) {
+	items := s.Items
+	if items == nil {
+		items = []Expression{}
+	}
+
+	return s.marshal("sequence", items)
+}
+
+var _ Expression = &Choice{}
+
+// Choice is an expression matched by any one of its alternatives.
+type Choice struct {
+	Node
+
+	Alternatives []Expression
+}
+
+// MarshalJSON fulfils the json.Marshaller interface.
+func (c *Choice) MarshalJSON() ([]byte, error) {
+	return c.marshal("choice", c.Alternatives)
+}
+
+var _ Expression = &Optional{}
+
+// Optional is an expression matched by zero or one matches of its expression.
+type Optional struct {
+	Node
+
+	Expression Expression
+}
+
+// MarshalJSON fulfils the json.Marshaller interface.
+func (o *Optional) MarshalJSON() ([]byte, error) {
+	return o.marshal("optional", o.Expression)
+}
+
+var _ Expression = &Star{}
+
+// Star is an expression matched by zero or more matches of its expression.
+type Star struct {
+	Node
+
+	Expression Expression
+}
+
+// MarshalJSON fulfils the json.Marshaller interface.
+func (s *Star) MarshalJSON() ([]byte, error) {
+	return s.marshal("star", s.Expression)
+}
+
+var _ Expression = &Plus{}
+
+// Plus is an expression matched by one or more matches of its expression.
+type Plus struct {
+	Node
+
+	Expression Expression
+}
+
+// MarshalJSON fulfils the json.Marshaller interface.
+func (p *Plus) MarshalJSON() ([]byte, error) {
+	return p.marshal("plus", p.Expression)
+}
+
+var _ Expression = &Repeat{}
+
+// Repeat is an expression matched by between Min and Max (inclusive) matches of its expression.
+type Repeat struct {
+	Node
+
+	Min        int        `json:"min"`
+	Max        int        `json:"max"`
+	Expression Expression `json:"expression"`
+}
+
+// MarshalJSON fulfils the json.Marshaller interface.
+func (r *Repeat) MarshalJSON() ([]byte, error) {
+	type repeat Repeat
+
+	return r.marshal("repeat", (*repeat)(r))
+}
+
+var _ Expression = &Exception{}
+
+// Exception is an expression matched by anything matching its Match expression that does not match its Except
+// expression.
+type Exception struct {
+	Node
+
+	Match  Expression `json:"match"`
+	Except Expression `json:"except"`
+}
+
+// MarshalJSON fulfils the json.Marshaller interface.
+func (e *Exception) MarshalJSON() ([]byte, error) {
+	type exception Exception
+
+	return e.marshal("exception", (*exception)(e))
+}
+
+var _ Expression = &Reference{}
+
+// Reference is an expression matched by the definition of the rule with the given Name, or, if URI is set instead, by
+// the production it identifies in another specification.
+type Reference struct {
+	Node
+
+	Name string `json:"name,omitempty"`
+	URI  string `json:"uri,omitempty"`
+}
+
+// MarshalJSON fulfils the json.Marshaller interface.
+func (r *Reference) MarshalJSON() ([]byte, error) {
+	type reference Reference
+
+	return r.marshal("reference", (*reference)(r))
+}
+
+var _ Expression = &Literal{}
+
+// Literal is an expression matched by its text exactly.
+type Literal struct {
+	Node
+
+	Text string
+}
+
+// MarshalJSON fulfils the json.Marshaller interface.
+func (l *Literal) MarshalJSON() ([]byte, error) {
+	return l.marshal("literal", l.Text)
+}
+
+var _ Expression = &CharClass{}
+
+// CharClass is an expression matched by a single character that is one of its Chars or within any of its Ranges, or, if
+// it is Negated, by a single character that is none of them.
+type CharClass struct {
+	Node
+
+	Chars   []rune      `json:"chars,omitempty"`
+	Ranges  []CharRange `json:"ranges,omitempty"`
+	Negated bool        `json:"negated,omitempty"`
+}
+
+// MarshalJSON fulfils the json.Marshaller interface.
+func (c *CharClass) MarshalJSON() ([]byte, error) {
+	type charClass CharClass
+
+	return c.marshal("charClass", (*charClass)(c))
+}
+
+// CharRange is an inclusive range of characters.
+type CharRange struct {
+	Low  rune `json:"low"`
+	High rune `json:"high"`
+}
+
+var _ Expression = &Special{}
+
+// Special is an expression whose meaning is described by its text in a form outside the scope of EBNF.
+type Special struct {
+	Node
+
+	Text string
+}
+
+// MarshalJSON fulfils the json.Marshaller interface.
+func (s *Special) MarshalJSON() ([]byte, error) {
+	return s.marshal("special", s.Text)
+}
+
+// marshal marshals an expression as an object with its kind as the only key (the same as the expressions of the w3c
+// package), apart from its comments if it has any.
+func (n Node) marshal(kind string, value any) ([]byte, error) {
+	out := map[string]any{kind: value}
+	if len(n.Comments) > 0 {
+		out["comments"] = n.Comments
+	}
+	marshalled, err := json.Marshal(out)
+	if err != nil {
+		return nil, &JSONError{wrapped: err}
+	}
+
+	return marshalled, nil
+}
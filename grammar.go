@@ -0,0 +1,45 @@
+package ebnf
+
+import "github.com/alec-w/ebnf-go/source"
+
+// Grammar is the dialect-neutral representation of a parsed EBNF grammar.
+type Grammar struct {
+	// Dialect is the name of the dialect the grammar was written in.
+	Dialect          string   `json:"dialect"`
+	Rules            []Rule   `json:"rules"`
+	TrailingComments []string `json:"trailingComments,omitempty"`
+	// Syntax is the dialect-specific representation the Grammar was converted from, e.g. an iso.Syntax or w3c.Syntax.
+	Syntax any `json:"-"`
+}
+
+// Rule is a single rule of a Grammar.
+type Rule struct {
+	// Number is the number the rule is labelled with (a production number), if it has one.
+	Number string `json:"number,omitempty"`
+	// Name is the name the rule is referenced by (a meta identifier or symbol).
+	Name        string       `json:"name"`
+	Comments    []string     `json:"comments,omitempty"`
+	Expression  Expression   `json:"expression"`
+	Constraints []Constraint `json:"constraints,omitempty"`
+	Span        source.Span  `json:"-"`
+}
+
+// Constraint annotates a rule with a condition that its expression does not describe, such as a well-formedness or
+// validity constraint of the W3C notation.
+type Constraint struct {
+	// Kind is the kind of constraint (e.g. "wfc" or "vc").
+	Kind string      `json:"kind"`
+	Name string      `json:"name"`
+	Span source.Span `json:"-"`
+}
+
+// Rule returns the first rule in the Grammar with the given name, and whether there is one.
+func (g Grammar) Rule(name string) (Rule, bool) {
+	for _, rule := range g.Rules {
+		if rule.Name == name {
+			return rule, true
+		}
+	}
+
+	return Rule{}, false
+}
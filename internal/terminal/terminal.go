@@ -0,0 +1,11 @@
+// Package terminal provides helpers shared by the commands of this module for writing to a terminal.
+package terminal
+
+import "os"
+
+// IsTerminal returns true if the file is a terminal, in which case errors are rendered in colour.
+func IsTerminal(file *os.File) bool {
+	info, err := file.Stat()
+
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
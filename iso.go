@@ -0,0 +1,127 @@
+package ebnf
+
+import (
+	"context"
+
+	"github.com/alec-w/ebnf-go/iso"
+	"github.com/alec-w/ebnf-go/source"
+)
+
+// isoDialect adapts an iso.Parser to the Dialect interface.
+type isoDialect struct {
+	parser *iso.Parser
+}
+
+// ISO returns the dialect of ISO 14977 grammars, parsed by an iso.Parser configured with the given options.
+func ISO(opts ...iso.Option) Dialect {
+	return isoDialect{parser: iso.New(opts...)}
+}
+
+func (isoDialect) Name() string {
+	return DialectISO
+}
+
+func (d isoDialect) Parse(ctx context.Context, grammar string) (Grammar, error) {
+	syntax, err := d.parser.ParseContext(ctx, grammar)
+
+	return FromISO(syntax), err
+}
+
+// FromISO converts the Syntax of an ISO 14977 grammar to a Grammar.
+//
+// Every expression records the span of the node it was converted from, and the expression converted from a factor
+// records the factor's comments (those of a grouped sequence before the comments of the expression it groups). A
+// grouped sequence is converted to the expression it groups, and an empty primary to an empty Sequence.
+//
+// The brackets of a grouped sequence are not kept, and nor are the whitespace within a meta identifier (which is
+// removed from its Name) and the line of each rule (which is that of the start of its span).
+func FromISO(syntax iso.Syntax) Grammar {
+	grammar := Grammar{
+		Dialect:          DialectISO,
+		Rules:            make([]Rule, 0, len(syntax.Rules)),
+		TrailingComments: syntax.TrailingComments,
+		Syntax:           syntax,
+	}
+	for _, rule := range syntax.Rules {
+		grammar.Rules = append(grammar.Rules, Rule{
+			Name:       rule.MetaIdentifier,
+			Comments:   rule.Comments,
+			Expression: fromISODefinitionsList(rule.Definitions),
+			Span:       rule.Span,
+		})
+	}
+
+	return grammar
+}
+
+func fromISODefinitionsList(definitions iso.DefinitionsList) Expression {
+	if len(definitions) == 1 {
+		return fromISODefinition(definitions[0])
+	}
+	span := source.Span{Start: definitions[0].Span.Start, End: definitions[len(definitions)-1].Span.End}
+	choice := &Choice{Node: Node{Source: span}, Alternatives: make([]Expression, 0, len(definitions))}
+	for _, definition := range definitions {
+		choice.Alternatives = append(choice.Alternatives, fromISODefinition(definition))
+	}
+
+	return choice
+}
+
+func fromISODefinition(definition iso.Definition) Expression {
+	if len(definition.Terms) == 1 {
+		return fromISOTerm(definition.Terms[0])
+	}
+	sequence := &Sequence{Node: Node{Source: definition.Span}, Items: make([]Expression, 0, len(definition.Terms))}
+	for _, term := range definition.Terms {
+		sequence.Items = append(sequence.Items, fromISOTerm(term))
+	}
+
+	return sequence
+}
+
+func fromISOTerm(term iso.Term) Expression {
+	match := fromISOFactor(term.Factor)
+	if term.Exception.Primary.IsZero() {
+		return match
+	}
+
+	return &Exception{Node: Node{Source: term.Span}, Match: match, Except: fromISOFactor(term.Exception)}
+}
+
+func fromISOFactor(factor iso.Factor) Expression {
+	converted := fromISOPrimary(factor.Primary)
+	if factor.Repetitions >= 0 {
+		converted = &Repeat{
+			Node:       Node{Source: factor.Span},
+			Min:        factor.Repetitions,
+			Max:        factor.Repetitions,
+			Expression: converted,
+		}
+	}
+	if len(factor.Comments) > 0 {
+		node := converted.node()
+		node.Comments = append(append([]string(nil), factor.Comments...), node.Comments...)
+	}
+
+	return converted
+}
+
+func fromISOPrimary(primary iso.Primary) Expression {
+	node := Node{Source: primary.Span}
+	switch {
+	case primary.OptionalSequence != nil:
+		return &Optional{Node: node, Expression: fromISODefinitionsList(primary.OptionalSequence)}
+	case primary.RepeatedSequence != nil:
+		return &Star{Node: node, Expression: fromISODefinitionsList(primary.RepeatedSequence)}
+	case primary.GroupedSequence != nil:
+		return fromISODefinitionsList(primary.GroupedSequence)
+	case primary.MetaIdentifier != "":
+		return &Reference{Node: node, Name: primary.MetaIdentifier}
+	case primary.Terminal != "" || primary.EmptyTerminal:
+		return &Literal{Node: node, Text: primary.Terminal}
+	case primary.SpecialSequence != "" || primary.EmptySpecialSequence:
+		return &Special{Node: node, Text: primary.SpecialSequence}
+	default:
+		return &Sequence{Node: node}
+	}
+}
@@ -7,6 +7,8 @@ import (
 	"os"
 	"strings"
 
+	"github.com/alec-w/ebnf-go/diag"
+	"github.com/alec-w/ebnf-go/internal/terminal"
 	"github.com/alec-w/ebnf-go/iso"
 )
 
@@ -23,10 +25,27 @@ integer = "0" | nonZeroDigit, { digit } ;
 
 func main() {
 	parser := iso.New()
-	syntax, err := parser.Parse(sample)
+	grammar := sample
+	var name string
+	// A grammar file may be given to parse instead of the sample.
+	if len(os.Args) > 1 {
+		name = os.Args[1]
+		content, err := os.ReadFile(os.Args[1])
+		if err != nil {
+			//nolint:forbidigo // cmd/cli is for manual testing currently
+			fmt.Printf("Error: %s.\n", err)
+			os.Exit(1)
+		}
+		grammar = string(content)
+	}
+	// The grammar is kept to render any errors, so it is parsed from a reader to record the file name in them.
+	syntax, err := parser.ParseReader(name, strings.NewReader(grammar))
 	if err != nil {
-		//nolint:forbidigo // cmd/cli is for manual testing currently
-		fmt.Printf("Error: %s.\n", err)
+		var opts []diag.Option
+		if terminal.IsTerminal(os.Stderr) {
+			opts = append(opts, diag.WithColour())
+		}
+		_ = diag.NewRenderer(opts...).Render(os.Stderr, grammar, err)
 		os.Exit(1)
 	}
 	out := new(strings.Builder)
@@ -0,0 +1,186 @@
+package iso
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/alec-w/ebnf-go/cst"
+	"github.com/alec-w/ebnf-go/source"
+)
+
+// The kinds of the nodes of the concrete syntax tree of an ISO 14977 grammar, named after the syntax they represent.
+const (
+	KindSyntax           cst.Kind = "syntax"
+	KindRule             cst.Kind = "rule"
+	KindDefinition       cst.Kind = "definition"
+	KindTerm             cst.Kind = "term"
+	KindFactor           cst.Kind = "factor"
+	KindOptionalSequence cst.Kind = "optional-sequence"
+	KindRepeatedSequence cst.Kind = "repeated-sequence"
+	KindGroupedSequence  cst.Kind = "grouped-sequence"
+	// KindMetaIdentifier is the kind of the token of a meta identifier, including any whitespace within it.
+	KindMetaIdentifier cst.Kind = "meta-identifier"
+	// KindTerminal is the kind of the token of a terminal string, including its quotes.
+	KindTerminal cst.Kind = "terminal"
+	// KindSpecialSequence is the kind of the token of a special sequence, including its special sequence symbols.
+	KindSpecialSequence     cst.Kind = "special-sequence"
+	KindInteger             cst.Kind = "integer"
+	KindDefiningSymbol      cst.Kind = "defining-symbol"
+	KindTerminatorSymbol    cst.Kind = "terminator-symbol"
+	KindDefinitionSeparator cst.Kind = "definition-separator-symbol"
+	KindConcatenateSymbol   cst.Kind = "concatenate-symbol"
+	KindExceptSymbol        cst.Kind = "except-symbol"
+	KindRepetitionSymbol    cst.Kind = "repetition-symbol"
+	KindStartOptionSymbol   cst.Kind = "start-option-symbol"
+	KindEndOptionSymbol     cst.Kind = "end-option-symbol"
+	KindStartRepeatSymbol   cst.Kind = "start-repeat-symbol"
+	KindEndRepeatSymbol     cst.Kind = "end-repeat-symbol"
+	KindStartGroupSymbol    cst.Kind = "start-group-symbol"
+	KindEndGroupSymbol      cst.Kind = "end-group-symbol"
+)
+
+// symbolKinds are the kinds of the tokens of the symbols of the syntax, which are checked in order so a symbol must
+// come before any other symbol it starts with.
+//
+//nolint:gochecknoglobals // the symbols are fixed, a global avoids rebuilding them for every token
+var symbolKinds = []struct {
+	symbol string
+	kind   cst.Kind
+}{
+	{symbol: "(/", kind: KindStartOptionSymbol},
+	{symbol: "(:", kind: KindStartRepeatSymbol},
+	{symbol: "/)", kind: KindEndOptionSymbol},
+	{symbol: ":)", kind: KindEndRepeatSymbol},
+	{symbol: "=", kind: KindDefiningSymbol},
+	{symbol: ";", kind: KindTerminatorSymbol},
+	{symbol: ".", kind: KindTerminatorSymbol},
+	{symbol: "|", kind: KindDefinitionSeparator},
+	{symbol: "/", kind: KindDefinitionSeparator},
+	{symbol: "!", kind: KindDefinitionSeparator},
+	{symbol: ",", kind: KindConcatenateSymbol},
+	{symbol: "-", kind: KindExceptSymbol},
+	{symbol: "*", kind: KindRepetitionSymbol},
+	{symbol: "[", kind: KindStartOptionSymbol},
+	{symbol: "]", kind: KindEndOptionSymbol},
+	{symbol: "{", kind: KindStartRepeatSymbol},
+	{symbol: "}", kind: KindEndRepeatSymbol},
+	{symbol: "(", kind: KindStartGroupSymbol},
+	{symbol: ")", kind: KindEndGroupSymbol},
+}
+
+// concreteSyntaxTree builds the concrete syntax tree of the grammar from the rules of its syntax, which may be partial
+// if the parser recovered from errors, in which case the text of the rules that could not be parsed is kept as tokens
+// of the syntax.
+func (p *parser) concreteSyntaxTree(syntax Syntax) *cst.Node {
+	root := &cst.Node{Kind: KindSyntax, Span: p.file.Span(0, len(p.source))}
+	var branches []*cst.Node
+	for _, rule := range syntax.Rules {
+		branches = append(branches, &cst.Node{Kind: KindRule, Span: rule.Span})
+		branches = appendDefinitionsList(branches, rule.Definitions)
+	}
+
+	return cst.Build(root, branches, p.tokens())
+}
+
+// appendDefinitionsList appends the branches of each definition of the list, and of the syntax within them, in order.
+func appendDefinitionsList(branches []*cst.Node, definitions DefinitionsList) []*cst.Node {
+	for _, definition := range definitions {
+		branches = append(branches, &cst.Node{Kind: KindDefinition, Span: definition.Span})
+		for _, term := range definition.Terms {
+			branches = append(branches, &cst.Node{Kind: KindTerm, Span: term.Span})
+			branches = appendFactor(branches, term.Factor)
+			if !term.Exception.Primary.IsZero() {
+				branches = appendFactor(branches, term.Exception)
+			}
+		}
+	}
+
+	return branches
+}
+
+// appendFactor appends the branches of the factor, and of any sequence that is its primary, in order.
+func appendFactor(branches []*cst.Node, factor Factor) []*cst.Node {
+	branches = append(branches, &cst.Node{Kind: KindFactor, Span: factor.Span})
+	primary := factor.Primary
+	switch {
+	case primary.OptionalSequence != nil:
+		branches = append(branches, &cst.Node{Kind: KindOptionalSequence, Span: primary.Span})
+		branches = appendDefinitionsList(branches, primary.OptionalSequence)
+	case primary.RepeatedSequence != nil:
+		branches = append(branches, &cst.Node{Kind: KindRepeatedSequence, Span: primary.Span})
+		branches = appendDefinitionsList(branches, primary.RepeatedSequence)
+	case primary.GroupedSequence != nil:
+		branches = append(branches, &cst.Node{Kind: KindGroupedSequence, Span: primary.Span})
+		branches = appendDefinitionsList(branches, primary.GroupedSequence)
+	}
+
+	return branches
+}
+
+// tokens splits the whole grammar into tokens, each of which spans the text that would be parsed as it. Text that is
+// not a token (e.g. an unterminated terminal) is split into tokens of each character, of the kind cst.KindError.
+func (p *parser) tokens() []*cst.Node {
+	var tokens []*cst.Node
+	offset := 0
+	if strings.HasPrefix(p.source, source.BOM) {
+		offset = len(source.BOM)
+		tokens = append(tokens, &cst.Node{Kind: cst.KindByteOrderMark, Text: source.BOM, Span: p.file.Span(0, offset)})
+	}
+	for offset < len(p.source) {
+		kind, end := p.token(offset)
+		tokens = append(tokens, &cst.Node{Kind: kind, Text: p.source[offset:end], Span: p.file.Span(offset, end)})
+		offset = end
+	}
+
+	return tokens
+}
+
+// token returns the kind and end of the token at the given offset, which is found by parsing it as the parser would.
+func (p *parser) token(offset int) (cst.Kind, int) {
+	// The token is parsed by a parser of its own, without limits, so that it is not affected by the state of the parse.
+	probe := &parser{source: p.source, offset: offset, file: p.file}
+	rest := p.source[offset:]
+	char, width := utf8.DecodeRuneInString(rest)
+	switch {
+	case unicode.IsSpace(char):
+		end := strings.IndexFunc(rest, func(char rune) bool { return !unicode.IsSpace(char) })
+		if end < 0 {
+			end = len(rest)
+		}
+
+		return cst.KindWhitespace, offset + end
+	case strings.HasPrefix(rest, "(*"):
+		if _, err := probe.parseComment(); err == nil {
+			return cst.KindComment, probe.offset
+		}
+	case unicode.IsLetter(char):
+		_, end, _ := probe.parseMetaIdentifier()
+
+		return KindMetaIdentifier, end
+	case unicode.IsDigit(char):
+		for end := offset; ; {
+			digit, width := utf8.DecodeRuneInString(p.source[end:])
+			if !unicode.IsDigit(digit) {
+				return KindInteger, end
+			}
+			end += width
+		}
+	case char == '\'' || char == '"':
+		if _, err := probe.parseTerminal(); err == nil {
+			return KindTerminal, probe.offset
+		}
+	case char == '?':
+		if _, err := probe.parseSpecialSequence(); err == nil {
+			return KindSpecialSequence, probe.offset
+		}
+	default:
+		for _, symbol := range symbolKinds {
+			if strings.HasPrefix(rest, symbol.symbol) {
+				return symbol.kind, offset + len(symbol.symbol)
+			}
+		}
+	}
+
+	return cst.KindError, offset + width
+}
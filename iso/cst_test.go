@@ -0,0 +1,174 @@
+package iso_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alec-w/ebnf-go/cst"
+	"github.com/alec-w/ebnf-go/iso"
+)
+
+func TestParseCST(t *testing.T) {
+	t.Parallel()
+	testCases := map[string]struct {
+		grammar  string
+		opts     []iso.Option
+		expected string
+	}{
+		"rule": {
+			grammar: "a = 'b' ;",
+			expected: `(syntax (rule meta-identifier:"a" whitespace:" " defining-symbol:"=" whitespace:" " ` +
+				`(definition (term (factor terminal:"'b'"))) whitespace:" " terminator-symbol:";"))`,
+		},
+		"whitespace within meta identifiers": {
+			grammar: "non zero digit = digit\tone.",
+			expected: `(syntax (rule meta-identifier:"non zero digit" whitespace:" " defining-symbol:"=" ` +
+				`whitespace:" " (definition (term (factor meta-identifier:"digit\tone"))) terminator-symbol:"."))`,
+		},
+		"comments": {
+			grammar: "(* a *) a (* b *) = (* c *) 2 (* d *) * b ; (* e *)",
+			expected: `(syntax comment:"(* a *)" whitespace:" " (rule meta-identifier:"a" whitespace:" " ` +
+				`comment:"(* b *)" whitespace:" " defining-symbol:"=" whitespace:" " comment:"(* c *)" ` +
+				`whitespace:" " (definition (term (factor integer:"2" whitespace:" " comment:"(* d *)" ` +
+				`whitespace:" " repetition-symbol:"*" whitespace:" " meta-identifier:"b"))) whitespace:" " ` +
+				`terminator-symbol:";") whitespace:" " comment:"(* e *)")`,
+		},
+		"sequences": {
+			grammar: "a = [b] | {c}, (d - e) / (/f/) ! (:g:) ;",
+			expected: `(syntax (rule meta-identifier:"a" whitespace:" " defining-symbol:"=" whitespace:" " ` +
+				`(definition (term (factor (optional-sequence start-option-symbol:"[" ` +
+				`(definition (term (factor meta-identifier:"b"))) end-option-symbol:"]")))) whitespace:" " ` +
+				`definition-separator-symbol:"|" whitespace:" " (definition (term (factor (repeated-sequence ` +
+				`start-repeat-symbol:"{" (definition (term (factor meta-identifier:"c"))) end-repeat-symbol:"}")))` +
+				` concatenate-symbol:"," whitespace:" " (term (factor (grouped-sequence start-group-symbol:"(" ` +
+				`(definition (term (factor meta-identifier:"d") whitespace:" " except-symbol:"-" whitespace:" " ` +
+				`(factor meta-identifier:"e"))) end-group-symbol:")")))) whitespace:" " ` +
+				`definition-separator-symbol:"/" whitespace:" " (definition (term (factor (optional-sequence ` +
+				`start-option-symbol:"(/" (definition (term (factor meta-identifier:"f"))) ` +
+				`end-option-symbol:"/)")))) whitespace:" " definition-separator-symbol:"!" whitespace:" " ` +
+				`(definition (term (factor (repeated-sequence start-repeat-symbol:"(:" (definition (term ` +
+				`(factor meta-identifier:"g"))) end-repeat-symbol:":)")))) whitespace:" " terminator-symbol:";"))`,
+		},
+		"special sequence and empty definition": {
+			grammar: "a = ? b ? | ;",
+			expected: `(syntax (rule meta-identifier:"a" whitespace:" " defining-symbol:"=" whitespace:" " ` +
+				`(definition (term (factor special-sequence:"? b ?"))) whitespace:" " ` +
+				`definition-separator-symbol:"|" whitespace:" " (definition (term (factor))) terminator-symbol:";"))`,
+		},
+		"byte order mark": {
+			grammar: "\uFEFFa=b;\r\n",
+			expected: `(syntax byte-order-mark:"\ufeff" (rule meta-identifier:"a" defining-symbol:"=" ` +
+				`(definition (term (factor meta-identifier:"b"))) terminator-symbol:";") whitespace:"\r\n")`,
+		},
+		"recovery from an unterminated terminal": {
+			grammar: "a = 'b ;\nc = d ;",
+			opts:    []iso.Option{iso.WithRecovery()},
+			expected: `(syntax meta-identifier:"a" whitespace:" " defining-symbol:"=" whitespace:" " ` +
+				`error:"'" meta-identifier:"b" whitespace:" " terminator-symbol:";" whitespace:"\n" (rule ` +
+				`meta-identifier:"c" whitespace:" " defining-symbol:"=" whitespace:" " (definition (term ` +
+				`(factor meta-identifier:"d"))) whitespace:" " terminator-symbol:";"))`,
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			syntax, _ := iso.New(append(tc.opts, iso.WithCST())...).Parse(tc.grammar)
+			if syntax.CST == nil {
+				t.Fatalf("Expected a concrete syntax tree.")
+			}
+			if actual := formatTree(syntax.CST); actual != tc.expected {
+				t.Errorf("Expected tree:\n%s\nGot:\n%s", tc.expected, actual)
+			}
+			if actual := syntax.CST.String(); actual != tc.grammar {
+				t.Errorf("Expected tree to reproduce %q. Got %q.", tc.grammar, actual)
+			}
+		})
+	}
+}
+
+func TestParseCSTNotBuilt(t *testing.T) {
+	t.Parallel()
+	syntax, err := iso.New().Parse("a = b ;")
+	if err != nil {
+		t.Fatalf("Expected no error but got %s.", err)
+	}
+	if syntax.CST != nil {
+		t.Errorf("Expected no concrete syntax tree without WithCST.")
+	}
+	syntax, err = iso.New(iso.WithCST()).Parse("a = b")
+	if err == nil || syntax.CST != nil {
+		t.Errorf("Expected an error and no concrete syntax tree. Got %v and %v.", err, syntax.CST)
+	}
+}
+
+func TestParseCSTCorpus(t *testing.T) {
+	t.Parallel()
+	grammars, err := filepath.Glob(filepath.Join("testdata", "corpus", "*.ebnf"))
+	if err != nil {
+		t.Fatalf("Expected no error finding corpus grammars but got %s.", err)
+	}
+	for _, grammar := range grammars {
+		t.Run(filepath.Base(grammar), func(t *testing.T) {
+			t.Parallel()
+			content, err := os.ReadFile(grammar)
+			if err != nil {
+				t.Fatalf("Expected no error reading grammar but got %s.", err)
+			}
+			syntax, err := iso.New(iso.WithCST()).Parse(string(content))
+			if err != nil {
+				t.Fatalf("Expected no error but got %s.", err)
+			}
+			if syntax.CST.String() != string(content) {
+				t.Errorf("Expected tree to reproduce the grammar.")
+			}
+			rules := 0
+			for _, child := range syntax.CST.Children {
+				if child.Kind == iso.KindRule {
+					rules++
+				}
+				if child.Kind == cst.KindError {
+					t.Errorf("Expected no errors in the tree. Got %q at %s.", child.Text, child.Span.Start)
+				}
+			}
+			if rules != len(syntax.Rules) {
+				t.Errorf("Expected %d rules in the tree. Got %d.", len(syntax.Rules), rules)
+			}
+		})
+	}
+}
+
+func FuzzParseCST(f *testing.F) {
+	for _, seed := range []string{
+		"a = 'b' ;",
+		"non zero digit = (* c *) 2 * { d } - e, [ f ] | ( g ) ; (* h *)",
+		"a = (/ b /) ! (: c :) / ? d ? .",
+		"a = 'b ; c = (* d ;",
+	} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, grammar string) {
+		syntax, _ := iso.New(iso.WithCST(), iso.WithRecovery()).Parse(grammar)
+		if syntax.CST == nil {
+			return
+		}
+		if actual := syntax.CST.String(); actual != grammar {
+			t.Errorf("Expected tree to reproduce %q. Got %q.", grammar, actual)
+		}
+	})
+}
+
+// formatTree returns the tree as an s-expression, with each token as its kind and quoted text.
+func formatTree(node *cst.Node) string {
+	if node.IsToken() {
+		return fmt.Sprintf("%s:%q", node.Kind, node.Text)
+	}
+	parts := []string{string(node.Kind)}
+	for _, child := range node.Children {
+		parts = append(parts, formatTree(child))
+	}
+
+	return "(" + strings.Join(parts, " ") + ")"
+}
@@ -0,0 +1,157 @@
+package iso
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/alec-w/ebnf-go/source"
+)
+
+// Editor records changes to a grammar, made through the Syntax it was parsed to, as edits of its source (see
+// source.Edit) that leave the rest of the source, with its layout and comments, as it was.
+//
+// Every change is made to the grammar as it was parsed, so rules are found by the meta identifiers they were parsed
+// with, and a change within a rule that has been deleted is dropped.
+type Editor struct {
+	grammar string
+	syntax  Syntax
+	edits   []source.Edit
+}
+
+// NewEditor instantiates an Editor of the grammar, which must be the grammar the syntax was parsed from.
+func NewEditor(grammar string, syntax Syntax) *Editor {
+	return &Editor{grammar: grammar, syntax: syntax}
+}
+
+// Edits returns the edits of the grammar, ordered by offset, which can be made to it with source.Apply.
+func (e *Editor) Edits() []source.Edit {
+	return append([]source.Edit(nil), e.edits...)
+}
+
+// RenameRule changes the meta identifier of the rules with the given meta identifier (ignoring any whitespace within
+// it), and of every reference to them, to the new meta identifier. It returns an EditError if another rule already
+// has the new meta identifier.
+func (e *Editor) RenameRule(metaIdentifier, newMetaIdentifier string) error {
+	metaIdentifier = removeWhitespace(metaIdentifier)
+	if !isMetaIdentifier(newMetaIdentifier) {
+		return &EditError{MetaIdentifier: metaIdentifier, Msg: newMetaIdentifier + " is not a valid meta identifier"}
+	}
+	rules := e.rules(metaIdentifier)
+	if len(rules) == 0 {
+		return e.unknownRuleError(metaIdentifier)
+	}
+	if name := removeWhitespace(newMetaIdentifier); name != metaIdentifier && len(e.rules(name)) > 0 {
+		return &EditError{MetaIdentifier: metaIdentifier, Msg: newMetaIdentifier + " is already defined"}
+	}
+	edits := make([]source.Edit, 0, len(rules))
+	for _, rule := range rules {
+		probe := &parser{source: e.grammar, offset: rule.Span.Start.Offset, file: source.NewFile(e.grammar)}
+		_, end, _ := probe.parseMetaIdentifier()
+		edits = append(edits, source.Edit{Start: rule.Span.Start.Offset, End: end, Text: newMetaIdentifier})
+	}
+	for _, rule := range e.syntax.Rules {
+		forEachMetaIdentifier(rule.Definitions, func(primary Primary) {
+			if primary.MetaIdentifier == metaIdentifier {
+				edits = append(edits, source.Edit{
+					Start: primary.Span.Start.Offset,
+					End:   primary.Span.End.Offset,
+					Text:  newMetaIdentifier,
+				})
+			}
+		})
+	}
+
+	return e.merge(metaIdentifier, edits...)
+}
+
+// AddAlternative adds a definition (or several, separated by definition separator symbols) as an alternative to the
+// definitions of the first rule with the given meta identifier, after its last definition.
+func (e *Editor) AddAlternative(metaIdentifier, definition string) error {
+	metaIdentifier = removeWhitespace(metaIdentifier)
+	rules := e.rules(metaIdentifier)
+	if len(rules) == 0 {
+		return e.unknownRuleError(metaIdentifier)
+	}
+	// The definition must parse as the definitions of a rule of its own.
+	syntax, err := New().Parse("alternative = " + definition + " ;")
+	if err != nil || len(syntax.Rules) != 1 {
+		return &EditError{
+			MetaIdentifier: metaIdentifier,
+			Msg:            definition + " is not a valid definition",
+			wrapped:        err,
+		}
+	}
+	definitions := rules[0].Definitions
+	end := definitions[len(definitions)-1].Span.End.Offset
+
+	return e.merge(metaIdentifier, source.Edit{Start: end, End: end, Text: " | " + definition})
+}
+
+// DeleteRule deletes the rules with the given meta identifier, along with the whitespace around each up to the end of
+// its line, and the whitespace before it on its line if nothing else is.
+//
+// Comments on the rules are kept, as are references to them from other rules.
+func (e *Editor) DeleteRule(metaIdentifier string) error {
+	metaIdentifier = removeWhitespace(metaIdentifier)
+	rules := e.rules(metaIdentifier)
+	if len(rules) == 0 {
+		return e.unknownRuleError(metaIdentifier)
+	}
+	edits := make([]source.Edit, 0, len(rules))
+	for _, rule := range rules {
+		start, end := source.LineExtent(e.grammar, rule.Span.Start.Offset, rule.Span.End.Offset)
+		edits = append(edits, source.Edit{Start: start, End: end})
+	}
+
+	return e.merge(metaIdentifier, edits...)
+}
+
+// rules returns the rules with the given meta identifier.
+func (e *Editor) rules(metaIdentifier string) []Rule {
+	var rules []Rule
+	for _, rule := range e.syntax.Rules {
+		if rule.MetaIdentifier == metaIdentifier {
+			rules = append(rules, rule)
+		}
+	}
+
+	return rules
+}
+
+// merge merges the edits of a change to the rule with the given meta identifier into the edits of the grammar, which
+// are left unchanged if any of them cannot be merged.
+func (e *Editor) merge(metaIdentifier string, edits ...source.Edit) error {
+	merged := e.edits
+	for _, edit := range edits {
+		var err error
+		if merged, err = source.MergeEdit(merged, edit); err != nil {
+			return &EditError{MetaIdentifier: metaIdentifier, Msg: err.Error(), wrapped: err}
+		}
+	}
+	e.edits = merged
+
+	return nil
+}
+
+func (e *Editor) unknownRuleError(metaIdentifier string) *EditError {
+	return &EditError{MetaIdentifier: metaIdentifier, Msg: "there is no rule with this meta identifier"}
+}
+
+// isMetaIdentifier returns true if the text is a meta identifier, a letter followed by letters, digits and whitespace
+// (between them).
+func isMetaIdentifier(text string) bool {
+	first, _ := utf8.DecodeRuneInString(text)
+	if !unicode.IsLetter(first) || strings.TrimRightFunc(text, unicode.IsSpace) != text {
+		return false
+	}
+
+	return strings.IndexFunc(text, func(char rune) bool {
+		return !unicode.IsLetter(char) && !unicode.IsDigit(char) && !unicode.IsSpace(char)
+	}) < 0
+}
+
+// removeWhitespace returns the text without any whitespace, as meta identifiers are parsed.
+func removeWhitespace(text string) string {
+	return strings.Join(strings.Fields(text), "")
+}
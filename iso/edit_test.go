@@ -0,0 +1,128 @@
+package iso_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/alec-w/ebnf-go/iso"
+	"github.com/alec-w/ebnf-go/source"
+)
+
+func TestEditor(t *testing.T) {
+	t.Parallel()
+	grammar := "(* A list. *)\nlist = item, { ',' , item } ;\n\n  item = letter | digit ; (* An item. *)\n" +
+		"letter = 'a' ;\ndigit (* A digit. *) = '0' | '1' ;\n"
+	tcs := []struct {
+		name     string
+		edit     func(*iso.Editor) error
+		expected string
+		err      bool
+	}{
+		{
+			name: "rename a rule",
+			edit: func(e *iso.Editor) error { return e.RenameRule("item", "element") },
+			expected: "(* A list. *)\nlist = element, { ',' , element } ;\n\n  element = letter | digit ; (* An item. *)\n" +
+				"letter = 'a' ;\ndigit (* A digit. *) = '0' | '1' ;\n",
+		},
+		{
+			name: "rename a rule to a meta identifier with whitespace",
+			edit: func(e *iso.Editor) error { return e.RenameRule("digit", "decimal digit") },
+			expected: "(* A list. *)\nlist = item, { ',' , item } ;\n\n  item = letter | decimal digit ; (* An item. *)\n" +
+				"letter = 'a' ;\ndecimal digit (* A digit. *) = '0' | '1' ;\n",
+		},
+		{
+			name: "rename a rule to its own meta identifier",
+			edit: func(e *iso.Editor) error { return e.RenameRule("digit", "dig it") },
+			expected: "(* A list. *)\nlist = item, { ',' , item } ;\n\n  item = letter | dig it ; (* An item. *)\n" +
+				"letter = 'a' ;\ndig it (* A digit. *) = '0' | '1' ;\n",
+		},
+		{
+			name: "add an alternative",
+			edit: func(e *iso.Editor) error { return e.AddAlternative("digit", "'2' | '3'") },
+			expected: "(* A list. *)\nlist = item, { ',' , item } ;\n\n  item = letter | digit ; (* An item. *)\n" +
+				"letter = 'a' ;\ndigit (* A digit. *) = '0' | '1' | '2' | '3' ;\n",
+		},
+		{
+			name: "delete a rule alone on its line",
+			edit: func(e *iso.Editor) error { return e.DeleteRule("letter") },
+			expected: "(* A list. *)\nlist = item, { ',' , item } ;\n\n  item = letter | digit ; (* An item. *)\n" +
+				"digit (* A digit. *) = '0' | '1' ;\n",
+		},
+		{
+			name: "delete a rule followed by a comment",
+			edit: func(e *iso.Editor) error { return e.DeleteRule("item") },
+			expected: "(* A list. *)\nlist = item, { ',' , item } ;\n\n  (* An item. *)\nletter = 'a' ;\n" +
+				"digit (* A digit. *) = '0' | '1' ;\n",
+		},
+		{
+			name: "several edits",
+			edit: func(e *iso.Editor) error {
+				return errors.Join(
+					e.AddAlternative("item", "list"),
+					e.RenameRule("item", "element"),
+					e.DeleteRule("letter"),
+					e.RenameRule("letter", "char"),
+				)
+			},
+			expected: "(* A list. *)\nlist = element, { ',' , element } ;\n\n  element = char | digit | list ; " +
+				"(* An item. *)\ndigit (* A digit. *) = '0' | '1' ;\n",
+		},
+		{name: "rename an unknown rule", edit: func(e *iso.Editor) error { return e.RenameRule("x", "y") }, err: true},
+		{
+			name: "rename to an invalid meta identifier",
+			edit: func(e *iso.Editor) error { return e.RenameRule("item", "1") },
+			err:  true,
+		},
+		{
+			name: "rename to a defined meta identifier",
+			edit: func(e *iso.Editor) error { return e.RenameRule("letter", "dig it") },
+			err:  true,
+		},
+		{
+			name: "add an invalid alternative",
+			edit: func(e *iso.Editor) error { return e.AddAlternative("item", "'a") },
+			err:  true,
+		},
+		{
+			name: "add several rules as an alternative",
+			edit: func(e *iso.Editor) error { return e.AddAlternative("item", "a ; b = c") },
+			err:  true,
+		},
+		{name: "delete an unknown rule", edit: func(e *iso.Editor) error { return e.DeleteRule("x") }, err: true},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			syntax, err := iso.New().Parse(grammar)
+			if err != nil {
+				t.Fatalf("Expected no error parsing the grammar but got %q.", err)
+			}
+			editor := iso.NewEditor(grammar, syntax)
+			err = tc.edit(editor)
+			if tc.err {
+				var editErr *iso.EditError
+				if !errors.As(err, &editErr) {
+					t.Errorf("Expected an edit error but got %v.", err)
+				}
+				if len(editor.Edits()) != 0 {
+					t.Errorf("Expected no edits but got %#v.", editor.Edits())
+				}
+
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error but got %q.", err)
+			}
+			actual, err := source.Apply(grammar, editor.Edits())
+			if err != nil {
+				t.Fatalf("Expected no error applying the edits but got %q.", err)
+			}
+			if actual != tc.expected {
+				t.Errorf("Expected edited grammar %q but got %q.", tc.expected, actual)
+			}
+			if _, err := iso.New().Parse(actual); err != nil {
+				t.Errorf("Expected the edited grammar to parse but got %q.", err)
+			}
+		})
+	}
+}
@@ -1,6 +1,11 @@
 package iso
 
-import "fmt"
+import (
+	"fmt"
+
+	"github.com/alec-w/ebnf-go/diag"
+	"github.com/alec-w/ebnf-go/source"
+)
 
 // JSONError is returned if there is an error marshalling a Syntax as JSON.
 type JSONError struct {
@@ -15,38 +20,164 @@ func (j *JSONError) Unwrap() error {
 	return j.wrapped
 }
 
+// PrintError is returned if there is an error writing a printed Syntax.
+type PrintError struct {
+	wrapped error
+}
+
+func (p *PrintError) Error() string {
+	return "failed printing grammar: " + p.wrapped.Error()
+}
+
+func (p *PrintError) Unwrap() error {
+	return p.wrapped
+}
+
+// ReadError is returned if there is an error reading an EBNF grammar from a file or io.Reader.
+type ReadError struct {
+	// Filename is the name of the file the grammar was being read from.
+	Filename string
+	wrapped  error
+}
+
+func (r *ReadError) Error() string {
+	return fmt.Sprintf("failed reading grammar from %s: %s", r.Filename, r.wrapped)
+}
+
+func (r *ReadError) Unwrap() error {
+	return r.wrapped
+}
+
 // ParseRuleError is returned to indicate which rule was being parsed when an error occurred parsing an EBNF grammar.
 // Receivers of this error should unwrap it to get more detail on the original parse error itself.
 type ParseRuleError struct {
+	// Filename is the name of the file containing the grammar, if it was parsed from a file.
+	Filename       string
 	MetaIdentifier string
 	Line           int
 	Wrapped        *ParseError
 }
 
 func (p *ParseRuleError) Error() string {
+	msg := fmt.Sprintf("failed parsing rule beginning on line %d", p.Line)
 	if p.MetaIdentifier != "" {
-		return fmt.Sprintf("failed parsing rule %s beginning on line %d", p.MetaIdentifier, p.Line)
+		msg = fmt.Sprintf("failed parsing rule %s beginning on line %d", p.MetaIdentifier, p.Line)
+	}
+	if p.Filename != "" {
+		return p.Filename + ": " + msg
 	}
 
-	return fmt.Sprintf("failed parsing rule beginning on line %d", p.Line)
+	return msg
 }
 
 func (p *ParseRuleError) Unwrap() error {
 	return p.Wrapped
 }
 
+// Diagnostic describes the wrapped parse error within the rule being parsed, for rendering (see diag.Renderer).
+func (p *ParseRuleError) Diagnostic() diag.Diagnostic {
+	if p.Wrapped == nil {
+		return diag.Diagnostic{
+			Message:  p.Error(),
+			Position: source.Position{Filename: p.Filename, Line: p.Line, Column: 1},
+		}
+	}
+	diagnostic := p.Wrapped.Diagnostic()
+	diagnostic.Rule = p.MetaIdentifier
+
+	return diagnostic
+}
+
 // ParseError is returned if there is an error parsing an EBNF grammar.
+//
+// Expected lists the tokens that would have been acceptable where the error occurred and Found is the token that was
+// found instead, using the descriptions of diag.Literal and diag.FoundToken. They are empty for errors that are not
+// caused by an unexpected token. Errors for unterminated constructs are reported at the opening delimiter, but Found
+// describes the token where the closing delimiter was expected.
 type ParseError struct {
-	Msg     string
-	wrapped error
-	Offset  int
-	Line    int
+	// Code identifies the kind of the error, and can be checked with errors.Is (see diag.Code).
+	Code diag.Code
+	Msg  string
+	// Filename is the name of the file containing the grammar, if it was parsed from a file.
+	Filename string
+	wrapped  error
+	Offset   int
+	Line     int
+	Column   int
+	Expected []string
+	Found    string
 }
 
 func (p *ParseError) Error() string {
-	return fmt.Sprintf("parse error at offset %d: %s", p.Offset, p.Msg)
+	msg := fmt.Sprintf("parse error at offset %d: %s", p.Offset, p.Msg)
+	if p.Filename != "" {
+		return p.Filename + ": " + msg
+	}
+
+	return msg
 }
 
 func (p *ParseError) Unwrap() error {
 	return p.wrapped
 }
+
+// Is allows checking the code of the error with errors.Is.
+func (p *ParseError) Is(target error) bool {
+	code, ok := target.(diag.Code)
+
+	return ok && code == p.Code
+}
+
+// Diagnostic describes the parse error for rendering (see diag.Renderer).
+func (p *ParseError) Diagnostic() diag.Diagnostic {
+	return diag.Diagnostic{
+		Code:     p.Code,
+		Message:  p.Msg,
+		Position: source.Position{Filename: p.Filename, Offset: p.Offset, Line: p.Line, Column: p.Column},
+		Expected: p.Expected,
+		Found:    p.Found,
+	}
+}
+
+// MergeError is returned if the syntaxes of an EBNF grammar split across several files cannot be merged (see Merge).
+type MergeError struct {
+	// Code identifies the kind of the error, and can be checked with errors.Is (see diag.Code).
+	Code diag.Code
+	Msg  string
+	// MetaIdentifier is the meta identifier of the rule the error occurred in.
+	MetaIdentifier string
+	// Position is where the error occurred, including the name of the file containing it.
+	Position source.Position
+}
+
+func (m *MergeError) Error() string {
+	return fmt.Sprintf("merge error at %s: %s", m.Position, m.Msg)
+}
+
+// Is allows checking the code of the error with errors.Is.
+func (m *MergeError) Is(target error) bool {
+	code, ok := target.(diag.Code)
+
+	return ok && code == m.Code
+}
+
+// Diagnostic describes the merge error for rendering (see diag.Renderer).
+func (m *MergeError) Diagnostic() diag.Diagnostic {
+	return diag.Diagnostic{Code: m.Code, Message: m.Msg, Position: m.Position, Rule: m.MetaIdentifier}
+}
+
+// EditError is returned if an edit of a grammar cannot be made (see Editor).
+type EditError struct {
+	// MetaIdentifier is the meta identifier of the rule being edited.
+	MetaIdentifier string
+	Msg            string
+	wrapped        error
+}
+
+func (e *EditError) Error() string {
+	return fmt.Sprintf("failed editing rule %s: %s", e.MetaIdentifier, e.Msg)
+}
+
+func (e *EditError) Unwrap() error {
+	return e.wrapped
+}
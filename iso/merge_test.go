@@ -0,0 +1,190 @@
+package iso_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"testing/fstest"
+
+	"github.com/alec-w/ebnf-go/diag"
+	"github.com/alec-w/ebnf-go/iso"
+	"github.com/alec-w/ebnf-go/source"
+)
+
+func TestParseFiles(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{
+		"lexical.ebnf":   {Data: []byte("digit = '0' | '1' ;\nletter = 'a' | 'b' ;\n(* end of lexical *)\n")},
+		"syntactic.ebnf": {Data: []byte("identifier = letter, { letter | digit } ;\nnumber = digit, { digit } ;\n")},
+		"duplicate.ebnf": {Data: []byte("digit = '0' ;\nsign = '+' | '-' ;\nsign = '+' ;\n")},
+		"undefined.ebnf": {Data: []byte("signed = [ sign ], number ;\nexpression = (term | { factor }) - digit ;\n")},
+		"broken.ebnf":    {Data: []byte("digit = '0'\n")},
+	}
+	tcs := []struct {
+		name             string
+		paths            []string
+		expectedRules    []string
+		expectedComments []string
+		expectedErrors   []diag.Diagnostic
+	}{
+		{
+			name:             "References across files",
+			paths:            []string{"syntactic.ebnf", "lexical.ebnf"},
+			expectedRules:    []string{"identifier", "number", "digit", "letter"},
+			expectedComments: []string{"end of lexical"},
+		},
+		{
+			name:  "Duplicate rule in another file",
+			paths: []string{"lexical.ebnf", "duplicate.ebnf"},
+			expectedErrors: []diag.Diagnostic{
+				{
+					Code:     diag.CodeDuplicateRule,
+					Message:  "rule digit is already defined at lexical.ebnf:1:1",
+					Position: source.Position{Filename: "duplicate.ebnf", Offset: 0, Line: 1, Column: 1},
+					Rule:     "digit",
+				},
+			},
+		},
+		{
+			name:  "Undefined references",
+			paths: []string{"lexical.ebnf", "syntactic.ebnf", "undefined.ebnf"},
+			expectedErrors: []diag.Diagnostic{
+				{
+					Code:     diag.CodeUndefinedReference,
+					Message:  "meta identifier sign is not defined by any rule",
+					Position: source.Position{Filename: "undefined.ebnf", Offset: 11, Line: 1, Column: 12},
+					Rule:     "signed",
+				},
+				{
+					Code:     diag.CodeUndefinedReference,
+					Message:  "meta identifier term is not defined by any rule",
+					Position: source.Position{Filename: "undefined.ebnf", Offset: 42, Line: 2, Column: 15},
+					Rule:     "expression",
+				},
+				{
+					Code:     diag.CodeUndefinedReference,
+					Message:  "meta identifier factor is not defined by any rule",
+					Position: source.Position{Filename: "undefined.ebnf", Offset: 51, Line: 2, Column: 24},
+					Rule:     "expression",
+				},
+			},
+		},
+		{
+			name:  "Parse error",
+			paths: []string{"lexical.ebnf", "broken.ebnf"},
+			expectedErrors: []diag.Diagnostic{
+				{
+					Code:     diag.CodeMissingTerminator,
+					Message:  `expected "-", ",", "|", "/", "!", ";" or ".", found end of input`,
+					Position: source.Position{Filename: "broken.ebnf", Offset: 12, Line: 2, Column: 1},
+					Rule:     "digit",
+					Expected: []string{`"-"`, `","`, `"|"`, `"/"`, `"!"`, `";"`, `"."`},
+					Found:    diag.EndOfInput,
+				},
+			},
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			parser := iso.New()
+			syntax, err := parser.ParseFiles(fsys, tc.paths...)
+			actualErrors := diag.Diagnostics(err)
+			if len(actualErrors) != len(tc.expectedErrors) {
+				t.Fatalf("Expected %d errors. Got %d: %v.", len(tc.expectedErrors), len(actualErrors), err)
+			}
+			for i, expected := range tc.expectedErrors {
+				actual := actualErrors[i]
+				if actual.Code != expected.Code || actual.Message != expected.Message ||
+					actual.Position != expected.Position || actual.Rule != expected.Rule ||
+					!slices.Equal(actual.Expected, expected.Expected) || actual.Found != expected.Found {
+					t.Errorf("Expected error %#v. Got %#v.", expected, actual)
+				}
+				if !errors.Is(err, expected.Code) {
+					t.Errorf("Expected errors to include code %s.", expected.Code)
+				}
+			}
+			var actualRules []string
+			for _, rule := range syntax.Rules {
+				actualRules = append(actualRules, rule.MetaIdentifier)
+			}
+			if !slices.Equal(actualRules, tc.expectedRules) {
+				t.Errorf("Expected rules %v. Got %v.", tc.expectedRules, actualRules)
+			}
+			if !slices.Equal(syntax.TrailingComments, tc.expectedComments) {
+				t.Errorf("Expected trailing comments %v. Got %v.", tc.expectedComments, syntax.TrailingComments)
+			}
+		})
+	}
+}
+
+func TestMergeAllowsDuplicatesWithinAFile(t *testing.T) {
+	t.Parallel()
+	parser := iso.New()
+	syntax, err := parser.Parse("sign = '+' ;\nsign = '-' ;\n")
+	if err != nil {
+		t.Fatalf("Got unexpected error %s.", err)
+	}
+	merged, err := iso.Merge(syntax)
+	if err != nil {
+		t.Fatalf("Got unexpected error %s.", err)
+	}
+	if len(merged.Rules) != 2 {
+		t.Errorf("Expected 2 rules. Got %d.", len(merged.Rules))
+	}
+}
+
+func TestMergeReportsDuplicatesAcrossSyntaxes(t *testing.T) {
+	t.Parallel()
+	testCases := map[string]*iso.Parser{
+		"without filenames":    iso.New(),
+		"with shared filename": iso.New(iso.WithFilename("grammar.ebnf")),
+	}
+	for name, parser := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			first, err := parser.Parse("x = 'a' ;\n")
+			if err != nil {
+				t.Fatalf("Got unexpected error %s.", err)
+			}
+			second, err := parser.Parse("y = x ;\nx = 'b' ;\n")
+			if err != nil {
+				t.Fatalf("Got unexpected error %s.", err)
+			}
+			_, err = iso.Merge(first, second)
+			if !errors.Is(err, diag.CodeDuplicateRule) {
+				t.Fatalf("Expected a duplicate rule error. Got %v.", err)
+			}
+			var mergeErr *iso.MergeError
+			if !errors.As(err, &mergeErr) || mergeErr.MetaIdentifier != "x" || mergeErr.Position.Line != 2 {
+				t.Errorf("Expected merge error for rule x on line 2. Got %v.", err)
+			}
+		})
+	}
+}
+
+func TestParseFilesWithRecovery(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{
+		"lexical.ebnf": {Data: []byte("digit = '0' | '1' ;\nletter = 'a' | 'b' ;\n")},
+		"partial.ebnf": {Data: []byte("sign = '+' , ) ;\nword = letter, { letter } ;\nnumber = digit, sign ;\n")},
+	}
+	syntax, err := iso.New(iso.WithRecovery()).ParseFiles(fsys, "lexical.ebnf", "partial.ebnf")
+	// The rule that could not be parsed is reported, as is the reference to it.
+	var codes []diag.Code
+	for _, diagnostic := range diag.Diagnostics(err) {
+		codes = append(codes, diagnostic.Code)
+	}
+	expectedCodes := []diag.Code{diag.CodeMissingTerminator, diag.CodeUndefinedReference}
+	if !slices.Equal(codes, expectedCodes) {
+		t.Errorf("Expected errors with codes %v. Got %v: %v.", expectedCodes, codes, err)
+	}
+	var rules []string
+	for _, rule := range syntax.Rules {
+		rules = append(rules, rule.MetaIdentifier)
+	}
+	expectedRules := []string{"digit", "letter", "word", "number"}
+	if !slices.Equal(rules, expectedRules) {
+		t.Errorf("Expected rules %v. Got %v.", expectedRules, rules)
+	}
+}
@@ -1,150 +1,311 @@
 package iso
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
 	"strconv"
+	"strings"
 	"unicode"
 	"unicode/utf8"
+
+	"github.com/alec-w/ebnf-go/diag"
+	"github.com/alec-w/ebnf-go/limit"
+	"github.com/alec-w/ebnf-go/source"
 )
 
+// metaIdentifierToken describes a meta identifier in the tokens expected by a ParseError.
+const metaIdentifierToken = "meta identifier"
+
 // Parser is used to parse an EBNF grammar.
+//
+// A Parser is configured once, by the options given to New, and keeps no state between parses, so it is safe for
+// concurrent use by multiple goroutines.
 type Parser struct {
+	config config
+}
+
+// config is the configuration of a Parser, shared by every parse it performs.
+type config struct {
+	// filename is recorded in the positions of the Syntax and in any errors of Parse and ParseContext.
+	filename string
+	limits   limit.Limits
+	recovery bool
+	strict   bool
+	cst      bool
+}
+
+// parser holds the state of a single parse.
+type parser struct {
+	config
 	source string
 	offset int
-	line   int
+	file   *source.File
+	// depth is the number of bracketed sequences or comments being parsed.
+	depth int
+}
+
+// Option configures a Parser.
+type Option func(*Parser)
+
+// WithRecovery configures a Parser to recover from errors in a rule by skipping to the next terminator symbol ("." or
+// ";") and continuing to parse the rules that follow.
+//
+// When recovering, Parse returns every error found joined together (see errors.Join), along with a partial Syntax of
+// the rules that were parsed successfully.
+func WithRecovery() Option {
+	return func(p *Parser) {
+		p.config.recovery = true
+	}
+}
+
+// WithLimits configures the limits on the resources a Parser may use, replacing the default limits (see limit.Default).
+//
+// Exceeding a limit on the nesting depth or literal length is an error in the rule where it occurs, but exceeding the
+// limit on the number of rules stops parsing (even when recovering), as does the input size which is checked before
+// parsing starts.
+func WithLimits(limits limit.Limits) Option {
+	return func(p *Parser) {
+		p.config.limits = limits
+	}
+}
+
+// WithStrict configures a Parser to accept only the syntax of ISO 14977 itself, which limits the letters and digits of
+// meta identifiers to those of ISO 646 (the basic latin letters and decimal digits) rather than those of any script.
+//
+// Syntax that is accepted otherwise is reported as an error with the code diag.CodeNonStandardSyntax.
+func WithStrict() Option {
+	return func(p *Parser) {
+		p.config.strict = true
+	}
+}
+
+// WithCST configures a Parser to build the lossless concrete syntax tree of each grammar it parses, which is recorded
+// in the CST of the Syntax.
+//
+// The tree keeps every token of the grammar as it is written, including the whitespace within meta identifiers, and
+// the whitespace and comments between tokens, so the grammar can be reproduced exactly (see cst.Node.String). When
+// recovering from errors, the text of any rule that could not be parsed is kept as tokens of the syntax.
+func WithCST() Option {
+	return func(p *Parser) {
+		p.config.cst = true
+	}
+}
+
+// WithFilename configures the file name that Parse and ParseContext record in the positions of the Syntax and in any
+// errors. ParseReader and ParseFile record the name they are given instead.
+func WithFilename(name string) Option {
+	return func(p *Parser) {
+		p.config.filename = name
+	}
 }
 
 // New instantiates a new Parser.
-func New() Parser {
-	return Parser{}
+func New(opts ...Option) *Parser {
+	parser := &Parser{config: config{limits: limit.Default()}}
+	for _, opt := range opts {
+		opt(parser)
+	}
+
+	return parser
 }
 
 // Parse is the entrypoint of the parser.
 //
 // Given a source EBNF grammar it produces a structured representation of it.
-func (p *Parser) Parse(source string) (Syntax, error) {
-	p.source = source
-	p.offset = 0
-	p.line = 1
+func (p *Parser) Parse(grammar string) (Syntax, error) {
+	return p.parse(context.Background(), p.config.filename, grammar)
+}
 
-	return p.parseSyntax()
+// ParseContext parses the given EBNF grammar, like Parse, but stops (even when recovering) if the context is cancelled
+// or its deadline passes before every rule has been parsed.
+func (p *Parser) ParseContext(ctx context.Context, grammar string) (Syntax, error) {
+	return p.parse(ctx, p.config.filename, grammar)
 }
 
-func (p *Parser) parseSyntax() (Syntax, error) {
-	// A syntax is made up of one or more rules.
-	// So parse one rule...
-	var syntax Syntax
-	// It is not possible to distinguish between comments on the syntax as a whole and comments on the first rule of
-	// the syntax therefore comments at the start of the syntax will be attached to the first rule, so they are not
-	// parsed here.
-	var comments []string
-	for p.isCommentStart() {
-		comments = append(comments, p.parseComment())
+// ParseReader parses the EBNF grammar read from r, recording the given file name in the positions of the Syntax and
+// in any errors.
+func (p *Parser) ParseReader(name string, r io.Reader) (Syntax, error) {
+	if p.config.limits.MaxInputSize > 0 {
+		// Reading one byte beyond the limit is enough to know it has been exceeded, without reading all of the input.
+		r = io.LimitReader(r, int64(p.config.limits.MaxInputSize)+1)
 	}
-	rule, err := p.parseRule()
+	grammar, err := io.ReadAll(r)
 	if err != nil {
-		return Syntax{}, err
+		return Syntax{}, &ReadError{Filename: name, wrapped: err}
 	}
-	rule.Comments = append(rule.Comments, comments...)
-	syntax.Rules = append(syntax.Rules, rule)
-	p.skipWhitespace()
-	// ...then optionally parse more if the entire grammar has not been parsed.
-	for p.source[p.offset:] != "" {
-		// We may have trailing comments or another rule (optionally preceded by comments)
+
+	return p.parse(context.Background(), name, string(grammar))
+}
+
+// ParseFile parses the EBNF grammar in the file at path within fsys (e.g. an os.DirFS or embed.FS), recording the path
+// in the positions of the Syntax and in any errors.
+func (p *Parser) ParseFile(fsys fs.FS, path string) (Syntax, error) {
+	file, err := fsys.Open(path)
+	if err != nil {
+		return Syntax{}, &ReadError{Filename: path, wrapped: err}
+	}
+	syntax, err := p.ParseReader(path, file)
+	if closeErr := file.Close(); closeErr != nil && err == nil {
+		return Syntax{}, &ReadError{Filename: path, wrapped: closeErr}
+	}
+
+	return syntax, err
+}
+
+func (p *Parser) parse(ctx context.Context, name, grammar string) (Syntax, error) {
+	if p.config.limits.InputSizeExceeded(len(grammar)) {
+		return Syntax{}, &limit.InputSizeError{Filename: name, Max: p.config.limits.MaxInputSize}
+	}
+	state := &parser{config: p.config, source: grammar, file: source.NewNamedFile(name, grammar)}
+	// A leading byte order mark is not part of the grammar.
+	if strings.HasPrefix(grammar, source.BOM) {
+		state.offset = len(source.BOM)
+	}
+	syntax, err := state.parseSyntax(ctx)
+	if p.config.cst && (err == nil || p.config.recovery) {
+		syntax.CST = state.concreteSyntaxTree(syntax)
+	}
+
+	return syntax, err
+}
+
+func (p *parser) parseSyntax(ctx context.Context) (Syntax, error) {
+	// A syntax is made up of one or more rules, each optionally preceded by comments.
+	var syntax Syntax
+	var errs []error
+	for {
+		// An error within a bracketed sequence or comment of a previous rule may have left the depth unbalanced.
+		p.depth = 0
+		// It is not possible to distinguish between comments on the syntax as a whole and comments on the first rule
+		// of the syntax therefore comments at the start of the syntax will be attached to the first rule.
 		// So parse any comments...
-		var comments []string
-		for p.isCommentStart() {
-			comments = append(comments, p.parseComment())
-		}
-		p.skipWhitespace()
-		// ...then see if we've reached the end (so these were trailing comments)...
-		if p.source[p.offset:] == "" {
-			syntax.TrailingComments = comments
+		comments, commentsErr := p.parseComments()
+		if commentsErr == nil {
+			p.skipWhitespace()
+			// ...then see if we've reached the end (so these were trailing comments), which is only allowed after the
+			// first rule...
+			if p.source[p.offset:] == "" && len(syntax.Rules)+len(errs) > 0 {
+				syntax.TrailingComments = comments
 
-			break
+				break
+			}
+			// ...otherwise those were the comments preceding the next rule, unless parsing must stop before it
+			if err := p.checkRule(ctx, len(syntax.Rules)+len(errs)+1); err != nil {
+				if !p.recovery {
+					return Syntax{}, err
+				}
+				errs = append(errs, err)
+
+				break
+			}
+			rule, err := p.parseRule()
+			if err == nil {
+				rule.Comments = append(rule.Comments, comments...)
+				syntax.Rules = append(syntax.Rules, rule)
+
+				continue
+			}
+			errs = append(errs, err)
+		} else {
+			errs = append(errs, commentsErr)
 		}
-		// ...otherwise those were the comments preceding the next rule
-		rule, err := p.parseRule()
-		if err != nil {
-			return Syntax{}, err
+		if !p.recovery {
+			return Syntax{}, errs[0]
 		}
-		rule.Comments = append(rule.Comments, comments...)
-		syntax.Rules = append(syntax.Rules, rule)
-		p.skipWhitespace()
+		p.skipToRuleEnd()
+	}
+	if len(errs) > 0 {
+		return syntax, errors.Join(errs...)
 	}
 
 	return syntax, nil
 }
 
-func (p *Parser) parseRule() (Rule, error) {
+func (p *parser) parseRule() (Rule, error) {
 	// A rule is made up of a meta identifier followed by a literal "=" then a list of definitions, then a terminating
 	// symbol (";" or ".")
 	p.skipWhitespace()
-	rule := Rule{Line: p.line}
+	startOffset := p.offset
+	rule := Rule{Line: p.file.Position(startOffset).Line}
 	// Look for start of meta identifier (letter)
 	char, _ := utf8.DecodeRuneInString(p.source[p.offset:])
 	if !unicode.IsLetter(char) {
-		return Rule{}, &ParseRuleError{Line: rule.Line, Wrapped: &ParseError{
-			Msg:    "expected rule meta identifier start character (letter)",
-			Offset: p.offset,
-		}}
+		return Rule{}, p.ruleError(rule, p.expectedError(diag.CodeMissingRuleName, p.offset, metaIdentifierToken))
 	}
 	// Parse the meta identifier
-	rule.MetaIdentifier = p.parseMetaIdentifier()
-	for p.isCommentStart() {
-		rule.Comments = append(rule.Comments, p.parseComment())
+	metaIdentifier, _, err := p.parseMetaIdentifier()
+	if err != nil {
+		return Rule{}, p.ruleError(rule, err)
+	}
+	rule.MetaIdentifier = metaIdentifier
+	comments, commentsErr := p.parseComments()
+	if commentsErr != nil {
+		return Rule{}, p.ruleError(rule, commentsErr)
 	}
+	rule.Comments = comments
 	// Remove leading whitespace
 	p.skipWhitespace()
 	// Look for "=" character
 	char, width := utf8.DecodeRuneInString(p.source[p.offset:])
 	if char != '=' {
-		return Rule{}, &ParseRuleError{Line: rule.Line, Wrapped: &ParseError{
-			Msg:    "expected defining symbol ('=')",
-			Offset: p.offset,
-		}}
+		return Rule{}, p.ruleError(rule, p.expectedError(diag.CodeMissingDefiningSymbol, p.offset, diag.Literal("=")))
 	}
 	p.offset += width
 	// Parse a definitions list
 	defintitionsList, err := p.parseDefinitionsList()
 	if err != nil {
-		return Rule{}, &ParseRuleError{Line: rule.Line, Wrapped: err}
+		return Rule{}, p.ruleError(rule, err)
 	}
 	rule.Definitions = defintitionsList
 	// Look for terminating character
 	p.skipWhitespace()
 	char, width = utf8.DecodeRuneInString(p.source[p.offset:])
 	if char != ';' && char != '.' {
-		return Rule{}, &ParseRuleError{Line: rule.Line, Wrapped: &ParseError{
-			Msg:    "expected terminator symbol ('.' or ';')",
-			Offset: p.offset,
-		}}
+		expected := append(continuations(defintitionsList), diag.Literal(";"), diag.Literal("."))
+
+		return Rule{}, p.ruleError(rule, p.expectedError(diag.CodeMissingTerminator, p.offset, expected...))
 	}
 	p.offset += width
+	rule.Span = p.file.Span(startOffset, p.offset)
 
 	return rule, nil
 }
 
-func (p *Parser) parseMetaIdentifier() string {
+// parseMetaIdentifier returns the parsed meta identifier and the offset of the end of its last character (before any
+// trailing whitespace).
+func (p *parser) parseMetaIdentifier() (string, int, *ParseError) {
 	p.skipWhitespace()
 	// A meta identifier is a sequence of letters and digits starting with a letter.
 	// Preceding/Tailing whitespace is allowed as is whitespace between characters - this is all ignored.
 	// This assumes that first character has already checked to be a letter.
 	// Since this is internal to the parser this should be checked there to avoid unreachable error handling code here
 	var metaIdentifier []rune
+	endOffset := p.offset
 	for {
 		char, width := utf8.DecodeRuneInString(p.source[p.offset:])
 		if !unicode.IsLetter(char) && !unicode.IsDigit(char) {
 			break
 		}
+		if p.strict && char > unicode.MaxASCII {
+			return "", 0, p.parseError(
+				diag.CodeNonStandardSyntax,
+				fmt.Sprintf("meta identifier contains %q which is not a letter or digit of ISO 646", char),
+				p.offset,
+			)
+		}
 		p.offset += width
+		endOffset = p.offset
 		metaIdentifier = append(metaIdentifier, char)
 		p.skipWhitespace()
 	}
 
-	return string(metaIdentifier)
+	return string(metaIdentifier), endOffset, nil
 }
 
-func (p *Parser) parseDefinitionsList() (DefinitionsList, *ParseError) {
+func (p *parser) parseDefinitionsList() (DefinitionsList, *ParseError) {
 	// A defintions list is a sequence of one or more definitions separated by "|", "/" or "!".
 	// So parse the first definition...
 	definition, err := p.parseDefinition()
@@ -176,14 +337,14 @@ func (p *Parser) parseDefinitionsList() (DefinitionsList, *ParseError) {
 	return definitionsList, nil
 }
 
-func (p *Parser) parseDefinition() (Definition, *ParseError) {
+func (p *parser) parseDefinition() (Definition, *ParseError) {
 	// A definition is a sequence of one or more terms separated by ","
 	// So parse one term...
 	term, err := p.parseTerm()
 	if err != nil {
 		return Definition{}, err
 	}
-	definition := Definition{Terms: []Term{term}}
+	definition := Definition{Terms: []Term{term}, Span: term.Span}
 	p.skipWhitespace()
 	// ...then optionally parse additional terms
 	next, width := utf8.DecodeRuneInString(p.source[p.offset:])
@@ -194,21 +355,21 @@ func (p *Parser) parseDefinition() (Definition, *ParseError) {
 			return Definition{}, err
 		}
 		definition.Terms = append(definition.Terms, term)
+		definition.Span.End = term.Span.End
 		next, width = utf8.DecodeRuneInString(p.source[p.offset:])
 	}
 
 	return definition, nil
 }
 
-func (p *Parser) parseTerm() (Term, *ParseError) {
+func (p *parser) parseTerm() (Term, *ParseError) {
 	// A term is a factor, then with an optional exception (also a factor) preceded by a literal "-"
 	// So parse a factor...
 	factor, err := p.parseFactor()
 	if err != nil {
 		return Term{}, err
 	}
-	term := Term{Factor: factor}
-	term.Factor = factor
+	term := Term{Factor: factor, Span: factor.Span}
 	// ...then optionally parse an exception
 	p.skipWhitespace()
 	next, width := utf8.DecodeRuneInString(p.source[p.offset:])
@@ -220,22 +381,26 @@ func (p *Parser) parseTerm() (Term, *ParseError) {
 			return Term{}, err
 		}
 		term.Exception = exception
+		term.Span.End = exception.Span.End
 	}
 
 	return term, nil
 }
 
-func (p *Parser) parseFactor() (Factor, *ParseError) {
+func (p *parser) parseFactor() (Factor, *ParseError) {
 	// A factor is a primary preceded by an optional integer number of repetitions (followed by a literal "*")
 	// By the spec, a repetitions of 0 is allowed (although pointless), so default (unspecified) to -1 to distinguish
 	// that case.
 	factor := Factor{Repetitions: -1}
 	// Optionally parse any preceding comments
-	for p.isCommentStart() {
-		factor.Comments = append(factor.Comments, p.parseComment())
+	comments, err := p.parseComments()
+	if err != nil {
+		return Factor{}, err
 	}
+	factor.Comments = comments
 	// So optionally parse a repetition count...
 	p.skipWhitespace()
+	startOffset := p.offset
 	char, _ := utf8.DecodeRuneInString(p.source[p.offset:])
 	if unicode.IsDigit(char) {
 		integer, err := p.parseInteger()
@@ -244,19 +409,23 @@ func (p *Parser) parseFactor() (Factor, *ParseError) {
 		}
 		factor.Repetitions = integer
 		// Optionally parse any comments after the number of repetitions
-		for p.isCommentStart() {
-			factor.Comments = append(factor.Comments, p.parseComment())
+		comments, err = p.parseComments()
+		if err != nil {
+			return Factor{}, err
 		}
+		factor.Comments = append(factor.Comments, comments...)
 		p.skipWhitespace()
 		char, width := utf8.DecodeRuneInString(p.source[p.offset:])
 		if char != '*' {
-			return Factor{}, &ParseError{Msg: "expected repetition symbol ('*')", Offset: p.offset}
+			return Factor{}, p.expectedError(diag.CodeMissingRepetitionSymbol, p.offset, diag.Literal("*"))
 		}
 		p.offset += width
 		// Optionally parse any comments after the repetitions
-		for p.isCommentStart() {
-			factor.Comments = append(factor.Comments, p.parseComment())
+		comments, err = p.parseComments()
+		if err != nil {
+			return Factor{}, err
 		}
+		factor.Comments = append(factor.Comments, comments...)
 	}
 	// ...then parse a primary
 	primary, err := p.parsePrimary()
@@ -264,11 +433,12 @@ func (p *Parser) parseFactor() (Factor, *ParseError) {
 		return Factor{}, err
 	}
 	factor.Primary = primary
+	factor.Span = p.file.Span(startOffset, primary.Span.End.Offset)
 
 	return factor, nil
 }
 
-func (p *Parser) parseInteger() (int, *ParseError) {
+func (p *parser) parseInteger() (int, *ParseError) {
 	// An integer is a sequence of one of more digits.
 	// This assumes the first character is a digit as this is internal to the parser so this should have
 	// already been checked to avoid unrreachable error handling code here.
@@ -288,21 +458,25 @@ func (p *Parser) parseInteger() (int, *ParseError) {
 	// root = 9223372036854775808 * "0" ;
 	// which (if encoding "0" in a single byte) would require exabytes of text to have required the 2^63 repetitions.
 	if err != nil {
-		return 0, &ParseError{
-			Msg:     "integer could not be parsed (max integer size is 2^63-1)",
-			Offset:  p.offset,
-			wrapped: err,
-		}
+		parseErr := p.parseError(
+			diag.CodeInvalidInteger,
+			"integer could not be parsed (max integer size is 2^63-1)",
+			startOffset,
+		)
+		parseErr.wrapped = err
+
+		return 0, parseErr
 	}
 
 	return parsedInt, nil
 }
 
-func (p *Parser) parsePrimary() (Primary, *ParseError) {
+func (p *parser) parsePrimary() (Primary, *ParseError) {
 	// A primary is one of an optional sequence, a repeated sequence, a special sequence, a grouped sequence, a meta
 	// identifier, a terminal, or empty.
 	// To determine which one should be matched the next character is inspected
 	p.skipWhitespace()
+	startOffset := p.offset
 	primary := Primary{}
 	var err *ParseError
 	char, _ := utf8.DecodeRuneInString(p.source[p.offset:])
@@ -316,23 +490,36 @@ func (p *Parser) parsePrimary() (Primary, *ParseError) {
 		repeatedSequence, err = p.parseRepeatedSequence()
 		primary.RepeatedSequence = repeatedSequence
 	case char == '?':
-		primary.SpecialSequence = p.parseSpecialSequence()
+		primary.SpecialSequence, err = p.parseSpecialSequence()
+		primary.EmptySpecialSequence = primary.SpecialSequence == ""
 	case char == '(':
 		primary, err = p.parseParenthisedSequence()
 	case unicode.IsLetter(char):
-		primary.MetaIdentifier = p.parseMetaIdentifier()
+		var endOffset int
+		primary.MetaIdentifier, endOffset, err = p.parseMetaIdentifier()
+		if err != nil {
+			return Primary{}, err
+		}
+		primary.Span = p.file.Span(startOffset, endOffset)
+
+		return primary, nil
 	case char == '\'':
 		fallthrough
 	case char == '"':
-		primary.Terminal = p.parseTerminal()
+		primary.Terminal, err = p.parseTerminal()
+		primary.EmptyTerminal = primary.Terminal == ""
 	default:
 		primary.Empty = true
 	}
+	if err != nil {
+		return Primary{}, err
+	}
+	primary.Span = p.file.Span(startOffset, p.offset)
 
-	return primary, err
+	return primary, nil
 }
 
-func (p *Parser) parseParenthisedSequence() (Primary, *ParseError) {
+func (p *parser) parseParenthisedSequence() (Primary, *ParseError) {
 	// This assumes the character at offset is "(", which should have already been checked by the caller as this is
 	// internal to the parser.
 	var primary Primary
@@ -364,7 +551,7 @@ func (p *Parser) parseParenthisedSequence() (Primary, *ParseError) {
 	return primary, nil
 }
 
-func (p *Parser) parseOptionalSequence() (DefinitionsList, *ParseError) {
+func (p *parser) parseOptionalSequence() (DefinitionsList, *ParseError) {
 	// An optional sequence is a definitions list wrapped in either [...] or (/.../)
 	// The spec says that grammars should only use one of these throughout, but the parser is more lenient
 	// as it is possible to support that without changing the parsing behaviour for grammars that use one set of symbols.
@@ -374,7 +561,7 @@ func (p *Parser) parseOptionalSequence() (DefinitionsList, *ParseError) {
 	)
 }
 
-func (p *Parser) parseRepeatedSequence() (DefinitionsList, *ParseError) {
+func (p *parser) parseRepeatedSequence() (DefinitionsList, *ParseError) {
 	// A repeated sequence is a definitions list wrapped in either {...} or (:...:)
 	// The spec says that grammars should only use one of these throughout, but the parser is more lenient
 	// as it is possible to support that without changing the parsing behaviour for grammars that use one set of symbols.
@@ -384,41 +571,31 @@ func (p *Parser) parseRepeatedSequence() (DefinitionsList, *ParseError) {
 	)
 }
 
-func (p *Parser) parseSpecialSequence() string {
+func (p *parser) parseSpecialSequence() (string, *ParseError) {
 	// A special sequence is any sequence of characters apart from "?" wrapped in ?...?.
 	p.skipWhitespace()
 	// This assumes the first non-whitespace character is "?" which should have been checked before calling this
 	// function. As this is internal to the parser this allows the removal of unreachable error handling code.
+	openingOffset := p.offset
 	_, width := utf8.DecodeRuneInString(p.source[p.offset:])
 	p.offset += width
-	// Leading whitespace is ignored
-	p.skipWhitespace()
-	startOffset := p.offset
-	for {
-		var char rune
-		char, width = utf8.DecodeRuneInString(p.source[p.offset:])
-		p.offset += width
-		if char == '?' {
-			break
-		}
-		if char == '\n' {
-			p.line++
-		}
-	}
-	// Trailing whitespace is ignored
-	endOffset := p.offset - width
-	for {
-		char, width := utf8.DecodeLastRuneInString(p.source[startOffset:endOffset])
-		if !unicode.IsSpace(char) {
-			break
-		}
-		endOffset -= width
-	}
-
-	return p.source[startOffset:endOffset]
+	length := strings.IndexRune(p.source[p.offset:], '?')
+	if length < 0 {
+		return "", p.unterminatedError(
+			diag.CodeUnterminatedSpecialSequence,
+			"special sequence is not terminated (expected special sequence symbol ('?'))",
+			openingOffset,
+			diag.Literal("?"),
+		)
+	}
+	sequence := p.source[p.offset : p.offset+length]
+	p.offset += length + width
+
+	// Leading and trailing whitespace is ignored
+	return strings.TrimFunc(sequence, unicode.IsSpace), nil
 }
 
-func (p *Parser) parseGroupedSequence() (DefinitionsList, *ParseError) {
+func (p *parser) parseGroupedSequence() (DefinitionsList, *ParseError) {
 	// A grouped sequence is a definitions list wrapped in parentheses (...)
 	return p.parseWrappedDefinitionsList(
 		[][]rune{{'('}},
@@ -428,13 +605,13 @@ func (p *Parser) parseGroupedSequence() (DefinitionsList, *ParseError) {
 
 // parseWrappedDefinitionsList is a utility function used to parse repeated, optional and grouped sequences as the
 // logic is the same for each because they are just definitions lists wrapped in different enclosing characters.
-func (p *Parser) parseWrappedDefinitionsList(
+func (p *parser) parseWrappedDefinitionsList(
 	startIdentifiers, endIdentifiers [][]rune,
 ) (DefinitionsList, *ParseError) {
 	// This assumes that the source at the current offset already starts with the one of the given start identifier#
 	// sequences (after whitespace is ignored) since this is internal to the parser this avoids unreachable error
 	// handling code.
-	parseEnclosingCharacters := func(identifiers [][]rune) {
+	parseEnclosingCharacters := func(identifiers [][]rune) bool {
 		p.skipWhitespace()
 		char, width := utf8.DecodeRuneInString(p.source[p.offset:])
 		for _, identifier := range identifiers {
@@ -451,81 +628,154 @@ func (p *Parser) parseWrappedDefinitionsList(
 			if chars == len(identifier) {
 				p.offset += totalWidth
 
-				break
+				return true
 			}
 		}
+
+		return false
 	}
+	p.skipWhitespace()
+	openingOffset := p.offset
 	parseEnclosingCharacters(startIdentifiers)
+	p.depth++
+	if p.limits.DepthExceeded(p.depth) {
+		cause := &limit.DepthError{Max: p.limits.MaxDepth}
+
+		return nil, p.causedError(diag.CodeDepthLimitExceeded, cause.Error(), openingOffset, cause)
+	}
 	definitionsList, err := p.parseDefinitionsList()
 	if err != nil {
 		return nil, err
 	}
-	parseEnclosingCharacters(endIdentifiers)
+	p.depth--
+	if !parseEnclosingCharacters(endIdentifiers) {
+		symbols := make([]string, 0, len(endIdentifiers))
+		expected := continuations(definitionsList)
+		for _, identifier := range endIdentifiers {
+			symbols = append(symbols, "'"+string(identifier)+"'")
+			expected = append(expected, diag.Literal(string(identifier)))
+		}
+
+		err := p.unterminatedError(
+			diag.CodeUnterminatedSequence,
+			"sequence is not terminated (expected "+strings.Join(symbols, " or ")+")",
+			openingOffset,
+			expected...,
+		)
+		// Unlike other constructs, a sequence may not be terminated because of an unexpected token before the end of
+		// the grammar.
+		err.Found = diag.FoundToken(p.source[p.offset:])
+
+		return nil, err
+	}
 
 	return definitionsList, nil
 }
 
-func (p *Parser) parseTerminal() string {
+func (p *parser) parseTerminal() (string, *ParseError) {
+	p.skipWhitespace()
+	openingOffset := p.offset
+	terminal, err := p.scanTerminal()
+	if err != nil {
+		return "", err
+	}
+	if p.limits.LiteralLengthExceeded(len(terminal)) {
+		cause := &limit.LiteralLengthError{Max: p.limits.MaxLiteralLength, Length: len(terminal)}
+
+		return "", p.causedError(diag.CodeLiteralLengthLimitExceeded, cause.Error(), openingOffset, cause)
+	}
+
+	return terminal, nil
+}
+
+// scanTerminal scans a terminal starting at the current offset without checking its length against the limits, which
+// only apply to terminals of the syntax (rather than quoted text within comments).
+func (p *parser) scanTerminal() (string, *ParseError) {
 	// A terminal is any set of characters apart from single quotes, wrapped in single quotes,
 	// or any set of characters apart from double quotes wrapped in double quotes.
 	// Essentially '...' or "..." where the character used as the terminator does not appear inside.
-	p.skipWhitespace()
 	// This assumes the next character is either single quote or double quote which should have been checked already
 	// as this is internal to the parser this avoids unreachable error handling code.
+	openingOffset := p.offset
 	terminatingChar, width := utf8.DecodeRuneInString(p.source[p.offset:])
 	p.offset += width
-	startOffset := p.offset
-	char, width := utf8.DecodeRuneInString(p.source[p.offset:])
-	if char == '\n' {
-		p.line++
-	}
-	p.offset += width
-	for ; char != terminatingChar; p.offset += width {
-		char, width = utf8.DecodeRuneInString(p.source[p.offset:])
-		if char == '\n' {
-			p.line++
+	length := strings.IndexRune(p.source[p.offset:], terminatingChar)
+	if length < 0 {
+		return "", p.unterminatedError(
+			diag.CodeUnterminatedLiteral,
+			fmt.Sprintf("terminal string is not terminated (expected closing quote (%q))", terminatingChar),
+			openingOffset,
+			diag.Literal(string(terminatingChar)),
+		)
+	}
+	terminal := p.source[p.offset : p.offset+length]
+	p.offset += length + width
+
+	return terminal, nil
+}
+
+// parseComments is a utility function used to parse any number of consecutive comments.
+func (p *parser) parseComments() ([]string, *ParseError) {
+	var comments []string
+	for p.isCommentStart() {
+		comment, err := p.parseComment()
+		if err != nil {
+			return nil, err
 		}
+		comments = append(comments, comment)
 	}
 
-	return p.source[startOffset : p.offset-width]
+	return comments, nil
 }
 
-func (p *Parser) parseComment() string {
+func (p *parser) parseComment() (string, *ParseError) {
 	// A comment is a repeated sequence of comment symbols wrapped in parentheses and stars (*...*).
 	p.skipWhitespace()
 	// This assumes the first non-whitespace characters are "(*" which should have been checked before calling this
 	// function. As this is internal to the parser this allows the removal of unreachable error handling code.
+	openingOffset := p.offset
 	_, width := utf8.DecodeRuneInString(p.source[p.offset:])
 	p.offset += width
 	_, width = utf8.DecodeRuneInString(p.source[p.offset:])
 	p.offset += width
+	// Comments can be nested within comments.
+	p.depth++
+	if p.limits.DepthExceeded(p.depth) {
+		cause := &limit.DepthError{Max: p.limits.MaxDepth}
+
+		return "", p.causedError(diag.CodeDepthLimitExceeded, cause.Error(), openingOffset, cause)
+	}
 	// Leading whitespace is ignored
 	p.skipWhitespace()
 	startOffset := p.offset
 	for {
 		p.skipWhitespace()
+		if p.source[p.offset:] == "" {
+			return "", p.unterminatedError(
+				diag.CodeUnterminatedComment,
+				"comment is not terminated (expected end comment symbol ('*)'))",
+				openingOffset,
+				diag.Literal("*)"),
+			)
+		}
 		if char, width := utf8.DecodeRuneInString(p.source[p.offset:]); char == '*' {
 			next, nextWidth := utf8.DecodeRuneInString(p.source[p.offset+width:])
 			if next == ')' {
 				p.offset += width + nextWidth
+				p.depth--
 				// Trailing whitespace is ignored
 				endOffset := p.offset - (width + nextWidth)
-				for {
-					char, width := utf8.DecodeLastRuneInString(p.source[startOffset:endOffset])
-					if !unicode.IsSpace(char) {
-						break
-					}
-					endOffset -= width
-				}
 
-				return p.source[startOffset:endOffset]
+				return strings.TrimRightFunc(p.source[startOffset:endOffset], unicode.IsSpace), nil
 			}
 		}
-		p.parseCommentSymbol()
+		if err := p.parseCommentSymbol(); err != nil {
+			return "", err
+		}
 	}
 }
 
-func (p *Parser) parseCommentSymbol() {
+func (p *parser) parseCommentSymbol() *ParseError {
 	// A comment symbol is a comment, a terminal, a special sequence or any other character.
 	// This means that comments can enclose other comments, but the inner comments must be correctly terminated
 	// and comments can contain quoted strings, but they must be correctly terminated, and comments can include
@@ -533,28 +783,62 @@ func (p *Parser) parseCommentSymbol() {
 	// This function doesn't return anything, but simply advances the offset forwards, so the outermost comment is
 	// just stored as a sequence of characters on the final parsed syntax.
 	p.skipWhitespace()
+	var err *ParseError
 	char, width := utf8.DecodeRuneInString(p.source[p.offset:])
 	switch char {
 	case '(':
 		next, _ := utf8.DecodeRuneInString(p.source[p.offset+width:])
 		if next == '*' {
-			p.parseComment()
+			_, err = p.parseComment()
 		} else {
 			p.offset += width
 		}
 	case '\'':
 		fallthrough
 	case '"':
-		p.parseTerminal()
+		// Quoted text within a comment is not a terminal of the syntax, so is not limited in length.
+		_, err = p.scanTerminal()
 	case '?':
-		p.parseSpecialSequence()
+		_, err = p.parseSpecialSequence()
 	default:
 		p.offset += width
 	}
+
+	return err
+}
+
+// skipToRuleEnd is used when recovering from an error to skip the remainder of the rule in which the error occurred, up
+// to and including the next terminator symbol ("." or ";").
+//
+// Terminals, special sequences and comments are skipped over as a whole (when they are terminated) so that terminator
+// symbols within them are not mistaken for the end of the rule.
+func (p *parser) skipToRuleEnd() {
+	for p.source[p.offset:] != "" {
+		char, width := utf8.DecodeRuneInString(p.source[p.offset:])
+		switch {
+		case char == ';' || char == '.':
+			p.offset += width
+
+			return
+		case char == '\'' || char == '"' || char == '?':
+			if length := strings.IndexRune(p.source[p.offset+width:], char); length >= 0 {
+				p.offset += width + length + width
+
+				continue
+			}
+		case strings.HasPrefix(p.source[p.offset:], "(*"):
+			startOffset := p.offset
+			if _, err := p.parseComment(); err == nil {
+				continue
+			}
+			p.offset = startOffset
+		}
+		p.offset += width
+	}
 }
 
 // isCommentStart is a utility function used to check if the next non whitespace character is a comment start symbol.
-func (p *Parser) isCommentStart() bool {
+func (p *parser) isCommentStart() bool {
 	p.skipWhitespace()
 	char, width := utf8.DecodeRuneInString(p.source[p.offset:])
 	if char == '(' {
@@ -571,13 +855,90 @@ func (p *Parser) isCommentStart() bool {
 //
 // The spec allows whitespace anywhere between the different components, whitespace is used to make a grammar easier
 // to read but does not change its meaning.
-func (p *Parser) skipWhitespace() {
+func (p *parser) skipWhitespace() {
 	char, width := utf8.DecodeRuneInString(p.source[p.offset:])
 	for unicode.IsSpace(char) {
-		if char == '\n' {
-			p.line++
-		}
 		p.offset += width
 		char, width = utf8.DecodeRuneInString(p.source[p.offset:])
 	}
 }
+
+// parseError is a utility function used to create a ParseError for the given offset.
+func (p *parser) parseError(code diag.Code, msg string, offset int) *ParseError {
+	position := p.file.Position(offset)
+
+	return &ParseError{
+		Code:     code,
+		Msg:      msg,
+		Filename: position.Filename,
+		Offset:   offset,
+		Line:     position.Line,
+		Column:   position.Column,
+	}
+}
+
+// causedError is a utility function used to create a ParseError for the given offset, caused by another error.
+func (p *parser) causedError(code diag.Code, msg string, offset int, cause error) *ParseError {
+	err := p.parseError(code, msg, offset)
+	err.wrapped = cause
+
+	return err
+}
+
+// checkRule is used before parsing each rule (given its number) to check that the context has not been cancelled and
+// the limit on the number of rules has not been exceeded.
+func (p *parser) checkRule(ctx context.Context, rule int) *ParseError {
+	if err := ctx.Err(); err != nil {
+		return p.causedError(diag.CodeCancelled, "parsing was stopped ("+err.Error()+")", p.offset, err)
+	}
+	if p.limits.RulesExceeded(rule) {
+		cause := &limit.RuleCountError{Max: p.limits.MaxRules}
+
+		return p.causedError(diag.CodeRuleLimitExceeded, cause.Error(), p.offset, cause)
+	}
+
+	return nil
+}
+
+// ruleError is a utility function used to wrap a ParseError in a ParseRuleError for the rule being parsed.
+func (p *parser) ruleError(rule Rule, wrapped *ParseError) *ParseRuleError {
+	return &ParseRuleError{
+		Filename:       p.file.Name(),
+		MetaIdentifier: rule.MetaIdentifier,
+		Line:           rule.Line,
+		Wrapped:        wrapped,
+	}
+}
+
+// expectedError is a utility function used to create a ParseError for an unexpected token at the given offset, where
+// one of the expected tokens would have been acceptable.
+func (p *parser) expectedError(code diag.Code, offset int, expected ...string) *ParseError {
+	found := diag.FoundToken(p.source[offset:])
+	err := p.parseError(code, diag.ExpectedMessage(expected, found), offset)
+	err.Expected = expected
+	err.Found = found
+
+	return err
+}
+
+// unterminatedError is a utility function used to create a ParseError for a construct opened at the given offset that
+// runs to the end of the grammar without being closed by one of the expected tokens.
+func (p *parser) unterminatedError(code diag.Code, msg string, openingOffset int, expected ...string) *ParseError {
+	err := p.parseError(code, msg, openingOffset)
+	err.Expected = expected
+	err.Found = diag.EndOfInput
+
+	return err
+}
+
+// continuations returns the tokens that could continue a parsed definitions list: an exception if the last term does
+// not already have one, another term or another definition.
+func continuations(definitionsList DefinitionsList) []string {
+	var expected []string
+	terms := definitionsList[len(definitionsList)-1].Terms
+	if terms[len(terms)-1].Exception.Span.IsZero() {
+		expected = append(expected, diag.Literal("-"))
+	}
+
+	return append(expected, diag.Literal(","), diag.Literal("|"), diag.Literal("/"), diag.Literal("!"))
+}
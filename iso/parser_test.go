@@ -1,10 +1,25 @@
 package iso_test
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
 	"testing"
+	"testing/fstest"
+	"testing/iotest"
 
+	"github.com/alec-w/ebnf-go/diag"
 	"github.com/alec-w/ebnf-go/iso"
+	"github.com/alec-w/ebnf-go/limit"
+	"github.com/alec-w/ebnf-go/source"
 )
 
 func assertSlicesEqual[T any](
@@ -214,6 +229,18 @@ func assertPrimariesEqual(t *testing.T, expected, actual iso.Primary) bool {
 		t.Logf("Expected primary terminal %q. Got %q.", expected.Terminal, actual.Terminal)
 		failed = true
 	}
+	if expected.EmptySpecialSequence != actual.EmptySpecialSequence {
+		t.Logf(
+			"Expected primary empty special sequence %t. Got %t.",
+			expected.EmptySpecialSequence,
+			actual.EmptySpecialSequence,
+		)
+		failed = true
+	}
+	if expected.EmptyTerminal != actual.EmptyTerminal {
+		t.Logf("Expected primary empty terminal %t. Got %t.", expected.EmptyTerminal, actual.EmptyTerminal)
+		failed = true
+	}
 	if expected.Empty != actual.Empty {
 		t.Logf("Expected primary empty %t. Got %t.", expected.Empty, actual.Empty)
 		failed = true
@@ -236,6 +263,35 @@ func TestParseSyntax(t *testing.T) {
 		grammar        string
 		expectedSyntax iso.Syntax
 	}{
+		{
+			name:    "Empty terminal and special sequence",
+			grammar: "empty = '' | ? ? ;",
+			expectedSyntax: iso.Syntax{
+				Rules: []iso.Rule{
+					{
+						Line:           1,
+						MetaIdentifier: "empty",
+						Definitions: iso.DefinitionsList{
+							{
+								Terms: []iso.Term{
+									{
+										Factor: iso.Factor{
+											Repetitions: -1,
+											Primary:     iso.Primary{EmptyTerminal: true},
+										},
+									},
+								},
+							},
+							{
+								Terms: []iso.Term{
+									{Factor: iso.Factor{Repetitions: -1, Primary: iso.Primary{EmptySpecialSequence: true}}},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
 		{
 			name: "Positive integer definition",
 			grammar: `
@@ -4582,3 +4638,681 @@ a = ;`,
 		})
 	}
 }
+
+func TestParseSyntaxErrors(t *testing.T) {
+	t.Parallel()
+	tcs := []struct {
+		name           string
+		grammar        string
+		expectedOffset int
+		expectedCode   diag.Code
+	}{
+		{
+			name:           "Unterminated double quoted terminal",
+			grammar:        `a = "abc ;`,
+			expectedOffset: 4,
+			expectedCode:   diag.CodeUnterminatedLiteral,
+		},
+		{
+			name:           "Unterminated terminal on later line",
+			grammar:        "a = b ;\r\nc = 'é', 'abc ;",
+			expectedOffset: 19,
+			expectedCode:   diag.CodeUnterminatedLiteral,
+		},
+		{
+			name:           "Unterminated single quoted terminal",
+			grammar:        `a = 'abc ;`,
+			expectedOffset: 4,
+			expectedCode:   diag.CodeUnterminatedLiteral,
+		},
+		{
+			name:           "Unterminated special sequence",
+			grammar:        `a = b, ? abc ;`,
+			expectedOffset: 7,
+			expectedCode:   diag.CodeUnterminatedSpecialSequence,
+		},
+		{
+			name:           "Unterminated comment",
+			grammar:        `a = b ; (* abc`,
+			expectedOffset: 8,
+			expectedCode:   diag.CodeUnterminatedComment,
+		},
+		{
+			name:           "Unterminated nested comment",
+			grammar:        `a = b, (* abc (* def *) ;`,
+			expectedOffset: 7,
+			expectedCode:   diag.CodeUnterminatedComment,
+		},
+		{
+			name:           "Unterminated terminal in comment",
+			grammar:        `(* don't *) a = b ;`,
+			expectedOffset: 6,
+			expectedCode:   diag.CodeUnterminatedLiteral,
+		},
+		{
+			name:           "Unterminated optional sequence",
+			grammar:        `a = [ b ;`,
+			expectedOffset: 4,
+			expectedCode:   diag.CodeUnterminatedSequence,
+		},
+		{
+			name:           "Unterminated repeated sequence",
+			grammar:        `a = (: b ;`,
+			expectedOffset: 4,
+			expectedCode:   diag.CodeUnterminatedSequence,
+		},
+		{
+			name:           "Unterminated grouped sequence",
+			grammar:        `a = ( b`,
+			expectedOffset: 4,
+			expectedCode:   diag.CodeUnterminatedSequence,
+		},
+		{
+			name:           "Integer too large",
+			grammar:        `a = 9223372036854775808 * b ;`,
+			expectedOffset: 4,
+			expectedCode:   diag.CodeInvalidInteger,
+		},
+		{name: "Missing terminator", grammar: `a = b`, expectedOffset: 5, expectedCode: diag.CodeMissingTerminator},
+	}
+
+	for _, tc := range tcs {
+		parser := iso.New()
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := parser.Parse(tc.grammar)
+			var parseErr *iso.ParseError
+			if !errors.As(err, &parseErr) {
+				t.Fatalf("Expected parse error. Got %v.", err)
+			}
+			if !errors.Is(err, tc.expectedCode) {
+				t.Errorf("Expected error with code %s. Got %s (%s).", tc.expectedCode, parseErr.Code, err)
+			}
+			if parseErr.Offset != tc.expectedOffset {
+				t.Errorf("Expected error at offset %d. Got %d (%s).", tc.expectedOffset, parseErr.Offset, err)
+			}
+			expectedLine := strings.Count(tc.grammar[:tc.expectedOffset], "\n") + 1
+			lineStart := strings.LastIndex(tc.grammar[:tc.expectedOffset], "\n") + 1
+			expectedColumn := len([]rune(tc.grammar[lineStart:tc.expectedOffset])) + 1
+			if parseErr.Line != expectedLine || parseErr.Column != expectedColumn {
+				t.Errorf(
+					"Expected error at %d:%d. Got %d:%d.",
+					expectedLine,
+					expectedColumn,
+					parseErr.Line,
+					parseErr.Column,
+				)
+			}
+		})
+	}
+}
+
+func TestParseSyntaxExpectedTokens(t *testing.T) {
+	t.Parallel()
+	tcs := []struct {
+		name             string
+		grammar          string
+		expectedExpected []string
+		expectedFound    string
+	}{
+		{
+			name:             "Missing meta identifier",
+			grammar:          `= b ;`,
+			expectedExpected: []string{"meta identifier"},
+			expectedFound:    `"="`,
+		},
+		{
+			name:             "Missing defining symbol",
+			grammar:          `a "b" ;`,
+			expectedExpected: []string{`"="`},
+			expectedFound:    `"\""`,
+		},
+		{
+			name:             "Missing repetition symbol",
+			grammar:          `a = 2 b ;`,
+			expectedExpected: []string{`"*"`},
+			expectedFound:    `"b"`,
+		},
+		{
+			name:             "Missing terminator",
+			grammar:          `a = b`,
+			expectedExpected: []string{`"-"`, `","`, `"|"`, `"/"`, `"!"`, `";"`, `"."`},
+			expectedFound:    "end of input",
+		},
+		{
+			name:             "Missing terminator after exception",
+			grammar:          `a = b - c "d"`,
+			expectedExpected: []string{`","`, `"|"`, `"/"`, `"!"`, `";"`, `"."`},
+			expectedFound:    `"\""`,
+		},
+		{
+			name:             "Unterminated optional sequence",
+			grammar:          `a = [ b ;`,
+			expectedExpected: []string{`"-"`, `","`, `"|"`, `"/"`, `"!"`, `"]"`, `"/)"`},
+			expectedFound:    `";"`,
+		},
+		{
+			name:             "Unterminated terminal",
+			grammar:          `a = "b ;`,
+			expectedExpected: []string{`"\""`},
+			expectedFound:    "end of input",
+		},
+	}
+
+	for _, tc := range tcs {
+		parser := iso.New()
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := parser.Parse(tc.grammar)
+			var parseErr *iso.ParseError
+			if !errors.As(err, &parseErr) {
+				t.Fatalf("Expected parse error. Got %v.", err)
+			}
+			if !slices.Equal(parseErr.Expected, tc.expectedExpected) {
+				t.Errorf("Expected tokens %v to be expected. Got %v.", tc.expectedExpected, parseErr.Expected)
+			}
+			if parseErr.Found != tc.expectedFound {
+				t.Errorf("Expected %s to be found. Got %s.", tc.expectedFound, parseErr.Found)
+			}
+		})
+	}
+}
+
+func TestParseSyntaxSpans(t *testing.T) {
+	t.Parallel()
+	grammar := "\uFEFF(* comment *) first = 2 * 'é', { b } - c | ;\r\nsecond = ( a | b ) ."
+	parser := iso.New()
+	syntax, err := parser.Parse(grammar)
+	if err != nil {
+		t.Fatalf("Got unexpected error %s.", err)
+	}
+	if len(syntax.Rules) != 2 {
+		t.Fatalf("Expected 2 rules. Got %d.", len(syntax.Rules))
+	}
+	firstDefinition := syntax.Rules[0].Definitions[0]
+	secondPrimary := syntax.Rules[1].Definitions[0].Terms[0].Factor.Primary
+	tcs := []struct {
+		name     string
+		span     source.Span
+		expected string
+		line     int
+		column   int
+	}{
+		{name: "First rule", span: syntax.Rules[0].Span, expected: "first = 2 * 'é', { b } - c | ;", line: 1, column: 15},
+		{name: "Definition", span: firstDefinition.Span, expected: "2 * 'é', { b } - c", line: 1, column: 23},
+		{name: "Factor", span: firstDefinition.Terms[0].Factor.Span, expected: "2 * 'é'", line: 1, column: 23},
+		{name: "Terminal", span: firstDefinition.Terms[0].Factor.Primary.Span, expected: "'é'", line: 1, column: 27},
+		{name: "Term with exception", span: firstDefinition.Terms[1].Span, expected: "{ b } - c", line: 1, column: 32},
+		{name: "Exception", span: firstDefinition.Terms[1].Exception.Span, expected: "c", line: 1, column: 40},
+		{name: "Empty", span: syntax.Rules[0].Definitions[1].Span, expected: "", line: 1, column: 44},
+		{name: "Second rule", span: syntax.Rules[1].Span, expected: "second = ( a | b ) .", line: 2, column: 1},
+		{name: "Grouped sequence", span: secondPrimary.Span, expected: "( a | b )", line: 2, column: 10},
+		{
+			name:     "Meta identifier",
+			span:     secondPrimary.GroupedSequence[1].Span,
+			expected: "b",
+			line:     2,
+			column:   16,
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			if actual := grammar[tc.span.Start.Offset:tc.span.End.Offset]; actual != tc.expected {
+				t.Errorf("Expected span to cover %q. Got %q.", tc.expected, actual)
+			}
+			if tc.span.Start.Line != tc.line || tc.span.Start.Column != tc.column {
+				t.Errorf(
+					"Expected span to start at %d:%d. Got %d:%d.",
+					tc.line,
+					tc.column,
+					tc.span.Start.Line,
+					tc.span.Start.Column,
+				)
+			}
+		})
+	}
+}
+
+func TestParseSyntaxWithRecovery(t *testing.T) {
+	t.Parallel()
+	grammar := `first = "a" ;
+second = "b" "c" ;
+third = "d" | (* a comment; with a terminator *) "e" ;
+fourth = "f ;
+`
+	parser := iso.New(iso.WithRecovery())
+	syntax, err := parser.Parse(grammar)
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		t.Fatalf("Expected joined errors. Got %v.", err)
+	}
+	errs := joined.Unwrap()
+	expectedErrors := []struct {
+		line   int
+		offset int
+	}{
+		{line: 2, offset: 27},
+		{line: 4, offset: 97},
+	}
+	if len(errs) != len(expectedErrors) {
+		t.Fatalf("Expected %d errors. Got %d (%v).", len(expectedErrors), len(errs), err)
+	}
+	for i, expected := range expectedErrors {
+		var ruleErr *iso.ParseRuleError
+		if !errors.As(errs[i], &ruleErr) {
+			t.Errorf("Expected error %d to be a rule error. Got %v.", i+1, errs[i])
+
+			continue
+		}
+		if ruleErr.Line != expected.line {
+			t.Errorf("Expected error %d to be for rule on line %d. Got %d.", i+1, expected.line, ruleErr.Line)
+		}
+		if ruleErr.Wrapped.Offset != expected.offset {
+			t.Errorf("Expected error %d at offset %d. Got %d.", i+1, expected.offset, ruleErr.Wrapped.Offset)
+		}
+	}
+	var metaIdentifiers []string
+	for _, rule := range syntax.Rules {
+		metaIdentifiers = append(metaIdentifiers, rule.MetaIdentifier)
+	}
+	if strings.Join(metaIdentifiers, ",") != "first,third" {
+		t.Errorf("Expected rules first and third to be parsed. Got %v.", metaIdentifiers)
+	}
+}
+
+func TestParseFile(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{
+		"grammars/digits.ebnf": {Data: []byte("digit = '0' | '1' ;\n")},
+		"grammars/broken.ebnf": {Data: []byte("digit = '0' ;\ninteger = digit\n")},
+	}
+	t.Run("Positions", func(t *testing.T) {
+		t.Parallel()
+		parser := iso.New()
+		syntax, err := parser.ParseFile(fsys, "grammars/digits.ebnf")
+		if err != nil {
+			t.Fatalf("Got unexpected error %s.", err)
+		}
+		for _, span := range []source.Span{
+			syntax.Rules[0].Span,
+			syntax.Rules[0].Definitions[1].Terms[0].Factor.Primary.Span,
+		} {
+			if span.Start.Filename != "grammars/digits.ebnf" || span.End.Filename != "grammars/digits.ebnf" {
+				t.Errorf("Expected span to be in file %q. Got %+v.", "grammars/digits.ebnf", span)
+			}
+		}
+	})
+	t.Run("Parse error", func(t *testing.T) {
+		t.Parallel()
+		parser := iso.New()
+		_, err := parser.ParseFile(fsys, "grammars/broken.ebnf")
+		var ruleErr *iso.ParseRuleError
+		if !errors.As(err, &ruleErr) {
+			t.Fatalf("Expected a parse rule error. Got %v.", err)
+		}
+		if ruleErr.Filename != "grammars/broken.ebnf" {
+			t.Errorf("Expected parse rule error in file %q. Got %q.", "grammars/broken.ebnf", ruleErr.Filename)
+		}
+		expectedMsg := "grammars/broken.ebnf: failed parsing rule integer beginning on line 2"
+		if ruleErr.Error() != expectedMsg {
+			t.Errorf("Expected error message %q. Got %q.", expectedMsg, ruleErr.Error())
+		}
+		expectedPosition := source.Position{Filename: "grammars/broken.ebnf", Offset: 30, Line: 3, Column: 1}
+		if actual := diag.Diagnostics(err)[0].Position; actual != expectedPosition {
+			t.Errorf("Expected diagnostic at %#v. Got %#v.", expectedPosition, actual)
+		}
+		if !strings.HasPrefix(ruleErr.Wrapped.Error(), "grammars/broken.ebnf: ") {
+			t.Errorf("Expected parse error message to start with the file name. Got %q.", ruleErr.Wrapped.Error())
+		}
+	})
+	t.Run("Missing file", func(t *testing.T) {
+		t.Parallel()
+		parser := iso.New()
+		_, err := parser.ParseFile(fsys, "grammars/missing.ebnf")
+		var readErr *iso.ReadError
+		if !errors.As(err, &readErr) {
+			t.Fatalf("Expected a read error. Got %v.", err)
+		}
+		if readErr.Filename != "grammars/missing.ebnf" {
+			t.Errorf("Expected read error for file %q. Got %q.", "grammars/missing.ebnf", readErr.Filename)
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("Expected read error to wrap %s. Got %s.", fs.ErrNotExist, err)
+		}
+	})
+}
+
+func TestParseReader(t *testing.T) {
+	t.Parallel()
+	t.Run("Positions", func(t *testing.T) {
+		t.Parallel()
+		parser := iso.New()
+		syntax, err := parser.ParseReader("digits.ebnf", strings.NewReader("digit = '0' | '1' ;"))
+		if err != nil {
+			t.Fatalf("Got unexpected error %s.", err)
+		}
+		expected := source.Position{Filename: "digits.ebnf", Offset: 0, Line: 1, Column: 1}
+		if actual := syntax.Rules[0].Span.Start; actual != expected {
+			t.Errorf("Expected rule to start at %#v. Got %#v.", expected, actual)
+		}
+	})
+	t.Run("Read error", func(t *testing.T) {
+		t.Parallel()
+		readErr := errors.New("read failed")
+		parser := iso.New()
+		_, err := parser.ParseReader("digits.ebnf", iotest.ErrReader(readErr))
+		var actual *iso.ReadError
+		if !errors.As(err, &actual) {
+			t.Fatalf("Expected a read error. Got %v.", err)
+		}
+		if actual.Filename != "digits.ebnf" {
+			t.Errorf("Expected read error for file %q. Got %q.", "digits.ebnf", actual.Filename)
+		}
+		if !errors.Is(err, readErr) {
+			t.Errorf("Expected read error to wrap %s. Got %s.", readErr, err)
+		}
+	})
+}
+
+func TestParseSyntaxLimits(t *testing.T) {
+	t.Parallel()
+	deep := limit.DefaultMaxDepth + 1
+	tcs := []struct {
+		name           string
+		grammar        string
+		limits         limit.Limits
+		expectedCode   diag.Code
+		expectedOffset int
+		expectedCause  error
+	}{
+		{
+			name:           "Default depth",
+			grammar:        "a = " + strings.Repeat("(", deep) + "'b'" + strings.Repeat(")", deep) + ";",
+			limits:         limit.Default(),
+			expectedCode:   diag.CodeDepthLimitExceeded,
+			expectedOffset: 4 + limit.DefaultMaxDepth,
+			expectedCause:  &limit.DepthError{Max: limit.DefaultMaxDepth},
+		},
+		{
+			name:           "Depth of sequences",
+			grammar:        "a = [ { ( 'b' ) } ] ;",
+			limits:         limit.Limits{MaxDepth: 2},
+			expectedCode:   diag.CodeDepthLimitExceeded,
+			expectedOffset: 8,
+			expectedCause:  &limit.DepthError{Max: 2},
+		},
+		{
+			name:           "Depth of comments",
+			grammar:        "a = (* (* (* b *) *) *) 'b' ;",
+			limits:         limit.Limits{MaxDepth: 2},
+			expectedCode:   diag.CodeDepthLimitExceeded,
+			expectedOffset: 10,
+			expectedCause:  &limit.DepthError{Max: 2},
+		},
+		{
+			name:           "Literal length",
+			grammar:        "a = 'bcd' | 'bcde' ;",
+			limits:         limit.Limits{MaxLiteralLength: 3},
+			expectedCode:   diag.CodeLiteralLengthLimitExceeded,
+			expectedOffset: 12,
+			expectedCause:  &limit.LiteralLengthError{Max: 3, Length: 4},
+		},
+		{
+			name:           "Rule count",
+			grammar:        "a = 'a' ;\nb = 'b' ;\n(* c *) c = 'c' ;",
+			limits:         limit.Limits{MaxRules: 2},
+			expectedCode:   diag.CodeRuleLimitExceeded,
+			expectedOffset: 28,
+			expectedCause:  &limit.RuleCountError{Max: 2},
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			parser := iso.New(iso.WithLimits(tc.limits))
+			_, err := parser.Parse(tc.grammar)
+			if !errors.Is(err, tc.expectedCode) {
+				t.Fatalf("Expected error with code %s. Got %v.", tc.expectedCode, err)
+			}
+			var parseErr *iso.ParseError
+			if !errors.As(err, &parseErr) {
+				t.Fatalf("Expected a parse error. Got %v.", err)
+			}
+			if parseErr.Offset != tc.expectedOffset {
+				t.Errorf("Expected error at offset %d. Got %d.", tc.expectedOffset, parseErr.Offset)
+			}
+			if cause := parseErr.Unwrap(); cause == nil || cause.Error() != tc.expectedCause.Error() {
+				t.Errorf("Expected error caused by %q. Got %v.", tc.expectedCause, cause)
+			}
+		})
+	}
+}
+
+func TestParseSyntaxLiteralLengthInComment(t *testing.T) {
+	t.Parallel()
+	// Quoted text within a comment is not a terminal, so is not limited in length.
+	parser := iso.New(iso.WithLimits(limit.Limits{MaxLiteralLength: 3}))
+	if _, err := parser.Parse("a = (* 'bcde' *) 'b' ;"); err != nil {
+		t.Errorf("Expected no error. Got %v.", err)
+	}
+}
+
+func TestParseSyntaxLimitsStopRecovery(t *testing.T) {
+	t.Parallel()
+	parser := iso.New(iso.WithRecovery(), iso.WithLimits(limit.Limits{MaxRules: 2, MaxLiteralLength: 1}))
+	syntax, err := parser.Parse("a = 'aa' ;\nb = 'b' ;\nc = 'c' ;\nd = 'd' ;")
+	if len(syntax.Rules) != 1 || syntax.Rules[0].MetaIdentifier != "b" {
+		t.Errorf("Expected only rule b to be parsed. Got %v.", syntax.Rules)
+	}
+	if len(diag.Diagnostics(err)) != 2 {
+		t.Errorf("Expected 2 errors. Got %v.", err)
+	}
+	var ruleCountErr *limit.RuleCountError
+	if !errors.As(err, &ruleCountErr) {
+		t.Errorf("Expected rule count error. Got %v.", err)
+	}
+}
+
+func TestParseSyntaxInputSizeLimit(t *testing.T) {
+	t.Parallel()
+	for name, parse := range map[string]func(parser *iso.Parser) error{
+		"Parse": func(parser *iso.Parser) error {
+			_, err := parser.Parse("a = 'bc' ;")
+
+			return err
+		},
+		"ParseReader": func(parser *iso.Parser) error {
+			_, err := parser.ParseReader("a.ebnf", strings.NewReader("a = 'bc' ;"))
+
+			return err
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			var inputSizeErr *limit.InputSizeError
+			if err := parse(iso.New(iso.WithLimits(limit.Limits{MaxInputSize: 8}))); !errors.As(err, &inputSizeErr) {
+				t.Fatalf("Expected input size error. Got %v.", err)
+			}
+			if inputSizeErr.Max != 8 {
+				t.Errorf("Expected limit of 8 bytes. Got %d.", inputSizeErr.Max)
+			}
+			if !errors.Is(inputSizeErr, diag.CodeInputSizeLimitExceeded) {
+				t.Errorf("Expected error with code %s. Got %v.", diag.CodeInputSizeLimitExceeded, inputSizeErr)
+			}
+		})
+	}
+}
+
+func TestParseContext(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	parser := iso.New()
+	_, err := parser.ParseContext(ctx, "a = 'a' ;")
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected error to wrap %s. Got %v.", context.Canceled, err)
+	}
+	if !errors.Is(err, diag.CodeCancelled) {
+		t.Errorf("Expected error with code %s. Got %v.", diag.CodeCancelled, err)
+	}
+}
+
+func TestParseStrict(t *testing.T) {
+	t.Parallel()
+	testCases := map[string]struct {
+		grammar        string
+		expectedOffset int
+	}{
+		"non ISO 646 letter in rule meta identifier":       {grammar: "naïve = 'a' ;", expectedOffset: 2},
+		"non ISO 646 letter in referenced meta identifier": {grammar: "a = 'a', café ;", expectedOffset: 12},
+		"non ISO 646 digit in meta identifier":             {grammar: "a = b٣ ;", expectedOffset: 5},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			if _, err := iso.New().Parse(tc.grammar); err != nil {
+				t.Fatalf("Expected no error parsing without strict but got %s.", err)
+			}
+			_, err := iso.New(iso.WithStrict()).Parse(tc.grammar)
+			var parseErr *iso.ParseError
+			if !errors.As(err, &parseErr) {
+				t.Fatalf("Expected a parse error. Got %v.", err)
+			}
+			if parseErr.Code != diag.CodeNonStandardSyntax {
+				t.Errorf("Expected error with code %s. Got %s.", diag.CodeNonStandardSyntax, parseErr.Code)
+			}
+			if parseErr.Offset != tc.expectedOffset {
+				t.Errorf("Expected error at offset %d. Got %d.", tc.expectedOffset, parseErr.Offset)
+			}
+		})
+	}
+}
+
+func TestParseWithFilename(t *testing.T) {
+	t.Parallel()
+	parser := iso.New(iso.WithFilename("grammar.ebnf"))
+	syntax, err := parser.Parse("a = 'a' ;")
+	if err != nil {
+		t.Fatalf("Expected no error but got %s.", err)
+	}
+	if filename := syntax.Rules[0].Span.Start.Filename; filename != "grammar.ebnf" {
+		t.Errorf("Expected file name %q. Got %q.", "grammar.ebnf", filename)
+	}
+	_, err = parser.ParseContext(t.Context(), "a = 'a'")
+	var ruleErr *iso.ParseRuleError
+	if !errors.As(err, &ruleErr) {
+		t.Fatalf("Expected a parse rule error. Got %v.", err)
+	}
+	if ruleErr.Filename != "grammar.ebnf" {
+		t.Errorf("Expected file name %q. Got %q.", "grammar.ebnf", ruleErr.Filename)
+	}
+	syntax, err = parser.ParseReader("other.ebnf", strings.NewReader("a = 'a' ;"))
+	if err != nil {
+		t.Fatalf("Expected no error but got %s.", err)
+	}
+	if filename := syntax.Rules[0].Span.Start.Filename; filename != "other.ebnf" {
+		t.Errorf("Expected file name %q. Got %q.", "other.ebnf", filename)
+	}
+}
+
+// TestParserConcurrentUse shares a Parser between goroutines, which should be run with the race detector enabled.
+func TestParserConcurrentUse(t *testing.T) {
+	t.Parallel()
+	grammars := []string{
+		"a = 'a' ;",
+		"(* comment *) a = [ b ], { c } ; b = 'b' ; c = 3 * 'c' - 'd' ;",
+		"a = ( 'a' ; b = 'b' ;",
+		"a = 'a' ; b ;",
+		"a = ? special ? | 'a' ; (* trailing *)",
+	}
+	parser := iso.New(iso.WithRecovery(), iso.WithLimits(limit.Limits{MaxRules: 4}))
+	type result struct {
+		syntax string
+		err    string
+	}
+	parse := func(parser *iso.Parser, grammar string) result {
+		syntax, err := parser.Parse(grammar)
+		actual, marshalErr := json.Marshal(syntax)
+		if marshalErr != nil {
+			t.Errorf("Expected no error marshalling syntax but got %s.", marshalErr)
+		}
+		res := result{syntax: string(actual)}
+		if err != nil {
+			res.err = err.Error()
+		}
+
+		return res
+	}
+	expected := make([]result, len(grammars))
+	for i, grammar := range grammars {
+		expected[i] = parse(iso.New(iso.WithRecovery(), iso.WithLimits(limit.Limits{MaxRules: 4})), grammar)
+	}
+	const goroutines = 8
+	var wg sync.WaitGroup
+	for range goroutines {
+		wg.Go(func() {
+			for i, grammar := range grammars {
+				if actual := parse(parser, grammar); actual != expected[i] {
+					t.Errorf("Expected result %+v parsing %q. Got %+v.", expected[i], grammar, actual)
+				}
+			}
+		})
+	}
+	wg.Wait()
+}
+
+var update = flag.Bool("update", false, "update the golden files of the corpus tests")
+
+func TestParseSyntaxCorpus(t *testing.T) {
+	t.Parallel()
+	grammars, err := filepath.Glob(filepath.Join("testdata", "corpus", "*.ebnf"))
+	if err != nil {
+		t.Fatalf("Expected no error finding corpus grammars but got %s.", err)
+	}
+	if len(grammars) == 0 {
+		t.Fatal("Expected corpus grammars but found none.")
+	}
+	for _, grammar := range grammars {
+		t.Run(filepath.Base(grammar), func(t *testing.T) {
+			t.Parallel()
+			content, err := os.ReadFile(grammar)
+			if err != nil {
+				t.Fatalf("Expected no error reading grammar but got %s.", err)
+			}
+			parser := iso.New()
+			syntax, err := parser.Parse(string(content))
+			if err != nil {
+				t.Fatalf("Expected no error parsing grammar but got %s.", err)
+			}
+			actual, err := json.MarshalIndent(syntax, "", "  ")
+			if err != nil {
+				t.Fatalf("Expected no error marshalling syntax but got %s.", err)
+			}
+			actual = append(actual, '\n')
+			golden := strings.TrimSuffix(grammar, ".ebnf") + ".json"
+			if *update {
+				if err := os.WriteFile(golden, actual, 0o600); err != nil {
+					t.Fatalf("Expected no error updating golden file but got %s.", err)
+				}
+			}
+			expected, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("Expected no error reading golden file but got %s.", err)
+			}
+			if !bytes.Equal(expected, actual) {
+				t.Errorf("Syntax of %s does not match %s, run the tests with -update to regenerate it.", grammar, golden)
+			}
+		})
+	}
+}
+
+func BenchmarkParseLongLine(b *testing.B) {
+	grammar := "a = b" + strings.Repeat(", b", 80000) + " ;"
+	parser := iso.New()
+	for b.Loop() {
+		if _, err := parser.Parse(grammar); err != nil {
+			b.Fatalf("Expected no error but got %s.", err)
+		}
+	}
+}
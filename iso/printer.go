@@ -0,0 +1,316 @@
+package iso
+
+import (
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/alec-w/ebnf-go/cst"
+	"github.com/alec-w/ebnf-go/source"
+)
+
+// DefaultLineWidth is the line width a Printer keeps rules within unless configured otherwise.
+const DefaultLineWidth = 80
+
+// Printer prints a Syntax as an ISO 14977 grammar in a canonical layout, such that parsing the printed grammar gives
+// the same Syntax (apart from the positions of its nodes).
+//
+// Each rule is printed on its own line, preceded by its comments on their own lines, with its definitions separated by
+// "|", its terms by "," and terminated by ";". A rule that is wider than the line width is broken with each definition
+// on its own line, and a definition that is still wider is broken after the "," between its terms. Comments on a
+// factor are printed before it, and the trailing comments of the syntax are printed after the last rule. Meta
+// identifiers are printed as they are spelled in the grammar, with the whitespace within them, if the syntax has a
+// concrete syntax tree (see WithCST), and otherwise without any whitespace.
+//
+// A Printer is safe for concurrent use by multiple goroutines.
+type Printer struct {
+	lineWidth   int
+	align       bool
+	alternative bool
+	// spellings maps the offsets of the meta identifiers of the syntax being formatted to their spellings.
+	spellings map[int]string
+}
+
+// PrinterOption configures a Printer.
+type PrinterOption func(*Printer)
+
+// WithLineWidth configures the width (in characters) a Printer tries to keep lines within, which is DefaultLineWidth
+// unless configured otherwise. A width of 0 means that rules are never broken across lines.
+func WithLineWidth(width int) PrinterOption {
+	return func(p *Printer) {
+		p.lineWidth = width
+	}
+}
+
+// WithAlignedDefiningSymbols configures a Printer to pad the meta identifiers of rules so that the defining symbols
+// ("=") of every rule are aligned.
+func WithAlignedDefiningSymbols() PrinterOption {
+	return func(p *Printer) {
+		p.align = true
+	}
+}
+
+// WithAlternativeBrackets configures a Printer to print optional and repeated sequences with the alternative
+// representations of their brackets, "(/ ... /)" and "(: ... :)", rather than "[ ... ]" and "{ ... }".
+func WithAlternativeBrackets() PrinterOption {
+	return func(p *Printer) {
+		p.alternative = true
+	}
+}
+
+// NewPrinter instantiates a Printer.
+func NewPrinter(opts ...PrinterOption) *Printer {
+	printer := &Printer{lineWidth: DefaultLineWidth}
+	for _, opt := range opts {
+		opt(printer)
+	}
+
+	return printer
+}
+
+// Print writes the syntax to w as an ISO 14977 grammar.
+func (p *Printer) Print(w io.Writer, syntax Syntax) error {
+	if _, err := io.WriteString(w, p.Format(syntax)); err != nil {
+		return &PrintError{wrapped: err}
+	}
+
+	return nil
+}
+
+// Format returns the syntax as an ISO 14977 grammar.
+func (p *Printer) Format(syntax Syntax) string {
+	// The spellings are only for this syntax, so are kept on a copy of the printer.
+	printer := *p
+	printer.spellings = spellings(syntax.CST)
+
+	return printer.format(syntax)
+}
+
+func (p *Printer) format(syntax Syntax) string {
+	nameWidth := 0
+	if p.align {
+		for _, rule := range syntax.Rules {
+			nameWidth = max(nameWidth, utf8.RuneCountInString(p.metaIdentifier(rule.MetaIdentifier, rule.Span)))
+		}
+	}
+	out := new(strings.Builder)
+	for _, rule := range syntax.Rules {
+		for _, comment := range rule.Comments {
+			out.WriteString(formatComment(comment) + "\n")
+		}
+		for _, line := range p.formatRule(rule, nameWidth) {
+			out.WriteString(line + "\n")
+		}
+	}
+	for _, comment := range syntax.TrailingComments {
+		out.WriteString(formatComment(comment) + "\n")
+	}
+
+	return out.String()
+}
+
+// formatRule returns the lines of a rule, with its meta identifier padded to nameWidth.
+func (p *Printer) formatRule(rule Rule, nameWidth int) []string {
+	metaIdentifier := p.metaIdentifier(rule.MetaIdentifier, rule.Span)
+	padding := max(0, nameWidth-utf8.RuneCountInString(metaIdentifier))
+	head := metaIdentifier + strings.Repeat(" ", padding) + " = "
+	definitions := make([][]string, 0, len(rule.Definitions))
+	formatted := make([]string, 0, len(rule.Definitions))
+	for _, definition := range rule.Definitions {
+		terms := p.formatTerms(definition.Terms)
+		definitions = append(definitions, terms)
+		formatted = append(formatted, join(terms, ","))
+	}
+	line := strings.TrimRight(head+join(formatted, "|"), " ") + " ;"
+	if p.fits(line) {
+		return []string{line}
+	}
+	// The definitions separators are aligned below the defining symbol.
+	separator := strings.Repeat(" ", utf8.RuneCountInString(head)-len("= ")) + "| "
+	var lines []string
+	for i, terms := range definitions {
+		prefix := head
+		if i > 0 {
+			prefix = separator
+		}
+		lines = append(lines, p.wrapTerms(prefix, terms)...)
+	}
+	lines[len(lines)-1] = strings.TrimRight(lines[len(lines)-1], " ") + " ;"
+
+	return lines
+}
+
+// wrapTerms returns the lines of a definition starting with prefix, which is broken after the "," between its terms if
+// it is wider than the line width, with the terms on the following lines aligned with the first.
+func (p *Printer) wrapTerms(prefix string, terms []string) []string {
+	indent := strings.Repeat(" ", utf8.RuneCountInString(prefix))
+	var lines []string
+	line := prefix
+	for i, term := range terms {
+		switch {
+		case i == 0:
+			line += term
+		case p.fits(line + ", " + term):
+			line += ", " + term
+		default:
+			lines = append(lines, strings.TrimRight(line+",", " "))
+			line = indent + term
+		}
+	}
+
+	return append(lines, strings.TrimRight(line, " "))
+}
+
+// fits checks whether a line is within the line width.
+func (p *Printer) fits(line string) bool {
+	return p.lineWidth <= 0 || utf8.RuneCountInString(line) <= p.lineWidth
+}
+
+func (p *Printer) formatDefinitionsList(definitions DefinitionsList) string {
+	formatted := make([]string, 0, len(definitions))
+	for _, definition := range definitions {
+		formatted = append(formatted, join(p.formatTerms(definition.Terms), ","))
+	}
+
+	return join(formatted, "|")
+}
+
+func (p *Printer) formatTerms(terms []Term) []string {
+	formatted := make([]string, 0, len(terms))
+	for _, term := range terms {
+		parts := []string{p.formatFactor(term.Factor)}
+		if !term.Exception.Primary.IsZero() {
+			parts = append(parts, "-", p.formatFactor(term.Exception))
+		}
+		formatted = append(formatted, joinNonEmpty(parts))
+	}
+
+	return formatted
+}
+
+func (p *Printer) formatFactor(factor Factor) string {
+	parts := make([]string, 0, len(factor.Comments)+2)
+	for _, comment := range factor.Comments {
+		parts = append(parts, formatComment(comment))
+	}
+	if factor.Repetitions >= 0 {
+		parts = append(parts, strconv.Itoa(factor.Repetitions)+" *")
+	}
+	parts = append(parts, p.formatPrimary(factor.Primary))
+
+	return joinNonEmpty(parts)
+}
+
+func (p *Printer) formatPrimary(primary Primary) string {
+	switch {
+	case primary.OptionalSequence != nil:
+		if p.alternative {
+			return wrap("(/", p.formatDefinitionsList(primary.OptionalSequence), "/)")
+		}
+
+		return wrap("[", p.formatDefinitionsList(primary.OptionalSequence), "]")
+	case primary.RepeatedSequence != nil:
+		if p.alternative {
+			return wrap("(:", p.formatDefinitionsList(primary.RepeatedSequence), ":)")
+		}
+
+		return wrap("{", p.formatDefinitionsList(primary.RepeatedSequence), "}")
+	case primary.GroupedSequence != nil:
+		return wrap("(", p.formatDefinitionsList(primary.GroupedSequence), ")")
+	case primary.MetaIdentifier != "":
+		return p.metaIdentifier(primary.MetaIdentifier, primary.Span)
+	case primary.Terminal != "":
+		// A terminal cannot contain the quote it is wrapped in, so it is wrapped in double quotes if it contains a
+		// single quote.
+		if strings.Contains(primary.Terminal, "'") {
+			return `"` + primary.Terminal + `"`
+		}
+
+		return "'" + primary.Terminal + "'"
+	case primary.EmptyTerminal:
+		return "''"
+	case primary.SpecialSequence != "":
+		return "? " + primary.SpecialSequence + " ?"
+	case primary.EmptySpecialSequence:
+		return "? ?"
+	default:
+		// An empty primary (like the zero value of a primary) is an empty sequence, which is printed as nothing.
+		return ""
+	}
+}
+
+// metaIdentifier returns the spelling of the meta identifier starting at the span, with any whitespace within it
+// collapsed to single spaces, or the meta identifier itself if its spelling is not known.
+func (p *Printer) metaIdentifier(metaIdentifier string, span source.Span) string {
+	spelling, ok := p.spellings[span.Start.Offset]
+	if !ok || removeWhitespace(spelling) != metaIdentifier {
+		return metaIdentifier
+	}
+
+	return strings.Join(strings.Fields(spelling), " ")
+}
+
+// spellings returns the spellings of the meta identifiers of the concrete syntax tree by their offsets.
+func spellings(tree *cst.Node) map[int]string {
+	if tree == nil {
+		return nil
+	}
+	spellings := map[int]string{}
+	for _, token := range tree.Tokens() {
+		if token.Kind == KindMetaIdentifier {
+			spellings[token.Span.Start.Offset] = token.Text
+		}
+	}
+
+	return spellings
+}
+
+// formatComment returns a comment wrapped in comment symbols.
+func formatComment(comment string) string {
+	return wrap("(*", comment, "*)")
+}
+
+// wrap returns the text between the opening and closing symbols, separated by spaces.
+func wrap(opening, text, closing string) string {
+	if text == "" {
+		return opening + " " + closing
+	}
+
+	return opening + " " + text + " " + closing
+}
+
+// join returns the items joined by the separator, with a space before each non-empty item (other than the first) so
+// that empty items (e.g. empty definitions) do not leave doubled spaces. The separator is preceded by a space unless it
+// is a ",".
+func join(items []string, separator string) string {
+	out := new(strings.Builder)
+	for i, item := range items {
+		if i > 0 {
+			if separator != "," && out.Len() > 0 {
+				out.WriteString(" ")
+			}
+			out.WriteString(separator)
+		}
+		if item != "" {
+			if out.Len() > 0 {
+				out.WriteString(" ")
+			}
+			out.WriteString(item)
+		}
+	}
+
+	return out.String()
+}
+
+// joinNonEmpty returns the non-empty parts joined by spaces.
+func joinNonEmpty(parts []string) string {
+	nonEmpty := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if part != "" {
+			nonEmpty = append(nonEmpty, part)
+		}
+	}
+
+	return strings.Join(nonEmpty, " ")
+}
@@ -0,0 +1,229 @@
+package iso_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alec-w/ebnf-go/iso"
+)
+
+func TestPrinterFormat(t *testing.T) {
+	t.Parallel()
+	testCases := map[string]struct {
+		grammar    string
+		parserOpts []iso.Option
+		opts       []iso.PrinterOption
+		expected   string
+	}{
+		"terminals": {
+			grammar:  `a="a"|"'"|'"';`,
+			expected: "a = 'a' | \"'\" | '\"' ;\n",
+		},
+		"terms": {
+			grammar:  "a = b,c - d , 3*e - 2 * f ;",
+			expected: "a = b, c - d, 3 * e - 2 * f ;\n",
+		},
+		"sequences": {
+			grammar:  "a = [b] , {c|d} , (e) , (/f/) , (:g:) ;",
+			expected: "a = [ b ], { c | d }, ( e ), [ f ], { g } ;\n",
+		},
+		"alternative brackets": {
+			grammar:  "a = [b] , {c|d} , (e) ;",
+			opts:     []iso.PrinterOption{iso.WithAlternativeBrackets()},
+			expected: "a = (/ b /), (: c | d :), ( e ) ;\n",
+		},
+		"empty terminals": {
+			grammar:  `a = '' | "" | ? ? ;`,
+			expected: "a = '' | '' | ? ? ;\n",
+		},
+		"special sequences": {
+			grammar:  "a = ?  any character ? | ? ? ;",
+			expected: "a = ? any character ? | ? ? ;\n",
+		},
+		"empty special sequence exception": {
+			grammar:  "a = b - ? ? ;",
+			expected: "a = b - ? ? ;\n",
+		},
+		"empty definitions": {
+			grammar:  "a = ; b = | c , ; d = [ ] ;",
+			expected: "a = ;\nb = | c, ;\nd = [ ] ;\n",
+		},
+		"comments": {
+			grammar: "(* first *) a (* second *) = (*third*) 2 (* fourth *) * b ;\n(* trailing *)",
+			expected: "(* second *)\n(* first *)\na = (* third *) (* fourth *) 2 * b ;\n" +
+				"(* trailing *)\n",
+		},
+		"aligned defining symbols": {
+			grammar:  "a = b ; long name = c ; (* comment *) bc = d ;",
+			opts:     []iso.PrinterOption{iso.WithAlignedDefiningSymbols()},
+			expected: "a        = b ;\nlongname = c ;\n(* comment *)\nbc       = d ;\n",
+		},
+		"spelled meta identifiers": {
+			grammar:    "a = long name , other\n\tname | c d ; long  name = c ;",
+			parserOpts: []iso.Option{iso.WithCST()},
+			opts:       []iso.PrinterOption{iso.WithAlignedDefiningSymbols()},
+			expected:   "a         = long name, other name | c d ;\nlong name = c ;\n",
+		},
+		"broken definitions": {
+			grammar:  "rule = 'first' | 'second' | 'third' ;",
+			opts:     []iso.PrinterOption{iso.WithLineWidth(20)},
+			expected: "rule = 'first'\n     | 'second'\n     | 'third' ;\n",
+		},
+		"broken terms": {
+			grammar:  "rule = 'first', 'second', 'third' | 'fourth' ;",
+			opts:     []iso.PrinterOption{iso.WithLineWidth(25)},
+			expected: "rule = 'first', 'second',\n       'third'\n     | 'fourth' ;\n",
+		},
+		"unlimited line width": {
+			grammar:  "rule = 'first', 'second', 'third' | 'fourth' ;",
+			opts:     []iso.PrinterOption{iso.WithLineWidth(0)},
+			expected: "rule = 'first', 'second', 'third' | 'fourth' ;\n",
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			syntax, err := iso.New(tc.parserOpts...).Parse(tc.grammar)
+			if err != nil {
+				t.Fatalf("Expected no error but got %s.", err)
+			}
+			if actual := iso.NewPrinter(tc.opts...).Format(syntax); actual != tc.expected {
+				t.Errorf("Expected grammar:\n%s\nGot:\n%s", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestPrinterPrint(t *testing.T) {
+	t.Parallel()
+	syntax, err := iso.New().Parse("a = 'a' ;")
+	if err != nil {
+		t.Fatalf("Expected no error but got %s.", err)
+	}
+	out := new(bytes.Buffer)
+	if err := iso.NewPrinter().Print(out, syntax); err != nil {
+		t.Fatalf("Expected no error but got %s.", err)
+	}
+	if out.String() != "a = 'a' ;\n" {
+		t.Errorf("Expected grammar %q. Got %q.", "a = 'a' ;\n", out.String())
+	}
+	writeErr := errors.New("write failed")
+	err = iso.NewPrinter().Print(errorWriter{err: writeErr}, syntax)
+	var printErr *iso.PrintError
+	if !errors.As(err, &printErr) {
+		t.Fatalf("Expected a print error. Got %v.", err)
+	}
+	if !errors.Is(err, writeErr) {
+		t.Errorf("Expected error to wrap %s. Got %s.", writeErr, err)
+	}
+}
+
+// errorWriter is an io.Writer that always fails.
+type errorWriter struct {
+	err error
+}
+
+func (e errorWriter) Write([]byte) (int, error) {
+	return 0, e.err
+}
+
+func TestPrinterRoundTrip(t *testing.T) {
+	t.Parallel()
+	grammars, err := filepath.Glob(filepath.Join("testdata", "corpus", "*.ebnf"))
+	if err != nil {
+		t.Fatalf("Expected no error finding corpus grammars but got %s.", err)
+	}
+	for _, grammar := range grammars {
+		content, err := os.ReadFile(grammar)
+		if err != nil {
+			t.Fatalf("Expected no error reading grammar but got %s.", err)
+		}
+		for name, printer := range map[string]*iso.Printer{
+			"default":     iso.NewPrinter(),
+			"aligned":     iso.NewPrinter(iso.WithAlignedDefiningSymbols(), iso.WithLineWidth(40)),
+			"alternative": iso.NewPrinter(iso.WithAlternativeBrackets(), iso.WithLineWidth(0)),
+		} {
+			t.Run(filepath.Base(grammar)+"/"+name, func(t *testing.T) {
+				t.Parallel()
+				assertRoundTrip(t, printer, string(content))
+			})
+		}
+	}
+}
+
+func TestPrinterRoundTripEmptySpecialSequenceException(t *testing.T) {
+	t.Parallel()
+	grammar := "a = b - ? ? ;"
+	assertRoundTrip(t, iso.NewPrinter(), grammar)
+	syntax, err := iso.New().Parse(grammar)
+	if err != nil {
+		t.Fatalf("Expected no error parsing grammar but got %s.", err)
+	}
+	expected := `{"rules":[{"line":0,"metaIdentifier":"a","definitions":[{"terms":[{"exception":{"primary":` +
+		`{"emptySpecialSequence":true}},"factor":{"primary":{"metaIdentifier":"b"}}}]}]}]}`
+	if actual := marshalWithoutLines(t, syntax); actual != expected {
+		t.Errorf("Expected JSON %s. Got %s.", expected, actual)
+	}
+}
+
+// assertRoundTrip checks that printing the syntax of a grammar and parsing it again gives the same syntax, and that
+// printing that gives the same grammar again.
+func assertRoundTrip(t *testing.T, printer *iso.Printer, grammar string) {
+	t.Helper()
+	syntax, err := iso.New().Parse(grammar)
+	if err != nil {
+		return
+	}
+	printed := printer.Format(syntax)
+	reparsed, err := iso.New().Parse(printed)
+	if err != nil {
+		t.Fatalf("Expected no error parsing printed grammar but got %s. Printed:\n%s", err, printed)
+	}
+	if expected, actual := marshalWithoutLines(t, syntax), marshalWithoutLines(t, reparsed); expected != actual {
+		t.Errorf("Expected printed grammar to parse as:\n%s\nGot:\n%s\nPrinted:\n%s", expected, actual, printed)
+	}
+	if reprinted := printer.Format(reparsed); reprinted != printed {
+		t.Errorf("Expected printing to be stable. Printed:\n%s\nReprinted:\n%s", printed, reprinted)
+	}
+}
+
+// marshalWithoutLines returns the syntax as JSON without the lines of its rules, which change when printed.
+func marshalWithoutLines(t *testing.T, syntax iso.Syntax) string {
+	t.Helper()
+	rules := make([]iso.Rule, 0, len(syntax.Rules))
+	for _, rule := range syntax.Rules {
+		rule.Line = 0
+		rules = append(rules, rule)
+	}
+	syntax.Rules = rules
+	marshalled, err := json.Marshal(syntax)
+	if err != nil {
+		t.Fatalf("Expected no error marshalling syntax but got %s.", err)
+	}
+
+	return string(marshalled)
+}
+
+func FuzzPrinterRoundTrip(f *testing.F) {
+	for _, seed := range []string{
+		"a = 'a' ;",
+		"(* c *) a (* d *) = (* e *) 3 (* f *) * b - c, [ d | ] | { e } ; (* g *)",
+		"a = ( b ), (/ c /), (: d :), ? e ?, ? ?, '' ;",
+		"a = b - ? ? ;",
+		"a = , | - b ;",
+		"a = (* (* nested *) 'quoted *)' ? special *) ? **) b ;",
+	} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, grammar string) {
+		if strings.ContainsRune(grammar, '\x00') {
+			return
+		}
+		assertRoundTrip(t, iso.NewPrinter(iso.WithLineWidth(10)), grammar)
+	})
+}
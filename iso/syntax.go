@@ -2,12 +2,17 @@ package iso
 
 import (
 	"encoding/json"
+
+	"github.com/alec-w/ebnf-go/cst"
+	"github.com/alec-w/ebnf-go/source"
 )
 
 // Syntax represents a parsed EBNF syntax.
 type Syntax struct {
 	Rules            []Rule   `json:"rules"`
 	TrailingComments []string `json:"trailingComments,omitempty"`
+	// CST is the concrete syntax tree of the grammar, which is only built by a Parser configured WithCST.
+	CST *cst.Node `json:"-"`
 }
 
 // Rule is a single rule in a parsed EBNF syntax.
@@ -16,6 +21,7 @@ type Rule struct {
 	Comments       []string        `json:"comments,omitempty"`
 	MetaIdentifier string          `json:"metaIdentifier"`
 	Definitions    DefinitionsList `json:"definitions"`
+	Span           source.Span     `json:"-"`
 }
 
 // DefinitionsList is an explicit term for a list of definitions.
@@ -23,13 +29,15 @@ type DefinitionsList = []Definition
 
 // Definition is a single definition (within a rule or sequence) of a parsed EBNF syntax.
 type Definition struct {
-	Terms []Term `json:"terms"`
+	Terms []Term      `json:"terms"`
+	Span  source.Span `json:"-"`
 }
 
 // Term is a single term within a definition of a parsed EBNF syntax.
 type Term struct {
 	Factor    Factor
 	Exception Factor
+	Span      source.Span
 }
 
 // MarshalJSON fulfils the json.Marshaller interface to exclude a Term's Exception if it is empty.
@@ -52,6 +60,7 @@ type Factor struct {
 	Comments    []string
 	Repetitions int
 	Primary     Primary
+	Span        source.Span
 }
 
 // MarshalJSON fulfils the json.Marshaller interface to exclude a Factor's Repetitions field if there are no
@@ -76,26 +85,32 @@ func (f Factor) MarshalJSON() ([]byte, error) {
 // Primary is the core part of an EBNF syntax term, which will represent one of
 // - an optional sequence (0 or 1 instances of a sequence of definitions)
 // - a repeated sequence (0 or more repetitions of a sequence of definitions)
-// - a special sequence (a sequence of characters described in a form outside the scope of EBNF)
+// - a special sequence (a sequence of characters described in a form outside the scope of EBNF), which
+// EmptySpecialSequence records if it has no characters
 // - a grouped sequence (an instance of a sequence of definitions)
 // - a meta identifier (a reference to another rule of the syntax)
-// - a terminal (a string of characters)
+// - a terminal (a string of characters), which EmptyTerminal records if it has no characters
 // - empty (an empty sequence of definitions).
 type Primary struct {
-	OptionalSequence DefinitionsList `json:"optionalSequence,omitempty"`
-	RepeatedSequence DefinitionsList `json:"repeatedSequence,omitempty"`
-	SpecialSequence  string          `json:"specialSequence,omitempty"`
-	GroupedSequence  DefinitionsList `json:"groupedSequence,omitempty"`
-	MetaIdentifier   string          `json:"metaIdentifier,omitempty"`
-	Terminal         string          `json:"terminal,omitempty"`
-	Empty            bool            `json:"empty,omitempty"`
+	OptionalSequence     DefinitionsList `json:"optionalSequence,omitempty"`
+	RepeatedSequence     DefinitionsList `json:"repeatedSequence,omitempty"`
+	SpecialSequence      string          `json:"specialSequence,omitempty"`
+	EmptySpecialSequence bool            `json:"emptySpecialSequence,omitempty"`
+	GroupedSequence      DefinitionsList `json:"groupedSequence,omitempty"`
+	MetaIdentifier       string          `json:"metaIdentifier,omitempty"`
+	Terminal             string          `json:"terminal,omitempty"`
+	EmptyTerminal        bool            `json:"emptyTerminal,omitempty"`
+	Empty                bool            `json:"empty,omitempty"`
+	Span                 source.Span     `json:"-"`
 }
 
 // IsZero returns false if all the fields within the Primary are their empty values.
 func (p *Primary) IsZero() bool {
 	return p.OptionalSequence == nil && p.RepeatedSequence == nil && p.SpecialSequence == "" &&
+		!p.EmptySpecialSequence &&
 		p.GroupedSequence == nil &&
 		p.MetaIdentifier == "" &&
 		p.Terminal == "" &&
+		!p.EmptyTerminal &&
 		!p.Empty
 }
@@ -0,0 +1,115 @@
+package ebnf_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/alec-w/ebnf-go"
+	"github.com/alec-w/ebnf-go/iso"
+)
+
+func TestFromISO(t *testing.T) {
+	t.Parallel()
+	testCases := map[string]struct {
+		grammar  string
+		expected string
+	}{
+		"literal":       {grammar: "a = 'a' ;", expected: `{"literal":"a"}`},
+		"reference":     {grammar: "a = b ;", expected: `{"reference":{"name":"b"}}`},
+		"special":       {grammar: "a = ? any character ? ;", expected: `{"special":"any character"}`},
+		"empty":         {grammar: "a = ;", expected: `{"sequence":[]}`},
+		"empty special": {grammar: "a = ? ? ;", expected: `{"special":""}`},
+		"sequence": {
+			grammar:  "a = 'a', b ;",
+			expected: `{"sequence":[{"literal":"a"},{"reference":{"name":"b"}}]}`,
+		},
+		"choice": {
+			grammar:  "a = 'a' | 'b', c ;",
+			expected: `{"choice":[{"literal":"a"},{"sequence":[{"literal":"b"},{"reference":{"name":"c"}}]}]}`,
+		},
+		"optional": {grammar: "a = [ 'a' ] ;", expected: `{"optional":{"literal":"a"}}`},
+		"star":     {grammar: "a = { 'a' | 'b' } ;", expected: `{"star":{"choice":[{"literal":"a"},{"literal":"b"}]}}`},
+		"group":    {grammar: "a = ( 'a' ) ;", expected: `{"literal":"a"}`},
+		"repeat": {
+			grammar:  "a = 3 * 'a' ;",
+			expected: `{"repeat":{"min":3,"max":3,"expression":{"literal":"a"}}}`,
+		},
+		"exception": {
+			grammar:  "a = b - 'c' ;",
+			expected: `{"exception":{"match":{"reference":{"name":"b"}},"except":{"literal":"c"}}}`,
+		},
+		"exception of empty special": {
+			grammar:  "a = b - ? ? ;",
+			expected: `{"exception":{"match":{"reference":{"name":"b"}},"except":{"special":""}}}`,
+		},
+		"comments": {
+			grammar: "a = (* x *) 'a', (* y *) 2 * (* z *) b - (* w *) 'c' ;",
+			expected: `{"sequence":[{"comments":["x"],"literal":"a"},{"exception":{"match":{"comments":["y","z"],` +
+				`"repeat":{"min":2,"max":2,"expression":{"reference":{"name":"b"}}}},` +
+				`"except":{"comments":["w"],"literal":"c"}}}]}`,
+		},
+		"comments of a group": {
+			grammar:  "a = (* x *) ( (* y *) b ) ;",
+			expected: `{"comments":["x","y"],"reference":{"name":"b"}}`,
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			syntax, err := iso.New().Parse(tc.grammar)
+			if err != nil {
+				t.Fatalf("Expected no error but got %s.", err)
+			}
+			grammar := ebnf.FromISO(syntax)
+			actual, err := json.Marshal(grammar.Rules[0].Expression)
+			if err != nil {
+				t.Fatalf("Expected no error marshalling expression but got %s.", err)
+			}
+			if string(actual) != tc.expected {
+				t.Errorf("Expected expression %s. Got %s.", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestFromISOSpans(t *testing.T) {
+	t.Parallel()
+	grammar := "a = 'a' ;\nb = [ a ], 2 * c - 'd' | ( e ) ;"
+	syntax, err := iso.New().Parse(grammar)
+	if err != nil {
+		t.Fatalf("Expected no error but got %s.", err)
+	}
+	rule := ebnf.FromISO(syntax).Rules[1]
+	if rule.Span != syntax.Rules[1].Span {
+		t.Errorf("Expected rule span %#v. Got %#v.", syntax.Rules[1].Span, rule.Span)
+	}
+	choice, ok := rule.Expression.(*ebnf.Choice)
+	if !ok {
+		t.Fatalf("Expected a choice. Got %T.", rule.Expression)
+	}
+	sequence, ok := choice.Alternatives[0].(*ebnf.Sequence)
+	if !ok {
+		t.Fatalf("Expected a sequence. Got %T.", choice.Alternatives[0])
+	}
+	testCases := map[string]struct {
+		expression ebnf.Expression
+		expected   string
+	}{
+		"choice":    {expression: choice, expected: "[ a ], 2 * c - 'd' | ( e )"},
+		"sequence":  {expression: sequence, expected: "[ a ], 2 * c - 'd'"},
+		"optional":  {expression: sequence.Items[0], expected: "[ a ]"},
+		"exception": {expression: sequence.Items[1], expected: "2 * c - 'd'"},
+		"repeat":    {expression: sequence.Items[1].(*ebnf.Exception).Match, expected: "2 * c"},
+		"reference": {expression: sequence.Items[1].(*ebnf.Exception).Match.(*ebnf.Repeat).Expression, expected: "c"},
+		"group":     {expression: choice.Alternatives[1], expected: "e"},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			span := tc.expression.Span()
+			if actual := grammar[span.Start.Offset:span.End.Offset]; actual != tc.expected {
+				t.Errorf("Expected span of %q. Got %q.", tc.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,3 @@
+// Package limit provides limits on the resources used parsing an EBNF grammar, shared by the grammar parsers so that
+// grammars from untrusted sources can be parsed safely.
+package limit
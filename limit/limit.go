@@ -0,0 +1,121 @@
+package limit
+
+import (
+	"fmt"
+
+	"github.com/alec-w/ebnf-go/diag"
+	"github.com/alec-w/ebnf-go/source"
+)
+
+// DefaultMaxDepth is the nesting depth allowed by Default, which is far deeper than any grammar written by hand but
+// shallow enough to prevent the parsers exhausting the stack.
+const DefaultMaxDepth = 1000
+
+// DefaultMaxInputSize is the size of grammar in bytes allowed by Default, which is far larger than any published
+// grammar but bounds the memory and time used parsing one.
+const DefaultMaxInputSize = 16 << 20
+
+// Limits bounds the resources used parsing a grammar. A limit of zero (or less) is unbounded.
+type Limits struct {
+	// MaxDepth is the maximum nesting depth of bracketed sequences, parenthesised expressions or comments.
+	MaxDepth int
+	// MaxInputSize is the maximum size of a grammar in bytes.
+	MaxInputSize int
+	// MaxRules is the maximum number of rules in a grammar.
+	MaxRules int
+	// MaxLiteralLength is the maximum length in bytes of a terminal string or literal.
+	MaxLiteralLength int
+}
+
+// Default returns the limits used by the parsers unless configured otherwise, which only bound the nesting depth and
+// the input size.
+func Default() Limits {
+	return Limits{MaxDepth: DefaultMaxDepth, MaxInputSize: DefaultMaxInputSize}
+}
+
+// DepthExceeded returns true if the given depth is beyond the limit.
+func (l Limits) DepthExceeded(depth int) bool {
+	return exceeded(depth, l.MaxDepth)
+}
+
+// InputSizeExceeded returns true if the given size is beyond the limit.
+func (l Limits) InputSizeExceeded(size int) bool {
+	return exceeded(size, l.MaxInputSize)
+}
+
+// RulesExceeded returns true if the given number of rules is beyond the limit.
+func (l Limits) RulesExceeded(rules int) bool {
+	return exceeded(rules, l.MaxRules)
+}
+
+// LiteralLengthExceeded returns true if the given literal length is beyond the limit.
+func (l Limits) LiteralLengthExceeded(length int) bool {
+	return exceeded(length, l.MaxLiteralLength)
+}
+
+func exceeded(value, limit int) bool {
+	return limit > 0 && value > limit
+}
+
+// DepthError is the cause of a parse error for a bracketed sequence, parenthesised expression or comment nested deeper
+// than Limits.MaxDepth.
+type DepthError struct {
+	Max int
+}
+
+// Error fulfills the error interface.
+func (d *DepthError) Error() string {
+	return fmt.Sprintf("nesting depth exceeds the limit of %d", d.Max)
+}
+
+// InputSizeError is returned for a grammar larger than Limits.MaxInputSize, before any of it is parsed.
+//
+// Its code is diag.CodeInputSizeLimitExceeded, which can be checked with errors.Is.
+type InputSizeError struct {
+	// Filename is the name of the file containing the grammar, if it was parsed from a file.
+	Filename string
+	Max      int
+}
+
+// Error fulfills the error interface.
+func (i *InputSizeError) Error() string {
+	return fmt.Sprintf("grammar exceeds the size limit of %d bytes", i.Max)
+}
+
+// Is allows checking the code of the error with errors.Is.
+func (i *InputSizeError) Is(target error) bool {
+	code, ok := target.(diag.Code)
+
+	return ok && code == diag.CodeInputSizeLimitExceeded
+}
+
+// Diagnostic describes the input size error at the start of the grammar, for rendering (see diag.Renderer).
+func (i *InputSizeError) Diagnostic() diag.Diagnostic {
+	return diag.Diagnostic{
+		Code:     diag.CodeInputSizeLimitExceeded,
+		Message:  i.Error(),
+		Position: source.Position{Filename: i.Filename, Line: 1, Column: 1},
+	}
+}
+
+// RuleCountError is the cause of a parse error for a grammar with more rules than Limits.MaxRules.
+type RuleCountError struct {
+	Max int
+}
+
+// Error fulfills the error interface.
+func (r *RuleCountError) Error() string {
+	return fmt.Sprintf("number of rules exceeds the limit of %d", r.Max)
+}
+
+// LiteralLengthError is the cause of a parse error for a terminal string or literal longer than
+// Limits.MaxLiteralLength.
+type LiteralLengthError struct {
+	Max    int
+	Length int
+}
+
+// Error fulfills the error interface.
+func (l *LiteralLengthError) Error() string {
+	return fmt.Sprintf("literal of %d bytes exceeds the length limit of %d bytes", l.Length, l.Max)
+}
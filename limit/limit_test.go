@@ -0,0 +1,65 @@
+package limit_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/alec-w/ebnf-go/diag"
+	"github.com/alec-w/ebnf-go/limit"
+	"github.com/alec-w/ebnf-go/source"
+)
+
+func TestLimitsExceeded(t *testing.T) {
+	t.Parallel()
+	limits := limit.Limits{MaxDepth: 2, MaxInputSize: 10, MaxRules: 3}
+	tcs := []struct {
+		name     string
+		exceeded func(limit.Limits) bool
+		expected bool
+	}{
+		{name: "depth within limit", exceeded: func(l limit.Limits) bool { return l.DepthExceeded(2) }},
+		{name: "depth beyond limit", exceeded: func(l limit.Limits) bool { return l.DepthExceeded(3) }, expected: true},
+		{name: "input size within limit", exceeded: func(l limit.Limits) bool { return l.InputSizeExceeded(10) }},
+		{
+			name:     "input size beyond limit",
+			exceeded: func(l limit.Limits) bool { return l.InputSizeExceeded(11) },
+			expected: true,
+		},
+		{name: "rules beyond limit", exceeded: func(l limit.Limits) bool { return l.RulesExceeded(4) }, expected: true},
+		{name: "unbounded literal length", exceeded: func(l limit.Limits) bool { return l.LiteralLengthExceeded(1 << 30) }},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			if actual := tc.exceeded(limits); actual != tc.expected {
+				t.Errorf("Expected exceeded to be %t. Got %t.", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestDefaultBoundsInputSize(t *testing.T) {
+	t.Parallel()
+	if !limit.Default().InputSizeExceeded(limit.DefaultMaxInputSize + 1) {
+		t.Errorf("Expected default limits to bound the input size to %d bytes.", limit.DefaultMaxInputSize)
+	}
+}
+
+func TestInputSizeErrorDiagnostic(t *testing.T) {
+	t.Parallel()
+	err := &limit.InputSizeError{Filename: "a.ebnf", Max: 8}
+	if !errors.Is(err, diag.CodeInputSizeLimitExceeded) {
+		t.Errorf("Expected error with code %s. Got %v.", diag.CodeInputSizeLimitExceeded, err)
+	}
+	diagnostics := diag.Diagnostics(err)
+	if len(diagnostics) != 1 {
+		t.Fatalf("Expected 1 diagnostic. Got %d.", len(diagnostics))
+	}
+	if diagnostics[0].Code != diag.CodeInputSizeLimitExceeded {
+		t.Errorf("Expected diagnostic with code %s. Got %s.", diag.CodeInputSizeLimitExceeded, diagnostics[0].Code)
+	}
+	expectedPosition := source.Position{Filename: "a.ebnf", Line: 1, Column: 1}
+	if diagnostics[0].Position != expectedPosition {
+		t.Errorf("Expected diagnostic at %#v. Got %#v.", expectedPosition, diagnostics[0].Position)
+	}
+}
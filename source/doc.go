@@ -0,0 +1,2 @@
+// Package source provides positions and spans within the source of an EBNF grammar, shared by the grammar parsers.
+package source
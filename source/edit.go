@@ -0,0 +1,136 @@
+package source
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Edit replaces the text of a grammar between the byte offsets Start (inclusive) and End (exclusive) with Text, which
+// is inserted if they are the same.
+type Edit struct {
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+	Text  string `json:"text"`
+}
+
+// OverlappingEditError is returned if an edit replaces part of the text replaced by another edit.
+type OverlappingEditError struct {
+	Edit  Edit
+	Other Edit
+}
+
+func (o *OverlappingEditError) Error() string {
+	return fmt.Sprintf(
+		"edit of offsets %d to %d overlaps the edit of offsets %d to %d",
+		o.Edit.Start,
+		o.Edit.End,
+		o.Other.Start,
+		o.Other.End,
+	)
+}
+
+// InvalidEditError is returned if an edit is outside the grammar it edits, or ends before it starts.
+type InvalidEditError struct {
+	Edit Edit
+	// Length is the length of the grammar.
+	Length int
+}
+
+func (i *InvalidEditError) Error() string {
+	return fmt.Sprintf(
+		"edit of offsets %d to %d is invalid for a grammar of length %d",
+		i.Edit.Start,
+		i.Edit.End,
+		i.Length,
+	)
+}
+
+// MergeEdit adds an edit to edits, which must be ordered by offset without overlapping (as MergeEdit keeps them),
+// returning an OverlappingEditError if it replaces part, but not all, of the text replaced by another edit.
+//
+// An edit supersedes any edits within the text it replaces, including an edit that replaces the same text, and an edit
+// within the text replaced by another edit is dropped, as that text is replaced anyway. Insertions at the same offset
+// are made in the order they are merged, before any replacement starting at that offset.
+func MergeEdit(edits []Edit, edit Edit) ([]Edit, error) {
+	merged := make([]Edit, 0, len(edits)+1)
+	for _, other := range edits {
+		switch {
+		case other.within(edit):
+			continue
+		case edit.within(other):
+			return edits, nil
+		case edit.Start < other.End && other.Start < edit.End:
+			return edits, &OverlappingEditError{Edit: edit, Other: other}
+		}
+		merged = append(merged, other)
+	}
+	i := sort.Search(len(merged), func(i int) bool {
+		return merged[i].Start > edit.Start || merged[i].Start == edit.Start && edit.isInsertion() &&
+			!merged[i].isInsertion()
+	})
+
+	return slices.Insert(merged, i, edit), nil
+}
+
+// within returns true if the edit is within the text replaced by the other edit, which an insertion at either end of
+// that text is not.
+func (e Edit) within(other Edit) bool {
+	if other.isInsertion() || e.isInsertion() && (e.Start == other.Start || e.Start == other.End) {
+		return false
+	}
+
+	return other.Start <= e.Start && e.End <= other.End
+}
+
+// isInsertion returns true if the edit inserts text rather than replacing it.
+func (e Edit) isInsertion() bool {
+	return e.Start == e.End
+}
+
+// Apply returns the grammar with the edits made to it, which must be ordered by offset without overlapping (see
+// MergeEdit).
+func Apply(grammar string, edits []Edit) (string, error) {
+	out := new(strings.Builder)
+	offset := 0
+	for i, edit := range edits {
+		if edit.Start < 0 || edit.End < edit.Start || edit.End > len(grammar) {
+			return "", &InvalidEditError{Edit: edit, Length: len(grammar)}
+		}
+		if edit.Start < offset {
+			return "", &OverlappingEditError{Edit: edit, Other: edits[i-1]}
+		}
+		out.WriteString(grammar[offset:edit.Start])
+		out.WriteString(edit.Text)
+		offset = edit.End
+	}
+	out.WriteString(grammar[offset:])
+
+	return out.String(), nil
+}
+
+// LineExtent returns the offsets of the text between the offsets start and end of the grammar extended over the
+// whitespace around it that would be left behind were it deleted: the whole of its line if nothing else is on it, and
+// otherwise the spaces and tabs that follow it, or if nothing follows it on its line, those that precede it.
+func LineExtent(grammar string, start, end int) (int, int) {
+	lineStart := len(strings.TrimRight(grammar[:start], " \t"))
+	lineEnd := len(grammar) - len(strings.TrimLeft(grammar[end:], " \t"))
+	before := lineStart == 0 || grammar[lineStart-1] == '\n'
+	after := lineEnd == len(grammar) || grammar[lineEnd] == '\n' || strings.HasPrefix(grammar[lineEnd:], "\r\n")
+	switch {
+	case before && after:
+		if strings.HasPrefix(grammar[lineEnd:], "\r\n") {
+			return lineStart, lineEnd + len("\r\n")
+		}
+		if lineEnd < len(grammar) {
+			return lineStart, lineEnd + len("\n")
+		}
+
+		return lineStart, lineEnd
+	case after:
+		return lineStart, lineEnd
+	default:
+		return start, lineEnd
+	}
+}
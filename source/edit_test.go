@@ -0,0 +1,159 @@
+package source_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/alec-w/ebnf-go/source"
+)
+
+func TestMergeEdit(t *testing.T) {
+	t.Parallel()
+	edits := []source.Edit{{Start: 2, End: 4, Text: "a"}, {Start: 6, End: 6, Text: "b"}, {Start: 8, End: 10}}
+	tcs := []struct {
+		name        string
+		edit        source.Edit
+		expected    []source.Edit
+		overlapping bool
+	}{
+		{
+			name:     "before every edit",
+			edit:     source.Edit{Start: 0, End: 1, Text: "c"},
+			expected: []source.Edit{{Start: 0, End: 1, Text: "c"}, edits[0], edits[1], edits[2]},
+		},
+		{
+			name:     "between edits",
+			edit:     source.Edit{Start: 4, End: 5, Text: "c"},
+			expected: []source.Edit{edits[0], {Start: 4, End: 5, Text: "c"}, edits[1], edits[2]},
+		},
+		{
+			name:     "insertion at the same offset as an insertion",
+			edit:     source.Edit{Start: 6, End: 6, Text: "c"},
+			expected: []source.Edit{edits[0], edits[1], {Start: 6, End: 6, Text: "c"}, edits[2]},
+		},
+		{
+			name:     "insertion at the start of a replacement",
+			edit:     source.Edit{Start: 8, End: 8, Text: "c"},
+			expected: []source.Edit{edits[0], edits[1], {Start: 8, End: 8, Text: "c"}, edits[2]},
+		},
+		{
+			name:     "insertion at the end of a replacement",
+			edit:     source.Edit{Start: 4, End: 4, Text: "c"},
+			expected: []source.Edit{edits[0], {Start: 4, End: 4, Text: "c"}, edits[1], edits[2]},
+		},
+		{
+			name:     "replacement of the text replaced by an edit",
+			edit:     source.Edit{Start: 2, End: 4, Text: "c"},
+			expected: []source.Edit{{Start: 2, End: 4, Text: "c"}, edits[1], edits[2]},
+		},
+		{
+			name:     "replacement of the text of several edits",
+			edit:     source.Edit{Start: 1, End: 7, Text: "c"},
+			expected: []source.Edit{{Start: 1, End: 7, Text: "c"}, edits[2]},
+		},
+		{
+			name:     "within the text replaced by an edit",
+			edit:     source.Edit{Start: 9, End: 9, Text: "c"},
+			expected: edits,
+		},
+		{
+			name:        "overlapping an edit",
+			edit:        source.Edit{Start: 3, End: 5, Text: "c"},
+			expected:    edits,
+			overlapping: true,
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			actual, err := source.MergeEdit(edits, tc.edit)
+			var overlappingErr *source.OverlappingEditError
+			if overlapping := errors.As(err, &overlappingErr); overlapping != tc.overlapping {
+				t.Errorf("Expected overlapping %t but got error %v.", tc.overlapping, err)
+			}
+			if !slices.Equal(actual, tc.expected) {
+				t.Errorf("Expected edits %#v but got %#v.", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	t.Parallel()
+	tcs := []struct {
+		name     string
+		edits    []source.Edit
+		expected string
+		err      error
+	}{
+		{name: "no edits", expected: "abcdef"},
+		{
+			name:     "replacements and insertions",
+			edits:    []source.Edit{{Start: 0, End: 0, Text: "("}, {Start: 1, End: 3, Text: "x"}, {Start: 6, End: 6, Text: ")"}},
+			expected: "(axdef)",
+		},
+		{name: "deletion", edits: []source.Edit{{Start: 2, End: 4}}, expected: "abef"},
+		{
+			name:  "outside the grammar",
+			edits: []source.Edit{{Start: 4, End: 7}},
+			err:   &source.InvalidEditError{Edit: source.Edit{Start: 4, End: 7}, Length: 6},
+		},
+		{
+			name:  "ending before it starts",
+			edits: []source.Edit{{Start: 4, End: 3}},
+			err:   &source.InvalidEditError{Edit: source.Edit{Start: 4, End: 3}, Length: 6},
+		},
+		{
+			name:  "overlapping",
+			edits: []source.Edit{{Start: 1, End: 4}, {Start: 3, End: 5}},
+			err:   &source.OverlappingEditError{Edit: source.Edit{Start: 3, End: 5}, Other: source.Edit{Start: 1, End: 4}},
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			actual, err := source.Apply("abcdef", tc.edits)
+			if tc.err != nil {
+				if err == nil || err.Error() != tc.err.Error() {
+					t.Errorf("Expected error %q but got %v.", tc.err, err)
+				}
+
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error but got %q.", err)
+			}
+			if actual != tc.expected {
+				t.Errorf("Expected %q but got %q.", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestLineExtent(t *testing.T) {
+	t.Parallel()
+	tcs := []struct {
+		name          string
+		grammar       string
+		start         int
+		end           int
+		expectedStart int
+		expectedEnd   int
+	}{
+		{name: "alone on its line", grammar: "a\n  b \nc", start: 4, end: 5, expectedStart: 2, expectedEnd: 7},
+		{name: "alone on its line with CRLF", grammar: "a\r\n\tb\r\nc", start: 4, end: 5, expectedStart: 3, expectedEnd: 7},
+		{name: "alone on the last line", grammar: "a\n b ", start: 3, end: 4, expectedStart: 2, expectedEnd: 5},
+		{name: "followed on its line", grammar: "a\n b  c", start: 3, end: 4, expectedStart: 3, expectedEnd: 6},
+		{name: "preceded on its line", grammar: "a  b\nc", start: 3, end: 4, expectedStart: 1, expectedEnd: 4},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			start, end := source.LineExtent(tc.grammar, tc.start, tc.end)
+			if start != tc.expectedStart || end != tc.expectedEnd {
+				t.Errorf("Expected extent %d to %d but got %d to %d.", tc.expectedStart, tc.expectedEnd, start, end)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package source
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// BOM is the UTF-8 byte order mark, which is skipped if it appears at the start of a grammar.
+const BOM = "\uFEFF"
+
+// Position is a location within the source of a grammar.
+//
+// Filename is the name of the file containing the grammar, if it was parsed from a file. Offset is a byte offset
+// (starting at 0), Line and Column start at 1, with Column counted in runes.
+type Position struct {
+	Filename string `json:"filename,omitempty"`
+	Offset   int    `json:"offset"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+}
+
+// String returns the position in the form "line:column", prefixed by "filename:" if the filename is known.
+func (p Position) String() string {
+	position := strconv.Itoa(p.Line) + ":" + strconv.Itoa(p.Column)
+	if p.Filename == "" {
+		return position
+	}
+
+	return p.Filename + ":" + position
+}
+
+// Span is a range within the source of a grammar, from Start (inclusive) to End (exclusive).
+type Span struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+// IsZero returns true if the span has not been set.
+func (s Span) IsZero() bool {
+	return s == Span{}
+}
+
+// File maps byte offsets within the source of a grammar to positions.
+//
+// Lines are terminated by "\n", so CRLF line endings are handled (the "\r" is the last character of the line) and a
+// leading BOM is not counted as a column of the first line.
+type File struct {
+	name    string
+	content string
+	// lines holds the offset of the start of each line.
+	lines []int
+	// runes holds the offset of the start of each rune if the source is not entirely ASCII (otherwise it is nil and
+	// columns are found from byte offsets), so that columns are found without counting the runes of the line.
+	runes []int
+}
+
+// NewFile instantiates a File for the given source.
+func NewFile(content string) *File {
+	return NewNamedFile("", content)
+}
+
+// NewNamedFile instantiates a File for the given source, read from the file with the given name, which is recorded in
+// every Position of the File.
+func NewNamedFile(name, content string) *File {
+	start := 0
+	if strings.HasPrefix(content, BOM) {
+		start = len(BOM)
+	}
+	lines := []int{start}
+	ascii := true
+	for i := start; i < len(content); i++ {
+		if content[i] == '\n' {
+			lines = append(lines, i+1)
+		}
+		ascii = ascii && content[i] < utf8.RuneSelf
+	}
+	var runes []int
+	if !ascii {
+		runes = make([]int, 0, utf8.RuneCountInString(content[start:]))
+		for i := start; i < len(content); {
+			runes = append(runes, i)
+			_, width := utf8.DecodeRuneInString(content[i:])
+			i += width
+		}
+	}
+
+	return &File{name: name, content: content, lines: lines, runes: runes}
+}
+
+// Name returns the name of the file the source was read from, which is empty if it was not read from a file.
+func (f *File) Name() string {
+	return f.name
+}
+
+// Position returns the position of the given byte offset.
+//
+// Offsets outside the source are clamped to its start or end.
+func (f *File) Position(offset int) Position {
+	offset = max(min(offset, len(f.content)), 0)
+	line := max(sort.Search(len(f.lines), func(i int) bool { return f.lines[i] > offset })-1, 0)
+	column := 1 + max(offset-f.lines[line], 0)
+	if f.runes != nil {
+		column = 1 + sort.SearchInts(f.runes, offset) - sort.SearchInts(f.runes, f.lines[line])
+	}
+
+	return Position{Filename: f.name, Offset: offset, Line: line + 1, Column: column}
+}
+
+// Span returns the span between the given byte offsets.
+func (f *File) Span(start, end int) Span {
+	return Span{Start: f.Position(start), End: f.Position(end)}
+}
+
+// Line returns the content of the given line (starting at 1), without its line ending (or a leading BOM).
+//
+// An empty string is returned for lines outside the source.
+func (f *File) Line(line int) string {
+	if line < 1 || line > len(f.lines) {
+		return ""
+	}
+	end := len(f.content)
+	if line < len(f.lines) {
+		end = f.lines[line] - 1
+	}
+
+	return strings.TrimSuffix(f.content[f.lines[line-1]:end], "\r")
+}
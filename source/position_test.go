@@ -0,0 +1,136 @@
+package source_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/alec-w/ebnf-go/source"
+)
+
+func TestFilePosition(t *testing.T) {
+	t.Parallel()
+	content := "\uFEFFab\r\ncé\nd"
+	tcs := []struct {
+		name     string
+		offset   int
+		expected source.Position
+	}{
+		{name: "start of first line after BOM", offset: 3, expected: source.Position{Offset: 3, Line: 1, Column: 1}},
+		{name: "carriage return", offset: 5, expected: source.Position{Offset: 5, Line: 1, Column: 3}},
+		{name: "start of line after CRLF", offset: 7, expected: source.Position{Offset: 7, Line: 2, Column: 1}},
+		{name: "after multibyte rune", offset: 10, expected: source.Position{Offset: 10, Line: 2, Column: 3}},
+		{name: "last line", offset: 11, expected: source.Position{Offset: 11, Line: 3, Column: 1}},
+		{name: "end of content", offset: 12, expected: source.Position{Offset: 12, Line: 3, Column: 2}},
+		{name: "beyond end of content", offset: 20, expected: source.Position{Offset: 12, Line: 3, Column: 2}},
+	}
+	file := source.NewFile(content)
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			if actual := file.Position(tc.offset); actual != tc.expected {
+				t.Errorf("Expected position %#v but got %#v.", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestFileLine(t *testing.T) {
+	t.Parallel()
+	content := "\uFEFFab\r\ncé\nd"
+	tcs := []struct {
+		name     string
+		line     int
+		expected string
+	}{
+		{name: "first line after BOM without CRLF", line: 1, expected: "ab"},
+		{name: "line with multibyte rune", line: 2, expected: "cé"},
+		{name: "last line", line: 3, expected: "d"},
+		{name: "before first line", line: 0, expected: ""},
+		{name: "beyond last line", line: 4, expected: ""},
+	}
+	file := source.NewFile(content)
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			if actual := file.Line(tc.line); actual != tc.expected {
+				t.Errorf("Expected line %q but got %q.", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestNamedFilePosition(t *testing.T) {
+	t.Parallel()
+	file := source.NewNamedFile("grammar.ebnf", "a\nb")
+	expected := source.Position{Filename: "grammar.ebnf", Offset: 2, Line: 2, Column: 1}
+	if actual := file.Position(2); actual != expected {
+		t.Errorf("Expected position %#v but got %#v.", expected, actual)
+	}
+	if actual := file.Name(); actual != "grammar.ebnf" {
+		t.Errorf("Expected name %q but got %q.", "grammar.ebnf", actual)
+	}
+}
+
+func TestPositionString(t *testing.T) {
+	t.Parallel()
+	tcs := []struct {
+		name     string
+		position source.Position
+		expected string
+	}{
+		{name: "without filename", position: source.Position{Offset: 4, Line: 2, Column: 3}, expected: "2:3"},
+		{
+			name:     "with filename",
+			position: source.Position{Filename: "dir/grammar.ebnf", Offset: 4, Line: 2, Column: 3},
+			expected: "dir/grammar.ebnf:2:3",
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			if actual := tc.position.String(); actual != tc.expected {
+				t.Errorf("Expected %q but got %q.", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestFilePositionASCII(t *testing.T) {
+	t.Parallel()
+	content := "\uFEFFab\r\ncd"
+	tcs := []struct {
+		name     string
+		offset   int
+		expected source.Position
+	}{
+		{name: "within BOM", offset: 1, expected: source.Position{Offset: 1, Line: 1, Column: 1}},
+		{name: "second column", offset: 4, expected: source.Position{Offset: 4, Line: 1, Column: 2}},
+		{name: "start of line after CRLF", offset: 7, expected: source.Position{Offset: 7, Line: 2, Column: 1}},
+		{name: "end of content", offset: 9, expected: source.Position{Offset: 9, Line: 2, Column: 3}},
+	}
+	file := source.NewFile(content)
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			if actual := file.Position(tc.offset); actual != tc.expected {
+				t.Errorf("Expected position %#v but got %#v.", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func BenchmarkFilePositionLongLine(b *testing.B) {
+	for _, tc := range []struct{ name, content string }{
+		{name: "ASCII", content: strings.Repeat("b, ", 80000)},
+		{name: "non-ASCII", content: strings.Repeat("é, ", 80000)},
+	} {
+		b.Run(tc.name, func(b *testing.B) {
+			file := source.NewFile(tc.content)
+			for b.Loop() {
+				for offset := 0; offset < len(tc.content); offset += 64 {
+					file.Position(offset)
+				}
+			}
+		})
+	}
+}
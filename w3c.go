@@ -0,0 +1,147 @@
+package ebnf
+
+import (
+	"context"
+
+	"github.com/alec-w/ebnf-go/w3c"
+)
+
+// w3cDialect adapts a w3c.Parser to the Dialect interface.
+type w3cDialect struct {
+	parser *w3c.Parser
+}
+
+// W3C returns the dialect of grammars in the W3C notation (as used by the XML specification), parsed by a w3c.Parser
+// configured with the given options.
+func W3C(opts ...w3c.Option) Dialect {
+	return w3cDialect{parser: w3c.New(opts...)}
+}
+
+func (w3cDialect) Name() string {
+	return DialectW3C
+}
+
+func (d w3cDialect) Parse(ctx context.Context, grammar string) (Grammar, error) {
+	syntax, err := d.parser.ParseContext(ctx, grammar)
+
+	return FromW3C(syntax), err
+}
+
+// FromW3C converts the Syntax of a grammar in the W3C notation to a Grammar.
+//
+// Every expression records the span of the expression it was converted from, and rules keep their production numbers
+// and constraints. The repetitions of an expression (?, * or +) are converted to an Optional, Star or Plus of the
+// expression, one for each repetition symbol in the order they were written (e.g. "(b?)*" is a Star of an Optional),
+// each of which records the span of the expression up to and including its repetition symbol. A reference to a
+// production in another specification is converted to a Reference with its URI, and the single characters and ranges
+// of a character set to the Chars and Ranges of a CharClass.
+//
+// Parentheses are not kept, apart from within the spans of the expressions they enclose, and nor is the line of each
+// rule (which is that of the start of its span).
+func FromW3C(syntax w3c.Syntax) Grammar {
+	grammar := Grammar{
+		Dialect:          DialectW3C,
+		Rules:            make([]Rule, 0, len(syntax.Rules)),
+		TrailingComments: syntax.TrailingComments,
+		Syntax:           syntax,
+	}
+	for _, rule := range syntax.Rules {
+		converted := Rule{
+			Number:     rule.Number,
+			Name:       rule.Symbol,
+			Comments:   rule.Comments,
+			Expression: fromW3CExpression(rule.Expression),
+			Span:       rule.Span,
+		}
+		for _, constraint := range rule.Constraints {
+			converted.Constraints = append(converted.Constraints, Constraint{
+				Kind: string(constraint.Kind),
+				Name: constraint.Name,
+				Span: constraint.Span,
+			})
+		}
+		grammar.Rules = append(grammar.Rules, converted)
+	}
+
+	return grammar
+}
+
+func fromW3CExpression(expression w3c.Expression) Expression {
+	repeats := expression.Repeats()
+	if len(repeats) == 0 {
+		repeats = flaggedW3CRepeats(expression)
+	}
+	// The expression itself spans the operand of its innermost repetition.
+	node := Node{Source: expression.Span()}
+	if len(repeats) > 0 {
+		node.Source = repeats[0].Operand
+	}
+	var converted Expression
+	switch expr := expression.(type) {
+	case *w3c.ListExpression:
+		converted = &Sequence{Node: node, Items: fromW3CExpressions(expr.Expressions)}
+	case *w3c.AlternateExpression:
+		converted = &Choice{Node: node, Alternatives: fromW3CExpressions(expr.Expressions)}
+	case *w3c.ExceptionExpression:
+		converted = &Exception{
+			Node:   node,
+			Match:  fromW3CExpression(expr.Match),
+			Except: fromW3CExpression(expr.Except),
+		}
+	case *w3c.SymbolExpression:
+		converted = &Reference{Node: node, Name: expr.Symbol}
+	case *w3c.ExternalReferenceExpression:
+		converted = &Reference{Node: node, URI: expr.URI}
+	case *w3c.LiteralExpression:
+		converted = &Literal{Node: node, Text: expr.Literal}
+	case *w3c.CharacterSetExpression:
+		charClass := &CharClass{Node: node, Chars: expr.Enumerations, Negated: expr.Forbidden}
+		for _, r := range expr.Ranges {
+			charClass.Ranges = append(charClass.Ranges, CharRange{Low: r.Low, High: r.High})
+		}
+		converted = charClass
+	}
+	for _, repeat := range repeats {
+		node := Node{Source: repeat.Span}
+		switch repeat.Symbol {
+		case '?':
+			converted = &Optional{Node: node, Expression: converted}
+		case '*':
+			converted = &Star{Node: node, Expression: converted}
+		case '+':
+			converted = &Plus{Node: node, Expression: converted}
+		}
+	}
+
+	return converted
+}
+
+// flaggedW3CRepeats returns the repetitions of an expression that was not parsed (so does not record the order of its
+// repetitions) from its flags, each spanning the whole expression.
+func flaggedW3CRepeats(expression w3c.Expression) []w3c.Repetition {
+	var repeats []w3c.Repetition
+	for _, flag := range []struct {
+		set    bool
+		symbol rune
+	}{
+		{set: expression.Optional(), symbol: '?'},
+		{set: expression.ZeroOrMore(), symbol: '*'},
+		{set: expression.OneOrMore(), symbol: '+'},
+	} {
+		if flag.set {
+			span := expression.Span()
+			repeats = append(repeats, w3c.Repetition{Symbol: flag.symbol, Operand: span, Span: span})
+		}
+	}
+
+	return repeats
+}
+
+func fromW3CExpressions(expressions []w3c.Expression) []Expression {
+	converted := make([]Expression, 0, len(expressions))
+	for _, expression := range expressions {
+		converted = append(converted, fromW3CExpression(expression))
+	}
+
+	return converted
+}
@@ -0,0 +1,182 @@
+package w3c
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/alec-w/ebnf-go/cst"
+	"github.com/alec-w/ebnf-go/source"
+)
+
+// The kinds of the nodes of the concrete syntax tree of a W3C grammar, named after the syntax they represent.
+const (
+	KindSyntax                      cst.Kind = "syntax"
+	KindRule                        cst.Kind = "rule"
+	KindListExpression              cst.Kind = "list-expression"
+	KindAlternateExpression         cst.Kind = "alternate-expression"
+	KindExceptionExpression         cst.Kind = "exception-expression"
+	KindSymbolExpression            cst.Kind = "symbol-expression"
+	KindCharacterSetExpression      cst.Kind = "character-set-expression"
+	KindLiteralExpression           cst.Kind = "literal-expression"
+	KindExternalReferenceExpression cst.Kind = "external-reference-expression"
+	KindProductionNumber            cst.Kind = "production-number"
+	KindSymbol                      cst.Kind = "symbol"
+	KindDefiningSymbol              cst.Kind = "defining-symbol"
+	// KindLiteral is the kind of the token of a literal, including its quotes.
+	KindLiteral cst.Kind = "literal"
+	// KindCharacterSet is the kind of the token of a character set wrapped in brackets.
+	KindCharacterSet cst.Kind = "character-set"
+	// KindHexCharacter is the kind of the token of a hex character (#xN) outside a character set.
+	KindHexCharacter      cst.Kind = "hex-character"
+	KindExternalReference cst.Kind = "external-reference"
+	// KindConstraint is the kind of the token of a well-formedness or validity constraint annotation, including its
+	// brackets.
+	KindConstraint       cst.Kind = "constraint"
+	KindAlternateSymbol  cst.Kind = "alternate-symbol"
+	KindExceptSymbol     cst.Kind = "except-symbol"
+	KindRepetitionSymbol cst.Kind = "repetition-symbol"
+	KindOpenParenthesis  cst.Kind = "open-parenthesis"
+	KindCloseParenthesis cst.Kind = "close-parenthesis"
+)
+
+// concreteSyntaxTree builds the concrete syntax tree of the grammar from the rules of its syntax, which may be partial
+// if the parser recovered from errors, in which case the text of the rules that could not be parsed is kept as tokens
+// of the syntax.
+func (p *parser) concreteSyntaxTree(syntax Syntax) *cst.Node {
+	root := &cst.Node{Kind: KindSyntax, Span: p.file.Span(0, len(p.source))}
+	var branches []*cst.Node
+	for _, rule := range syntax.Rules {
+		branches = append(branches, &cst.Node{Kind: KindRule, Span: rule.Span})
+		branches = appendExpression(branches, rule.Expression)
+	}
+
+	return cst.Build(root, branches, p.tokens())
+}
+
+// appendExpression appends the branches of the expression, and of the expressions within it, in order.
+func appendExpression(branches []*cst.Node, expression Expression) []*cst.Node {
+	var kind cst.Kind
+	var children []Expression
+	switch {
+	case expression.ListExpression() != nil:
+		kind, children = KindListExpression, expression.ListExpression().Expressions
+	case expression.AlternateExpression() != nil:
+		kind, children = KindAlternateExpression, expression.AlternateExpression().Expressions
+	case expression.ExceptionExpression() != nil:
+		exception := expression.ExceptionExpression()
+		kind, children = KindExceptionExpression, []Expression{exception.Match, exception.Except}
+	case expression.SymbolExpression() != nil:
+		kind = KindSymbolExpression
+	case expression.CharacterSetExpression() != nil:
+		kind = KindCharacterSetExpression
+	case expression.LiteralExpression() != nil:
+		kind = KindLiteralExpression
+	case expression.ExternalReferenceExpression() != nil:
+		kind = KindExternalReferenceExpression
+	}
+	branches = append(branches, &cst.Node{Kind: kind, Span: expression.Span()})
+	for _, child := range children {
+		branches = appendExpression(branches, child)
+	}
+
+	return branches
+}
+
+// tokens splits the whole grammar into tokens, each of which spans the text that would be parsed as it. Text that is
+// not a token (e.g. an unterminated literal) is split into tokens of each character, of the kind cst.KindError.
+func (p *parser) tokens() []*cst.Node {
+	var tokens []*cst.Node
+	offset := 0
+	if strings.HasPrefix(p.source, source.BOM) {
+		offset = len(source.BOM)
+		tokens = append(tokens, &cst.Node{Kind: cst.KindByteOrderMark, Text: source.BOM, Span: p.file.Span(0, offset)})
+	}
+	for offset < len(p.source) {
+		kind, end := p.token(offset)
+		tokens = append(tokens, &cst.Node{Kind: kind, Text: p.source[offset:end], Span: p.file.Span(offset, end)})
+		offset = end
+	}
+
+	return tokens
+}
+
+// token returns the kind and end of the token at the given offset, which is found by parsing it as the parser would.
+func (p *parser) token(offset int) (cst.Kind, int) {
+	// The token is parsed by a parser of its own, without limits, so that it is not affected by the state of the parse.
+	probe := &parser{source: p.source, offset: offset, file: p.file}
+	rest := p.source[offset:]
+	char, width := probe.next()
+	switch {
+	case unicode.IsSpace(char):
+		end := strings.IndexFunc(rest, func(char rune) bool { return !unicode.IsSpace(char) })
+		if end < 0 {
+			end = len(rest)
+		}
+
+		return cst.KindWhitespace, offset + end
+	case probe.isCommentStart():
+		// An unterminated comment swallows the rest of the grammar.
+		end := strings.Index(rest[len("/*"):], "*/")
+		if end < 0 {
+			return cst.KindError, len(p.source)
+		}
+
+		return cst.KindComment, offset + len("/*") + end + len("*/")
+	case char == '[':
+		return probe.bracketToken()
+	case char == '#':
+		if _, err := probe.parseHexCharacter(false); err == nil {
+			return KindHexCharacter, probe.offset
+		}
+	case char == '\'' || char == '"':
+		if _, err := probe.parseLiteralExpression(); err == nil {
+			return KindLiteral, probe.offset
+		}
+	case probe.isBasicLatinLetter(char):
+		probe.parseSymbol()
+
+		return KindSymbol, probe.offset
+	case strings.HasPrefix(rest, "::="):
+		return KindDefiningSymbol, offset + len("::=")
+	case char == '|':
+		return KindAlternateSymbol, offset + width
+	case char == '-':
+		return KindExceptSymbol, offset + width
+	case char == '?' || char == '*' || char == '+':
+		return KindRepetitionSymbol, offset + width
+	case char == '(':
+		return KindOpenParenthesis, offset + width
+	case char == ')':
+		return KindCloseParenthesis, offset + width
+	}
+
+	return cst.KindError, offset + width
+}
+
+// bracketToken returns the kind and end of the token starting with "[" at the current offset, which is a production
+// number if it starts a rule, otherwise a constraint, an external reference or a character set.
+func (p *parser) bracketToken() (cst.Kind, int) {
+	offset := p.offset
+	if p.isRuleEnd() {
+		p.parseProductionNumber()
+
+		return KindProductionNumber, p.offset
+	}
+	var err error
+	kind := KindCharacterSet
+	switch {
+	case p.isConstraintStart():
+		kind = KindConstraint
+		_, err = p.parseConstraint()
+	case p.isExternalReferenceStart():
+		kind = KindExternalReference
+		_, err = p.parseExternalReferenceExpression()
+	default:
+		_, err = p.parseCharacterSetExpression()
+	}
+	if err != nil {
+		return cst.KindError, offset + len("[")
+	}
+
+	return kind, p.offset
+}
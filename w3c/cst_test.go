@@ -0,0 +1,171 @@
+package w3c_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alec-w/ebnf-go/cst"
+	"github.com/alec-w/ebnf-go/w3c"
+)
+
+func TestParserParseCST(t *testing.T) {
+	t.Parallel()
+	testCases := map[string]struct {
+		grammar  string
+		opts     []w3c.Option
+		expected string
+	}{
+		"rule": {
+			grammar: "[1] a ::= 'b' /* c */ [ wfc: D ]\n",
+			expected: `(syntax (rule production-number:"[1]" whitespace:" " symbol:"a" whitespace:" " ` +
+				`defining-symbol:"::=" whitespace:" " (literal-expression literal:"'b'") whitespace:" " ` +
+				`comment:"/* c */" whitespace:" " constraint:"[ wfc: D ]") whitespace:"\n")`,
+		},
+		"expressions": {
+			grammar: "a ::= (b | #x20)* - [^c] [http://d/#e]+ | f?",
+			expected: `(syntax (rule symbol:"a" whitespace:" " defining-symbol:"::=" whitespace:" " ` +
+				`(alternate-expression (list-expression (exception-expression (alternate-expression ` +
+				`open-parenthesis:"(" (symbol-expression symbol:"b") whitespace:" " alternate-symbol:"|" ` +
+				`whitespace:" " (character-set-expression hex-character:"#x20") close-parenthesis:")" ` +
+				`repetition-symbol:"*") whitespace:" " except-symbol:"-" whitespace:" " ` +
+				`(character-set-expression character-set:"[^c]")) whitespace:" " ` +
+				`(external-reference-expression external-reference:"[http://d/#e]" repetition-symbol:"+")) ` +
+				`whitespace:" " alternate-symbol:"|" whitespace:" " (symbol-expression symbol:"f" ` +
+				`repetition-symbol:"?"))))`,
+		},
+		"constraint within an expression": {
+			grammar: "a ::= (b [vc: C] c)+",
+			expected: `(syntax (rule symbol:"a" whitespace:" " defining-symbol:"::=" whitespace:" " ` +
+				`(list-expression open-parenthesis:"(" (symbol-expression symbol:"b") whitespace:" " ` +
+				`constraint:"[vc: C]" whitespace:" " (symbol-expression symbol:"c") close-parenthesis:")" ` +
+				`repetition-symbol:"+")))`,
+		},
+		"comments between rules": {
+			grammar: "/* a */\na ::= b /* b */\n/* c */\nc ::= d\n/* d */",
+			expected: `(syntax comment:"/* a */" whitespace:"\n" (rule symbol:"a" whitespace:" " ` +
+				`defining-symbol:"::=" whitespace:" " (symbol-expression symbol:"b")) whitespace:" " ` +
+				`comment:"/* b */" whitespace:"\n" comment:"/* c */" whitespace:"\n" (rule symbol:"c" ` +
+				`whitespace:" " defining-symbol:"::=" whitespace:" " (symbol-expression symbol:"d")) ` +
+				`whitespace:"\n" comment:"/* d */")`,
+		},
+		"byte order mark": {
+			grammar: "\uFEFFa::=b\r\n",
+			expected: `(syntax byte-order-mark:"\ufeff" (rule symbol:"a" defining-symbol:"::=" ` +
+				`(symbol-expression symbol:"b")) whitespace:"\r\n")`,
+		},
+		"recovery": {
+			grammar: "a ::= 'b\nc ::= d",
+			opts:    []w3c.Option{w3c.WithRecovery()},
+			expected: `(syntax symbol:"a" whitespace:" " defining-symbol:"::=" whitespace:" " error:"'" ` +
+				`symbol:"b" whitespace:"\n" (rule symbol:"c" whitespace:" " defining-symbol:"::=" whitespace:" " ` +
+				`(symbol-expression symbol:"d")))`,
+		},
+		"recovery from an unterminated comment": {
+			grammar: "a ::= b /* c",
+			opts:    []w3c.Option{w3c.WithRecovery()},
+			expected: `(syntax (rule symbol:"a" whitespace:" " defining-symbol:"::=" whitespace:" " ` +
+				`(symbol-expression symbol:"b")) whitespace:" " error:"/* c")`,
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			syntax, _ := w3c.New(append(tc.opts, w3c.WithCST())...).Parse(tc.grammar)
+			if syntax.CST == nil {
+				t.Fatalf("Expected a concrete syntax tree.")
+			}
+			if actual := formatTree(syntax.CST); actual != tc.expected {
+				t.Errorf("Expected tree:\n%s\nGot:\n%s", tc.expected, actual)
+			}
+			if actual := syntax.CST.String(); actual != tc.grammar {
+				t.Errorf("Expected tree to reproduce %q. Got %q.", tc.grammar, actual)
+			}
+		})
+	}
+}
+
+func TestParserParseCSTNotBuilt(t *testing.T) {
+	t.Parallel()
+	syntax, err := w3c.New().Parse("a ::= b")
+	if err != nil {
+		t.Fatalf("Expected no error but got %s.", err)
+	}
+	if syntax.CST != nil {
+		t.Errorf("Expected no concrete syntax tree without WithCST.")
+	}
+	syntax, err = w3c.New(w3c.WithCST()).Parse("a ::= (b")
+	if err == nil || syntax.CST != nil {
+		t.Errorf("Expected an error and no concrete syntax tree. Got %v and %v.", err, syntax.CST)
+	}
+}
+
+func TestParserParseCSTCorpus(t *testing.T) {
+	t.Parallel()
+	grammars, err := filepath.Glob(filepath.Join("testdata", "corpus", "*.ebnf"))
+	if err != nil {
+		t.Fatalf("Expected no error finding corpus grammars but got %s.", err)
+	}
+	for _, grammar := range grammars {
+		t.Run(filepath.Base(grammar), func(t *testing.T) {
+			t.Parallel()
+			content, err := os.ReadFile(grammar)
+			if err != nil {
+				t.Fatalf("Expected no error reading grammar but got %s.", err)
+			}
+			syntax, err := w3c.New(w3c.WithCST()).Parse(string(content))
+			if err != nil {
+				t.Fatalf("Expected no error but got %s.", err)
+			}
+			if syntax.CST.String() != string(content) {
+				t.Errorf("Expected tree to reproduce the grammar.")
+			}
+			rules := 0
+			for _, child := range syntax.CST.Children {
+				if child.Kind == w3c.KindRule {
+					rules++
+				}
+				if child.Kind == cst.KindError {
+					t.Errorf("Expected no errors in the tree. Got %q at %s.", child.Text, child.Span.Start)
+				}
+			}
+			if rules != len(syntax.Rules) {
+				t.Errorf("Expected %d rules in the tree. Got %d.", len(syntax.Rules), rules)
+			}
+		})
+	}
+}
+
+func FuzzParserParseCST(f *testing.F) {
+	for _, seed := range []string{
+		"testRule ::= 'word'",
+		"/* a */ [1] testRule ::= ('one' | #x20)* - [^a-z] [http://a/#b] /* b */ [ wfc: C ]",
+		"testRule ::= '\nb ::= c /* d",
+	} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, grammar string) {
+		syntax, _ := w3c.New(w3c.WithCST(), w3c.WithRecovery()).Parse(grammar)
+		if syntax.CST == nil {
+			return
+		}
+		if actual := syntax.CST.String(); actual != grammar {
+			t.Errorf("Expected tree to reproduce %q. Got %q.", grammar, actual)
+		}
+	})
+}
+
+// formatTree returns the tree as an s-expression, with each token as its kind and quoted text.
+func formatTree(node *cst.Node) string {
+	if node.IsToken() {
+		return fmt.Sprintf("%s:%q", node.Kind, node.Text)
+	}
+	parts := []string{string(node.Kind)}
+	for _, child := range node.Children {
+		parts = append(parts, formatTree(child))
+	}
+
+	return "(" + strings.Join(parts, " ") + ")"
+}
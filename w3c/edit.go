@@ -0,0 +1,182 @@
+package w3c
+
+import (
+	"github.com/alec-w/ebnf-go/source"
+)
+
+// Editor records changes to a grammar, made through the Syntax it was parsed to, as edits of its source (see
+// source.Edit) that leave the rest of the source, with its layout and comments, as it was.
+//
+// Every change is made to the grammar as it was parsed, so rules are found by the symbols they were parsed with, and a
+// change within a rule that has been deleted is dropped.
+type Editor struct {
+	grammar string
+	syntax  Syntax
+	edits   []source.Edit
+}
+
+// NewEditor instantiates an Editor of the grammar, which must be the grammar the syntax was parsed from.
+func NewEditor(grammar string, syntax Syntax) *Editor {
+	return &Editor{grammar: grammar, syntax: syntax}
+}
+
+// Edits returns the edits of the grammar, ordered by offset, which can be made to it with source.Apply.
+func (e *Editor) Edits() []source.Edit {
+	return append([]source.Edit(nil), e.edits...)
+}
+
+// RenameRule changes the symbol of the rules with the given symbol, and of every reference to them, to the new symbol.
+// It returns an EditError if another rule already has the new symbol.
+func (e *Editor) RenameRule(symbol, newSymbol string) error {
+	if !e.isSymbol(newSymbol) {
+		return NewEditError(newSymbol+" is not a valid symbol", symbol, nil)
+	}
+	rules := e.rules(symbol)
+	if len(rules) == 0 {
+		return e.unknownRuleError(symbol)
+	}
+	if newSymbol != symbol && len(e.rules(newSymbol)) > 0 {
+		return NewEditError(newSymbol+" is already defined", symbol, nil)
+	}
+	edits := make([]source.Edit, 0, len(rules))
+	for _, rule := range rules {
+		// The rule's span starts at its production number, if it has one.
+		probe := &parser{source: e.grammar, offset: rule.Span.Start.Offset}
+		if _, ok := probe.parseProductionNumber(); ok {
+			probe.skipWhitespace()
+		}
+		edits = append(edits, source.Edit{Start: probe.offset, End: probe.offset + len(symbol), Text: newSymbol})
+	}
+	for _, rule := range e.syntax.Rules {
+		forEachSymbol(rule.Expression, func(expression *SymbolExpression) {
+			if expression.Symbol != symbol {
+				return
+			}
+			// The expression's span includes any parentheses around the symbol and repetitions following it.
+			probe := &parser{source: e.grammar, offset: expression.Span().Start.Offset}
+			for probe.skipWhitespace(); probe.source[probe.offset] == '('; probe.skipWhitespace() {
+				probe.offset++
+			}
+			edits = append(edits, source.Edit{Start: probe.offset, End: probe.offset + len(symbol), Text: newSymbol})
+		})
+	}
+
+	return e.merge(symbol, edits...)
+}
+
+// AddAlternative adds an expression as an alternative to the expression of the first rule with the given symbol,
+// after it.
+//
+// If the expression of the rule ends with an exception of a parenthesised expression, which would take in the
+// alternative, the exception is parenthesised.
+func (e *Editor) AddAlternative(symbol, expression string) error {
+	rules := e.rules(symbol)
+	if len(rules) == 0 {
+		return e.unknownRuleError(symbol)
+	}
+	// The expression must parse as the expression of a rule of its own.
+	syntax, err := New().Parse("alternative ::= " + expression)
+	if err != nil || len(syntax.Rules) != 1 {
+		return NewEditError(expression+" is not a valid expression", symbol, err)
+	}
+	span := rules[0].Expression.Span()
+	if exception := e.trailingException(rules[0].Expression); exception != nil {
+		return e.merge(
+			symbol,
+			source.Edit{Start: exception.Span().Start.Offset, End: exception.Span().Start.Offset, Text: "("},
+			source.Edit{Start: span.End.Offset, End: span.End.Offset, Text: ") | " + expression},
+		)
+	}
+
+	return e.merge(symbol, source.Edit{Start: span.End.Offset, End: span.End.Offset, Text: " | " + expression})
+}
+
+// DeleteRule deletes the rules with the given symbol, along with the whitespace around each up to the end of its line,
+// and the whitespace before it on its line if nothing else is.
+//
+// Comments on the rules are kept, as are references to them from other rules.
+func (e *Editor) DeleteRule(symbol string) error {
+	rules := e.rules(symbol)
+	if len(rules) == 0 {
+		return e.unknownRuleError(symbol)
+	}
+	edits := make([]source.Edit, 0, len(rules))
+	for _, rule := range rules {
+		start, end := source.LineExtent(e.grammar, rule.Span.Start.Offset, rule.Span.End.Offset)
+		edits = append(edits, source.Edit{Start: start, End: end})
+	}
+
+	return e.merge(symbol, edits...)
+}
+
+// rules returns the rules with the given symbol.
+func (e *Editor) rules(symbol string) []Rule {
+	var rules []Rule
+	for _, rule := range e.syntax.Rules {
+		if rule.Symbol == symbol {
+			rules = append(rules, rule)
+		}
+	}
+
+	return rules
+}
+
+// trailingException returns the exception, if there is one, at the end of the expression (outside of any parentheses)
+// of a parenthesised expression, which takes in everything following it up to the end of the rule.
+//
+// The expressions of a parenthesised list are joined with those of the list around it, so an exception ending the list
+// is only at the end of the expression if it ends where the expression does.
+func (e *Editor) trailingException(expression Expression) *ExceptionExpression {
+	end := expression.Span().End.Offset
+	for expression != nil && !expression.isParenthesised() {
+		switch {
+		case expression.ListExpression() != nil:
+			expressions := expression.ListExpression().Expressions
+			expression = expressions[len(expressions)-1]
+		case expression.AlternateExpression() != nil:
+			expressions := expression.AlternateExpression().Expressions
+			expression = expressions[len(expressions)-1]
+		case expression.ExceptionExpression() != nil:
+			exception := expression.ExceptionExpression()
+			if e.grammar[exception.Except.Span().Start.Offset] == '(' && exception.Span().End.Offset == end {
+				return exception
+			}
+
+			return nil
+		default:
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// merge merges the edits of a change to the rule with the given symbol into the edits of the grammar, which are left
+// unchanged if any of them cannot be merged.
+func (e *Editor) merge(symbol string, edits ...source.Edit) error {
+	merged := e.edits
+	for _, edit := range edits {
+		var err error
+		if merged, err = source.MergeEdit(merged, edit); err != nil {
+			return NewEditError(err.Error(), symbol, err)
+		}
+	}
+	e.edits = merged
+
+	return nil
+}
+
+func (e *Editor) unknownRuleError(symbol string) *EditError {
+	return NewEditError("there is no rule with this symbol", symbol, nil)
+}
+
+// isSymbol returns true if the text is a symbol, a basic latin letter followed by basic latin letters, digits and
+// underscores.
+func (e *Editor) isSymbol(text string) bool {
+	probe := &parser{source: text}
+	if char, _ := probe.next(); !probe.isBasicLatinLetter(char) {
+		return false
+	}
+
+	return probe.parseSymbol() == text
+}
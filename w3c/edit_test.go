@@ -0,0 +1,154 @@
+package w3c_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/alec-w/ebnf-go/source"
+	"github.com/alec-w/ebnf-go/w3c"
+)
+
+func TestEditor(t *testing.T) {
+	t.Parallel()
+	grammar := "/* A list. */\n[1] list ::= item (',' item)*\n\n  item ::= letter | (digit)+ /* An item. */\n" +
+		"letter ::= [a-z]\n[2a] digit ::= [0-9] - ('5')\n"
+	tcs := []struct {
+		name     string
+		edit     func(*w3c.Editor) error
+		expected string
+		err      bool
+	}{
+		{
+			name: "rename a rule",
+			edit: func(e *w3c.Editor) error { return e.RenameRule("item", "element") },
+			expected: "/* A list. */\n[1] list ::= element (',' element)*\n\n  element ::= letter | (digit)+ /* An item. */\n" +
+				"letter ::= [a-z]\n[2a] digit ::= [0-9] - ('5')\n",
+		},
+		{
+			name: "rename a rule with a production number",
+			edit: func(e *w3c.Editor) error { return e.RenameRule("digit", "Digit") },
+			expected: "/* A list. */\n[1] list ::= item (',' item)*\n\n  item ::= letter | (Digit)+ /* An item. */\n" +
+				"letter ::= [a-z]\n[2a] Digit ::= [0-9] - ('5')\n",
+		},
+		{
+			name:     "rename a rule to its own symbol",
+			edit:     func(e *w3c.Editor) error { return e.RenameRule("letter", "letter") },
+			expected: grammar,
+		},
+		{
+			name: "add an alternative",
+			edit: func(e *w3c.Editor) error { return e.AddAlternative("letter", "[A-Z] | '_'") },
+			expected: "/* A list. */\n[1] list ::= item (',' item)*\n\n  item ::= letter | (digit)+ /* An item. */\n" +
+				"letter ::= [a-z] | [A-Z] | '_'\n[2a] digit ::= [0-9] - ('5')\n",
+		},
+		{
+			name: "add an alternative to a rule ending with an exception",
+			edit: func(e *w3c.Editor) error { return e.AddAlternative("digit", "'x'") },
+			expected: "/* A list. */\n[1] list ::= item (',' item)*\n\n  item ::= letter | (digit)+ /* An item. */\n" +
+				"letter ::= [a-z]\n[2a] digit ::= ([0-9] - ('5')) | 'x'\n",
+		},
+		{
+			name: "delete a rule alone on its line",
+			edit: func(e *w3c.Editor) error { return e.DeleteRule("digit") },
+			expected: "/* A list. */\n[1] list ::= item (',' item)*\n\n  item ::= letter | (digit)+ /* An item. */\n" +
+				"letter ::= [a-z]\n",
+		},
+		{
+			name: "delete a rule followed by a comment",
+			edit: func(e *w3c.Editor) error { return e.DeleteRule("item") },
+			expected: "/* A list. */\n[1] list ::= item (',' item)*\n\n  /* An item. */\nletter ::= [a-z]\n" +
+				"[2a] digit ::= [0-9] - ('5')\n",
+		},
+		{
+			name: "several edits",
+			edit: func(e *w3c.Editor) error {
+				return errors.Join(
+					e.AddAlternative("item", "list"),
+					e.RenameRule("item", "element"),
+					e.DeleteRule("letter"),
+					e.RenameRule("letter", "char"),
+				)
+			},
+			expected: "/* A list. */\n[1] list ::= element (',' element)*\n\n  element ::= char | (digit)+ | list " +
+				"/* An item. */\n[2a] digit ::= [0-9] - ('5')\n",
+		},
+		{name: "rename an unknown rule", edit: func(e *w3c.Editor) error { return e.RenameRule("x", "y") }, err: true},
+		{
+			name: "rename to an invalid symbol",
+			edit: func(e *w3c.Editor) error { return e.RenameRule("item", "_x") },
+			err:  true,
+		},
+		{
+			name: "rename to a defined symbol",
+			edit: func(e *w3c.Editor) error { return e.RenameRule("letter", "digit") },
+			err:  true,
+		},
+		{
+			name: "add an invalid alternative",
+			edit: func(e *w3c.Editor) error { return e.AddAlternative("item", "'a") },
+			err:  true,
+		},
+		{
+			name: "add several rules as an alternative",
+			edit: func(e *w3c.Editor) error { return e.AddAlternative("item", "a b ::= c") },
+			err:  true,
+		},
+		{name: "delete an unknown rule", edit: func(e *w3c.Editor) error { return e.DeleteRule("x") }, err: true},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			syntax, err := w3c.New().Parse(grammar)
+			if err != nil {
+				t.Fatalf("Expected no error parsing the grammar but got %q.", err)
+			}
+			editor := w3c.NewEditor(grammar, syntax)
+			err = tc.edit(editor)
+			if tc.err {
+				var editErr *w3c.EditError
+				if !errors.As(err, &editErr) {
+					t.Errorf("Expected an edit error but got %v.", err)
+				}
+				if len(editor.Edits()) != 0 {
+					t.Errorf("Expected no edits but got %#v.", editor.Edits())
+				}
+
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error but got %q.", err)
+			}
+			actual, err := source.Apply(grammar, editor.Edits())
+			if err != nil {
+				t.Fatalf("Expected no error applying the edits but got %q.", err)
+			}
+			if actual != tc.expected {
+				t.Errorf("Expected edited grammar %q but got %q.", tc.expected, actual)
+			}
+			if _, err := w3c.New().Parse(actual); err != nil {
+				t.Errorf("Expected the edited grammar to parse but got %q.", err)
+			}
+		})
+	}
+}
+
+func TestEditorAddAlternativeAfterParenthesisedList(t *testing.T) {
+	t.Parallel()
+	// The list within the parentheses is joined with the list around it, so that it ends with the exception.
+	grammar := "a ::= 'x' ('w' 'y' - ('q'))"
+	syntax, err := w3c.New().Parse(grammar)
+	if err != nil {
+		t.Fatalf("Expected no error parsing the grammar but got %q.", err)
+	}
+	editor := w3c.NewEditor(grammar, syntax)
+	if err := editor.AddAlternative("a", "'z'"); err != nil {
+		t.Fatalf("Expected no error but got %q.", err)
+	}
+	actual, err := source.Apply(grammar, editor.Edits())
+	if err != nil {
+		t.Fatalf("Expected no error applying the edits but got %q.", err)
+	}
+	if expected := "a ::= 'x' ('w' 'y' - ('q')) | 'z'"; actual != expected {
+		t.Errorf("Expected edited grammar %q but got %q.", expected, actual)
+	}
+}
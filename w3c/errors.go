@@ -1,13 +1,31 @@
 package w3c
 
-import "fmt"
+import (
+	"fmt"
+
+	"github.com/alec-w/ebnf-go/diag"
+	"github.com/alec-w/ebnf-go/source"
+)
 
 // ParseError is returned if there is an error parsing a grammar.
 type ParseError struct {
-	msg    string
-	Line   int
-	Offset int
-	cause  error
+	// Code identifies the kind of the error, and can be checked with errors.Is (see diag.Code).
+	Code diag.Code
+	msg  string
+	// Filename is the name of the file containing the grammar, if it was parsed from a file.
+	Filename string
+	Line     int
+	Column   int
+	Offset   int
+	// Symbol is the symbol of the rule being parsed when the error occurred, if known.
+	Symbol string
+	// Expected lists the tokens that would have been acceptable where the error occurred and Found is the token that
+	// was found instead, using the descriptions of diag.Literal and diag.FoundToken. They are empty for errors that are
+	// not caused by an unexpected token. Errors for unterminated constructs are reported at the opening delimiter, but
+	// Found describes the token where the closing delimiter was expected.
+	Expected []string
+	Found    string
+	cause    error
 }
 
 // NewParseError instantiates a ParseError.
@@ -17,7 +35,12 @@ func NewParseError(msg string, line, offset int, cause error) *ParseError {
 
 // Error fulfills the error interface.
 func (p *ParseError) Error() string {
-	return fmt.Sprintf("parse error on line %d at total offset %d: %s", p.Line, p.Offset, p.msg)
+	msg := fmt.Sprintf("parse error on line %d at total offset %d: %s", p.Line, p.Offset, p.msg)
+	if p.Filename != "" {
+		return p.Filename + ": " + msg
+	}
+
+	return msg
 }
 
 // Unwrap allows retrieving the original error (if there is one).
@@ -25,6 +48,80 @@ func (p *ParseError) Unwrap() error {
 	return p.cause
 }
 
+// Is allows checking the code of the error with errors.Is.
+func (p *ParseError) Is(target error) bool {
+	code, ok := target.(diag.Code)
+
+	return ok && code == p.Code
+}
+
+// Diagnostic describes the parse error for rendering (see diag.Renderer).
+func (p *ParseError) Diagnostic() diag.Diagnostic {
+	return diag.Diagnostic{
+		Code:     p.Code,
+		Message:  p.msg,
+		Position: source.Position{Filename: p.Filename, Offset: p.Offset, Line: p.Line, Column: p.Column},
+		Rule:     p.Symbol,
+		Expected: p.Expected,
+		Found:    p.Found,
+	}
+}
+
+// MergeError is returned if the syntaxes of a grammar split across several files cannot be merged (see Merge).
+type MergeError struct {
+	// Code identifies the kind of the error, and can be checked with errors.Is (see diag.Code).
+	Code diag.Code
+	msg  string
+	// Symbol is the symbol of the rule the error occurred in.
+	Symbol string
+	// Position is where the error occurred, including the name of the file containing it.
+	Position source.Position
+}
+
+// NewMergeError instantiates a MergeError.
+func NewMergeError(code diag.Code, msg, symbol string, position source.Position) *MergeError {
+	return &MergeError{Code: code, msg: msg, Symbol: symbol, Position: position}
+}
+
+// Error fulfills the error interface.
+func (m *MergeError) Error() string {
+	return fmt.Sprintf("merge error at %s: %s", m.Position, m.msg)
+}
+
+// Is allows checking the code of the error with errors.Is.
+func (m *MergeError) Is(target error) bool {
+	code, ok := target.(diag.Code)
+
+	return ok && code == m.Code
+}
+
+// Diagnostic describes the merge error for rendering (see diag.Renderer).
+func (m *MergeError) Diagnostic() diag.Diagnostic {
+	return diag.Diagnostic{Code: m.Code, Message: m.msg, Position: m.Position, Rule: m.Symbol}
+}
+
+// ReadError is returned if there is an error reading a grammar from a file or io.Reader.
+type ReadError struct {
+	// Filename is the name of the file the grammar was being read from.
+	Filename string
+	cause    error
+}
+
+// NewReadError instantiates a ReadError.
+func NewReadError(filename string, cause error) *ReadError {
+	return &ReadError{Filename: filename, cause: cause}
+}
+
+// Error fulfills the error interface.
+func (r *ReadError) Error() string {
+	return fmt.Sprintf("read error on %s: %s", r.Filename, r.cause)
+}
+
+// Unwrap allows retrieving the original error.
+func (r *ReadError) Unwrap() error {
+	return r.cause
+}
+
 // MarshalError is returned if there is an error marshalling a value.
 type MarshalError struct {
 	msg   string
@@ -45,3 +142,46 @@ func (m *MarshalError) Error() string {
 func (m *MarshalError) Unwrap() error {
 	return m.cause
 }
+
+// PrintError is returned if there is an error writing a printed Syntax.
+type PrintError struct {
+	cause error
+}
+
+// NewPrintError instantiates a PrintError.
+func NewPrintError(cause error) *PrintError {
+	return &PrintError{cause: cause}
+}
+
+// Error fulfills the error interface.
+func (p *PrintError) Error() string {
+	return fmt.Sprintf("print error: %s", p.cause)
+}
+
+// Unwrap allows retrieving the original error.
+func (p *PrintError) Unwrap() error {
+	return p.cause
+}
+
+// EditError is returned if an edit of a grammar cannot be made (see Editor).
+type EditError struct {
+	msg string
+	// Symbol is the symbol of the rule being edited.
+	Symbol string
+	cause  error
+}
+
+// NewEditError instantiates an EditError.
+func NewEditError(msg, symbol string, cause error) *EditError {
+	return &EditError{msg: msg, Symbol: symbol, cause: cause}
+}
+
+// Error fulfills the error interface.
+func (e *EditError) Error() string {
+	return fmt.Sprintf("failed editing rule %s: %s", e.Symbol, e.msg)
+}
+
+// Unwrap allows retrieving the original error (if there is one).
+func (e *EditError) Unwrap() error {
+	return e.cause
+}
@@ -0,0 +1,122 @@
+package w3c
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+
+	"github.com/alec-w/ebnf-go/diag"
+	"github.com/alec-w/ebnf-go/source"
+)
+
+// ParseFiles parses the EBNF grammar split across the files at paths within fsys, merging them into a single Syntax
+// (see Merge).
+//
+// If any of the files cannot be parsed no merge is attempted. When recovering, every file is parsed and the partial
+// syntaxes of all of them are merged, which is returned along with the parse and merge errors joined together (see
+// errors.Join). References to rules that could not be parsed are reported as undefined.
+func (p *Parser) ParseFiles(fsys fs.FS, paths ...string) (Syntax, error) {
+	syntaxes := make([]Syntax, 0, len(paths))
+	var errs []error
+	for _, path := range paths {
+		syntax, err := p.ParseFile(fsys, path)
+		if err != nil {
+			if !p.config.recovery {
+				return Syntax{}, err
+			}
+			errs = append(errs, err)
+		}
+		syntaxes = append(syntaxes, syntax)
+	}
+	merged, mergeErrs := merge(syntaxes)
+	if errs = append(errs, mergeErrs...); len(errs) > 0 && !p.config.recovery {
+		return Syntax{}, errors.Join(errs...)
+	}
+
+	return merged, errors.Join(errs...)
+}
+
+// Merge combines the syntaxes of an EBNF grammar split across several files (e.g. parsed with ParseFile) into a single
+// Syntax, with the rules (and trailing comments) of each in the order given.
+//
+// Every symbol must reference a rule defined in one of the syntaxes, and a rule may not be defined in more than one of
+// them. Rules are attributed to the syntax they are in (rather than the file names of their spans, which may be empty
+// or shared), so a symbol defined more than once within the same syntax is allowed (as it would be parsing its file
+// alone). References to productions in other specifications (see ExternalReferenceExpression) are not resolved. Every
+// violation is returned as a MergeError, joined together (see errors.Join).
+func Merge(syntaxes ...Syntax) (Syntax, error) {
+	merged, errs := merge(syntaxes)
+	if len(errs) > 0 {
+		return Syntax{}, errors.Join(errs...)
+	}
+
+	return merged, nil
+}
+
+// merge combines the syntaxes (see Merge), returning the merged syntax along with every violation.
+func merge(syntaxes []Syntax) (Syntax, []error) {
+	var merged Syntax
+	var errs []error
+	// definitions holds the first definition of each symbol.
+	definitions := map[string]ruleDefinition{}
+	for i, syntax := range syntaxes {
+		for _, rule := range syntax.Rules {
+			position := rule.Span.Start
+			first, ok := definitions[rule.Symbol]
+			if !ok {
+				definitions[rule.Symbol] = ruleDefinition{position: position, syntax: i}
+			} else if first.syntax != i {
+				errs = append(errs, NewMergeError(
+					diag.CodeDuplicateRule,
+					fmt.Sprintf("symbol %s is already defined at %s", rule.Symbol, first.position),
+					rule.Symbol,
+					position,
+				))
+			}
+			merged.Rules = append(merged.Rules, rule)
+		}
+		merged.TrailingComments = append(merged.TrailingComments, syntax.TrailingComments...)
+	}
+	for _, rule := range merged.Rules {
+		forEachSymbol(rule.Expression, func(symbol *SymbolExpression) {
+			if _, ok := definitions[symbol.Symbol]; ok {
+				return
+			}
+			errs = append(errs, NewMergeError(
+				diag.CodeUndefinedReference,
+				fmt.Sprintf("symbol %s is not defined by any rule", symbol.Symbol),
+				rule.Symbol,
+				symbol.Span().Start,
+			))
+		})
+	}
+
+	return merged, errs
+}
+
+// ruleDefinition is the definition of a rule within one of the syntaxes being merged.
+type ruleDefinition struct {
+	position source.Position
+	// syntax is the index of the syntax containing the definition.
+	syntax int
+}
+
+// forEachSymbol calls fn with every symbol expression within the expression, in the order they appear.
+func forEachSymbol(expression Expression, fn func(*SymbolExpression)) {
+	switch {
+	case expression == nil:
+	case expression.SymbolExpression() != nil:
+		fn(expression.SymbolExpression())
+	case expression.ListExpression() != nil:
+		for _, item := range expression.ListExpression().Expressions {
+			forEachSymbol(item, fn)
+		}
+	case expression.AlternateExpression() != nil:
+		for _, alternate := range expression.AlternateExpression().Expressions {
+			forEachSymbol(alternate, fn)
+		}
+	case expression.ExceptionExpression() != nil:
+		forEachSymbol(expression.ExceptionExpression().Match, fn)
+		forEachSymbol(expression.ExceptionExpression().Except, fn)
+	}
+}
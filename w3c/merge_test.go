@@ -0,0 +1,182 @@
+package w3c_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"testing/fstest"
+
+	"github.com/alec-w/ebnf-go/diag"
+	"github.com/alec-w/ebnf-go/source"
+	"github.com/alec-w/ebnf-go/w3c"
+)
+
+func TestParserParseFiles(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{
+		"lexical.ebnf":   {Data: []byte("Digit ::= [0-9]\nLetter ::= [a-z]\n/* end of lexical */\n")},
+		"syntactic.ebnf": {Data: []byte("Name ::= Letter (Letter | Digit)* - Digit\nChar ::= [http://example.com/#Char]\n")},
+		"duplicate.ebnf": {Data: []byte("Sign ::= '+' | '-'\nDigit ::= [0-9]\nSign ::= '+'\n")},
+		"undefined.ebnf": {Data: []byte("Signed ::= Sign? Number\n")},
+		"broken.ebnf":    {Data: []byte("Digit ::= [0-9\n")},
+	}
+	tcs := []struct {
+		name             string
+		paths            []string
+		expectedRules    []string
+		expectedComments []string
+		expectedErrors   []diag.Diagnostic
+	}{
+		{
+			name:             "references across files",
+			paths:            []string{"syntactic.ebnf", "lexical.ebnf"},
+			expectedRules:    []string{"Name", "Char", "Digit", "Letter"},
+			expectedComments: []string{"end of lexical"},
+		},
+		{
+			name:  "duplicate symbol in another file",
+			paths: []string{"lexical.ebnf", "duplicate.ebnf"},
+			expectedErrors: []diag.Diagnostic{
+				{
+					Code:     diag.CodeDuplicateRule,
+					Message:  "symbol Digit is already defined at lexical.ebnf:1:1",
+					Position: source.Position{Filename: "duplicate.ebnf", Offset: 19, Line: 2, Column: 1},
+					Rule:     "Digit",
+				},
+			},
+		},
+		{
+			name:  "undefined references",
+			paths: []string{"lexical.ebnf", "undefined.ebnf"},
+			expectedErrors: []diag.Diagnostic{
+				{
+					Code:     diag.CodeUndefinedReference,
+					Message:  "symbol Sign is not defined by any rule",
+					Position: source.Position{Filename: "undefined.ebnf", Offset: 11, Line: 1, Column: 12},
+					Rule:     "Signed",
+				},
+				{
+					Code:     diag.CodeUndefinedReference,
+					Message:  "symbol Number is not defined by any rule",
+					Position: source.Position{Filename: "undefined.ebnf", Offset: 17, Line: 1, Column: 18},
+					Rule:     "Signed",
+				},
+			},
+		},
+		{
+			name:  "parse error",
+			paths: []string{"lexical.ebnf", "broken.ebnf"},
+			expectedErrors: []diag.Diagnostic{
+				{
+					Code:     diag.CodeUnterminatedCharacterSet,
+					Message:  "character set is not terminated (expected ']')",
+					Position: source.Position{Filename: "broken.ebnf", Offset: 10, Line: 1, Column: 11},
+					Rule:     "Digit",
+					Expected: []string{`"]"`},
+					Found:    diag.EndOfInput,
+				},
+			},
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			syntax, err := w3c.New().ParseFiles(fsys, tc.paths...)
+			actualErrors := diag.Diagnostics(err)
+			if len(actualErrors) != len(tc.expectedErrors) {
+				t.Fatalf("Expected %d errors. Got %d: %v.", len(tc.expectedErrors), len(actualErrors), err)
+			}
+			for i, expected := range tc.expectedErrors {
+				actual := actualErrors[i]
+				if actual.Code != expected.Code || actual.Message != expected.Message ||
+					actual.Position != expected.Position || actual.Rule != expected.Rule ||
+					!slices.Equal(actual.Expected, expected.Expected) || actual.Found != expected.Found {
+					t.Errorf("Expected error %#v. Got %#v.", expected, actual)
+				}
+				if !errors.Is(err, expected.Code) {
+					t.Errorf("Expected errors to include code %s.", expected.Code)
+				}
+			}
+			var actualRules []string
+			for _, rule := range syntax.Rules {
+				actualRules = append(actualRules, rule.Symbol)
+			}
+			if !slices.Equal(actualRules, tc.expectedRules) {
+				t.Errorf("Expected rules %v. Got %v.", tc.expectedRules, actualRules)
+			}
+			if !slices.Equal(syntax.TrailingComments, tc.expectedComments) {
+				t.Errorf("Expected trailing comments %v. Got %v.", tc.expectedComments, syntax.TrailingComments)
+			}
+		})
+	}
+}
+
+func TestMergeAllowsDuplicatesWithinAFile(t *testing.T) {
+	t.Parallel()
+	syntax, err := w3c.New().Parse("Sign ::= '+'\nSign ::= '-'\n")
+	if err != nil {
+		t.Fatalf("Got unexpected error %s.", err)
+	}
+	merged, err := w3c.Merge(syntax)
+	if err != nil {
+		t.Fatalf("Got unexpected error %s.", err)
+	}
+	if len(merged.Rules) != 2 {
+		t.Errorf("Expected 2 rules. Got %d.", len(merged.Rules))
+	}
+}
+
+func TestMergeReportsDuplicatesAcrossSyntaxes(t *testing.T) {
+	t.Parallel()
+	testCases := map[string]*w3c.Parser{
+		"without filenames":    w3c.New(),
+		"with shared filename": w3c.New(w3c.WithFilename("grammar.ebnf")),
+	}
+	for name, parser := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			first, err := parser.Parse("x ::= 'a'\n")
+			if err != nil {
+				t.Fatalf("Got unexpected error %s.", err)
+			}
+			second, err := parser.Parse("y ::= x\nx ::= 'b'\n")
+			if err != nil {
+				t.Fatalf("Got unexpected error %s.", err)
+			}
+			_, err = w3c.Merge(first, second)
+			if !errors.Is(err, diag.CodeDuplicateRule) {
+				t.Fatalf("Expected a duplicate rule error. Got %v.", err)
+			}
+			var mergeErr *w3c.MergeError
+			if !errors.As(err, &mergeErr) || mergeErr.Symbol != "x" || mergeErr.Position.Line != 2 {
+				t.Errorf("Expected merge error for symbol x on line 2. Got %v.", err)
+			}
+		})
+	}
+}
+
+func TestParserParseFilesWithRecovery(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{
+		"lexical.ebnf": {Data: []byte("Digit ::= [0-9]\nLetter ::= [a-z]\n")},
+		"partial.ebnf": {Data: []byte("Sign ::= [+-\nWord ::= Letter+\nNumber ::= Sign? Digit+\n")},
+	}
+	syntax, err := w3c.New(w3c.WithRecovery()).ParseFiles(fsys, "lexical.ebnf", "partial.ebnf")
+	// The rule that could not be parsed is reported, as is the reference to it.
+	var codes []diag.Code
+	for _, diagnostic := range diag.Diagnostics(err) {
+		codes = append(codes, diagnostic.Code)
+	}
+	expectedCodes := []diag.Code{diag.CodeUnterminatedCharacterSet, diag.CodeUndefinedReference}
+	if !slices.Equal(codes, expectedCodes) {
+		t.Errorf("Expected errors with codes %v. Got %v: %v.", expectedCodes, codes, err)
+	}
+	var rules []string
+	for _, rule := range syntax.Rules {
+		rules = append(rules, rule.Symbol)
+	}
+	expectedRules := []string{"Digit", "Letter", "Word", "Number"}
+	if !slices.Equal(rules, expectedRules) {
+		t.Errorf("Expected rules %v. Got %v.", expectedRules, rules)
+	}
+}
@@ -1,80 +1,342 @@
 package w3c
 
 import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"slices"
 	"strconv"
+	"strings"
 	"unicode"
+	"unicode/utf16"
 	"unicode/utf8"
+
+	"github.com/alec-w/ebnf-go/diag"
+	"github.com/alec-w/ebnf-go/limit"
+	"github.com/alec-w/ebnf-go/source"
+)
+
+// Descriptions of the classes of tokens expected by a ParseError.
+const (
+	symbolToken           = "symbol"
+	literalToken          = "literal"
+	characterSetToken     = "character set"
+	productionNumberToken = "production number"
+	hexDigitToken         = "hex digit"
 )
 
 // Parser parses an EBNF grammar into a Syntax.
+//
+// A Parser is configured once, by the options given to New, and keeps no state between parses, so it is safe for
+// concurrent use by multiple goroutines.
 type Parser struct {
-	source string
-	offset int
-	line   int
+	config config
+}
+
+// config is the configuration of a Parser, shared by every parse it performs.
+type config struct {
+	// filename is recorded in the positions of the Syntax and in any errors of Parse and ParseContext.
+	filename string
+	limits   limit.Limits
+	recovery bool
+	strict   bool
+	cst      bool
+}
+
+// parser holds the state of a single parse.
+type parser struct {
+	config
+	source   string
+	offset   int
+	file     *source.File
+	comments []comment
+	// triviaStart is the index in comments of the first comment skipped in the current run of whitespace and comments,
+	// which ends at triviaEnd.
+	triviaStart int
+	triviaEnd   int
+	constraints []Constraint
+	// symbol is the symbol of the rule being parsed, recorded on any errors.
+	symbol string
+	// depth is the number of parenthesised expressions being parsed.
+	depth int
+	err   *ParseError
+}
+
+// comment is a comment that has been skipped but not yet attached to a rule or the syntax.
+type comment struct {
+	text string
+	// ownLine records whether there was a line break between the preceding token and the comment, which is used to
+	// decide whether a comment following a rule belongs to that rule or to the next one.
+	ownLine bool
+}
+
+// Option configures a Parser.
+type Option func(*Parser)
+
+// WithRecovery configures a Parser to recover from an error in a rule by skipping to the start of the next rule (a
+// symbol followed by "::=") and continuing to parse the rules that follow.
+//
+// When recovering, Parse returns every error found joined together (see errors.Join), along with a partial Syntax of
+// the rules that were parsed successfully.
+func WithRecovery() Option {
+	return func(p *Parser) {
+		p.config.recovery = true
+	}
+}
+
+// WithLimits configures the limits on the resources a Parser may use, replacing the default limits (see limit.Default).
+//
+// Exceeding a limit on the nesting depth or literal length is an error in the rule where it occurs, but exceeding the
+// limit on the number of rules stops parsing (even when recovering), as does the input size which is checked before
+// parsing starts.
+func WithLimits(limits limit.Limits) Option {
+	return func(p *Parser) {
+		p.config.limits = limits
+	}
+}
+
+// WithStrict configures a Parser to accept only the notation defined by the XML 1.0 specification, which rejects
+// references to productions in other specifications ([ followed by a URI and ]) as used by later W3C specifications.
+//
+// Syntax that is accepted otherwise is reported as an error with the code diag.CodeNonStandardSyntax.
+func WithStrict() Option {
+	return func(p *Parser) {
+		p.config.strict = true
+	}
+}
+
+// WithCST configures a Parser to build the lossless concrete syntax tree of each grammar it parses, which is recorded
+// in the CST of the Syntax.
+//
+// The tree keeps every token of the grammar as it is written, and the whitespace and comments between tokens, so the
+// grammar can be reproduced exactly (see cst.Node.String). When recovering from errors, the text of any rule that could
+// not be parsed is kept as tokens of the syntax.
+func WithCST() Option {
+	return func(p *Parser) {
+		p.config.cst = true
+	}
+}
+
+// WithFilename configures the file name that Parse and ParseContext record in the positions of the Syntax and in any
+// errors. ParseReader and ParseFile record the name they are given instead.
+func WithFilename(name string) Option {
+	return func(p *Parser) {
+		p.config.filename = name
+	}
 }
 
 // New instantiates a Parser.
-func New() *Parser {
-	return &Parser{}
+func New(opts ...Option) *Parser {
+	parser := &Parser{config: config{limits: limit.Default()}}
+	for _, opt := range opts {
+		opt(parser)
+	}
+
+	return parser
 }
 
 // Parse parses the given EBNF grammar into a Syntax representation.
-func (p *Parser) Parse(source string) (Syntax, error) {
-	p.source = source
-	p.offset = 0
-	p.line = 1
-	syntax, err := p.parseSyntax()
+func (p *Parser) Parse(grammar string) (Syntax, error) {
+	return p.parse(context.Background(), p.config.filename, grammar)
+}
+
+// ParseContext parses the given EBNF grammar into a Syntax representation, like Parse, but stops (even when recovering)
+// if the context is cancelled or its deadline passes before every rule has been parsed.
+func (p *Parser) ParseContext(ctx context.Context, grammar string) (Syntax, error) {
+	return p.parse(ctx, p.config.filename, grammar)
+}
+
+// ParseReader parses the EBNF grammar read from r into a Syntax representation, recording the given file name in its
+// positions and in any errors.
+func (p *Parser) ParseReader(name string, r io.Reader) (Syntax, error) {
+	if p.config.limits.MaxInputSize > 0 {
+		// Reading one byte beyond the limit is enough to know it has been exceeded, without reading all of the input.
+		r = io.LimitReader(r, int64(p.config.limits.MaxInputSize)+1)
+	}
+	grammar, err := io.ReadAll(r)
+	if err != nil {
+		return Syntax{}, NewReadError(name, err)
+	}
+
+	return p.parse(context.Background(), name, string(grammar))
+}
+
+// ParseFile parses the EBNF grammar in the file at path within fsys (e.g. an os.DirFS or embed.FS) into a Syntax
+// representation, recording the path in its positions and in any errors.
+func (p *Parser) ParseFile(fsys fs.FS, path string) (Syntax, error) {
+	file, err := fsys.Open(path)
+	if err != nil {
+		return Syntax{}, NewReadError(path, err)
+	}
+	syntax, err := p.ParseReader(path, file)
+	if closeErr := file.Close(); closeErr != nil && err == nil {
+		return Syntax{}, NewReadError(path, closeErr)
+	}
 
 	return syntax, err
 }
 
-func (p *Parser) parseSyntax() (Syntax, error) {
+func (p *Parser) parse(ctx context.Context, name, grammar string) (Syntax, error) {
+	if p.config.limits.InputSizeExceeded(len(grammar)) {
+		return Syntax{}, &limit.InputSizeError{Filename: name, Max: p.config.limits.MaxInputSize}
+	}
+	state := &parser{config: p.config, source: grammar, file: source.NewNamedFile(name, grammar)}
+	// A leading byte order mark is not part of the grammar.
+	if strings.HasPrefix(grammar, source.BOM) {
+		state.offset = len(source.BOM)
+	}
+	syntax, errs := state.parseSyntax(ctx)
+	if p.config.cst && (p.config.recovery || len(errs) == 0 && state.err == nil) {
+		syntax.CST = state.concreteSyntaxTree(syntax)
+	}
+	if state.err != nil {
+		// An unterminated comment swallows the rest of the grammar, so any other error is a consequence of it.
+		if !p.config.recovery {
+			return Syntax{}, state.err
+		}
+		i := 0
+		for i < len(errs) && errs[i].Offset < state.err.Offset {
+			i++
+		}
+		errs = slices.Insert(errs, i, state.err)
+	}
+	if len(errs) == 0 {
+		return syntax, nil
+	}
+	if !p.config.recovery {
+		return Syntax{}, errs[0]
+	}
+	joined := make([]error, 0, len(errs))
+	for _, err := range errs {
+		joined = append(joined, err)
+	}
+
+	return syntax, errors.Join(joined...)
+}
+
+// parseSyntax parses every rule of the grammar, returning the errors that occurred, which will be at most one unless
+// the parser is recovering from errors.
+func (p *parser) parseSyntax(ctx context.Context) (Syntax, []*ParseError) {
 	var syntax Syntax
+	var errs []*ParseError
 	for p.skipWhitespace(); p.source[p.offset:] != ""; p.skipWhitespace() {
+		if err := p.checkRule(ctx, len(syntax.Rules)+len(errs)+1); err != nil {
+			errs = append(errs, err)
+			if !p.recovery {
+				return Syntax{}, errs
+			}
+
+			break
+		}
+		ruleStart := p.offset
 		rule, err := p.parseRule()
 		if err != nil {
-			return Syntax{}, err
+			var parseErr *ParseError
+			if !errors.As(err, &parseErr) {
+				parseErr = p.parseErrorWithCause(diag.CodeUnexpectedToken, "could not parse rule", err)
+			}
+			errs = append(errs, parseErr)
+			if !p.recovery {
+				return Syntax{}, errs
+			}
+			p.skipToRuleStart(ruleStart, parseErr.Offset)
+
+			continue
 		}
 		syntax.Rules = append(syntax.Rules, rule)
 	}
+	syntax.TrailingComments = p.takeComments(len(p.comments))
 
-	return syntax, nil
+	return syntax, errs
 }
 
-func (p *Parser) parseRule() (Rule, error) {
-	p.skipWhitespace()
-	if char, _ := utf8.DecodeRuneInString(p.source[p.offset:]); !p.isBasicLatinLetter(char) {
-		return Rule{}, p.parseError("expected start of rule to begin with basic latin letter")
+// skipToRuleStart is used when recovering from an error to skip the remainder of the rule starting at ruleStart in
+// which the error occurred (at errOffset), up to the start of the next rule.
+//
+// Comments and constraints recorded for the rule in which the error occurred are discarded, but comments preceding the
+// next rule are kept for it.
+func (p *parser) skipToRuleStart(ruleStart, errOffset int) {
+	p.constraints = nil
+	p.comments = nil
+	p.triviaStart = 0
+	// Constructs that are not terminated report the error at their start, but may have consumed the rest of the
+	// grammar, so the search starts from the error rather than the current offset.
+	p.offset = errOffset
+	// The error may be at the start of the next rule (such as when a parenthesised expression is not closed), which is
+	// kept, unless it is the start of the rule in which the error occurred, which would be parsed again.
+	for atNextRule := errOffset > ruleStart && p.isRuleEnd(); !atNextRule; atNextRule = p.isRuleEnd() {
+		// Literals are skipped as a whole (when they are terminated) so their contents are not mistaken for the start
+		// of a rule or a comment.
+		char, width := p.next()
+		if char == '\'' || char == '"' {
+			if length := strings.IndexRune(p.source[p.offset+width:], char); length >= 0 {
+				p.offset += width + length
+			}
+		}
+		p.offset += width
 	}
-	rule := Rule{Symbol: p.parseSymbol(), Line: p.line}
+	p.comments = append(p.comments[:0], p.comments[p.triviaStart:]...)
+	p.triviaStart = 0
+}
+
+func (p *parser) parseRule() (Rule, error) {
+	// An error within a parenthesised expression of a previous rule may have left the depth unbalanced.
+	p.depth = 0
 	p.skipWhitespace()
-	char, width := utf8.DecodeRuneInString(p.source[p.offset:])
-	if char != ':' {
-		return Rule{}, p.parseError("expected rule defining symbol to be ':=='")
+	startOffset := p.offset
+	number, hasNumber := p.parseProductionNumber()
+	if hasNumber {
+		p.skipWhitespace()
 	}
-	p.offset += width
-	char, width = utf8.DecodeRuneInString(p.source[p.offset:])
-	if char != ':' {
-		return Rule{}, p.parseError("expected rule defining symbol to be '::='")
+	if char, _ := utf8.DecodeRuneInString(p.source[p.offset:]); !p.isBasicLatinLetter(char) {
+		if hasNumber {
+			return Rule{}, p.expectedError(diag.CodeMissingRuleName, symbolToken)
+		}
+
+		return Rule{}, p.expectedError(diag.CodeMissingRuleName, productionNumberToken, symbolToken)
 	}
-	p.offset += width
-	char, width = utf8.DecodeRuneInString(p.source[p.offset:])
-	if char != '=' {
-		return Rule{}, p.parseError("expected rule defining symbol to be '::='")
+	rule := Rule{Number: number, Line: p.file.Position(p.offset).Line}
+	rule.Symbol = p.parseSymbol()
+	p.symbol = rule.Symbol
+	defer func() { p.symbol = "" }()
+	p.skipWhitespace()
+	// The defining symbol is checked a character at a time, so that an error points at the first wrong character.
+	for _, expected := range "::=" {
+		char, width := utf8.DecodeRuneInString(p.source[p.offset:])
+		if char != expected {
+			return Rule{}, p.expectedError(diag.CodeMissingDefiningSymbol, diag.Literal(string(expected)))
+		}
+		p.offset += width
 	}
-	p.offset += width
 	expression, err := p.parseExpression()
 	if err != nil {
 		return Rule{}, err
 	}
 	rule.Expression = expression
+	rule.Constraints = p.constraints
+	p.constraints = nil
+	rule.Span = p.file.Span(startOffset, expression.Span().End.Offset)
+	// Constraints usually follow the expression, but may be within it.
+	if n := len(rule.Constraints); n > 0 && rule.Constraints[n-1].Span.End.Offset > rule.Span.End.Offset {
+		rule.Span.End = rule.Constraints[n-1].Span.End
+	}
+	// Comments following the rule on the same line as its final token belong to it, any starting on a later line are
+	// left for the next rule (or the syntax if this is the last rule).
+	end := p.triviaStart
+	for end < len(p.comments) && !p.comments[end].ownLine {
+		end++
+	}
+	rule.Comments = p.takeComments(end)
 
 	return rule, nil
 }
 
-func (p *Parser) parseSymbol() string {
+// parseSymbol parses a symbol, which starts with a basic latin letter (which should have already been checked by the
+// caller as this is internal to the parser) followed by any basic latin letters, digits and underscores.
+func (p *parser) parseSymbol() string {
 	var symbol []rune
-	for char, width := p.next(); p.isBasicLatinLetter(char); char, width = p.next() {
+	for char, width := p.next(); p.isSymbolCharacter(char); char, width = p.next() {
 		symbol = append(symbol, char)
 		p.offset += width
 	}
@@ -82,37 +344,92 @@ func (p *Parser) parseSymbol() string {
 	return string(symbol)
 }
 
-/*
-Expressions are parsed recursively for the shortest distance, then combined together
-A | (B | C) D
-Is parsed as:
-1st call - expression = A, next = (B | C) D - returns A | (B | C) D
-2nd call (invoked from 1st) - expression = (B | C), next = D - returns (B | C) D
-3rd call (invoked from 2nd) - expression = B, next = C - returns B | C
-4th call (invoked from 3rd) - expression = C, next = nil - returns C
-5th call (invoked from 2nd) - expression = D, next = nil - returns D
-*/
+// parseExpression parses the expression of a rule, or within parentheses, up to the end of the rule or the closing
+// parenthesis.
+//
+// The expression is parsed as a loop over its units (parenthesised or simple expressions, with any repetitions and
+// exception), rather than recursively, so that only nesting within parentheses is bounded by the depth limit. The units
+// are grouped into lists separated by "|", so lists bind tighter than alternates:
+// A | (B | C) D
+// Is parsed as alt(A, list((B | C), D)).
+func (p *parser) parseExpression() (Expression, error) {
+	var alternates []Expression
+	unit, err := p.parseExpressionUnit()
+	if err != nil {
+		return nil, err
+	}
+	list := []Expression{unit}
+	for !p.isRuleEnd() {
+		p.skipWhitespace()
+		char, width := utf8.DecodeRuneInString(p.source[p.offset:])
+		switch {
+		case char == '[' && p.isConstraintStart():
+			constraint, err := p.parseConstraint()
+			if err != nil {
+				return nil, err
+			}
+			p.constraints = append(p.constraints, constraint)
+		case p.isBasicLatinLetter(char) || char == '[' || char == '#' || char == '\'' || char == '"' || char == '(':
+			unit, err := p.parseExpressionUnit()
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, unit)
+		case char == '|':
+			p.offset += width
+			alternates = append(alternates, joinExpressionsAsList(list))
+			unit, err := p.parseExpressionUnit()
+			if err != nil {
+				return nil, err
+			}
+			list = []Expression{unit}
+		case char == ')':
+			return joinExpressionsAsAlternates(append(alternates, joinExpressionsAsList(list))), nil
+		default:
+			expected := append(expressionContinuations(), constraintTokens()...)
+			if p.depth > 0 {
+				expected = append(expected, diag.Literal(")"))
+			}
+
+			return nil, p.expectedError(diag.CodeUnexpectedToken, append(expected, diag.EndOfInput)...)
+		}
+	}
+
+	return joinExpressionsAsAlternates(append(alternates, joinExpressionsAsList(list))), nil
+}
 
-func (p *Parser) parseExpression() (Expression, error) {
+// parseExpressionUnit parses a parenthesised or simple expression, with any repetitions and exception, which is a
+// single unit of a list or alternate.
+func (p *parser) parseExpressionUnit() (Expression, error) {
 	var expression Expression
 	p.skipWhitespace()
+	startOffset := p.offset
 	char, width := utf8.DecodeRuneInString(p.source[p.offset:])
 	if char == '(' {
 		p.offset += width
+		p.depth++
+		if p.limits.DepthExceeded(p.depth) {
+			cause := &limit.DepthError{Max: p.limits.MaxDepth}
+
+			return nil, p.parseErrorWithCauseAt(diag.CodeDepthLimitExceeded, cause.Error(), startOffset, cause)
+		}
 		var err error
 		expression, err = p.parseExpression()
 		if err != nil {
 			return nil, err
 		}
+		p.depth--
 		p.skipWhitespace()
 		char, width := utf8.DecodeRuneInString(p.source[p.offset:])
 		if char != ')' {
-			return nil, p.parseError(
-				"expected closing parenthesis at end of parenthesised expression",
+			return nil, p.expectedError(
+				diag.CodeUnterminatedSequence,
+				append(expressionContinuations(), diag.Literal(")"))...,
 			)
 		}
 		p.offset += width
 		expression.setParenthesised(true)
+		expression.setSpan(p.file.Span(startOffset, p.offset))
 	} else {
 		var err error
 		expression, err = p.parseSimpleExpression()
@@ -121,64 +438,101 @@ func (p *Parser) parseExpression() (Expression, error) {
 		}
 	}
 	expression = p.parseExpressionRepetitions(expression)
-	expression, err := p.parseExpressionException(expression)
-	if err != nil {
-		return nil, err
+
+	return p.parseExpressionException(expression)
+}
+
+// joinExpressionsAsList returns the units as a list, or the unit itself if there is only one.
+//
+// The expressions of a (parenthesised) list without repetitions are joined with the others, as "A (B C)" is the same as
+// "A B C".
+func joinExpressionsAsList(units []Expression) Expression {
+	if len(units) == 1 {
+		return units[0]
 	}
-	for !p.isRuleEnd() {
-		p.skipWhitespace()
-		char, width = utf8.DecodeRuneInString(p.source[p.offset:])
-		switch {
-		case p.isBasicLatinLetter(char) || char == '[' || char == '#' || char == '\'' || char == '"' || char == '(':
-			next, err := p.parseExpression()
-			if err != nil {
-				return nil, err
-			}
-			expression = p.parseExpressionsAsList(expression, next)
-		case char == '|':
-			p.offset += width
-			next, err := p.parseExpression()
-			if err != nil {
-				return nil, err
-			}
-			expression = p.parseExpressionsAsAlternates(expression, next)
-		case char == ')':
-			return expression, nil
-		default:
-			return nil, p.parseError(
-				"expected end of rule, another expression, or an expression alternate symbol",
-			)
+	expressions := make([]Expression, 0, len(units))
+	for _, unit := range units {
+		if list := unit.ListExpression(); list != nil && !unit.hasRepetitions() {
+			expressions = append(expressions, list.Expressions...)
+		} else {
+			expressions = append(expressions, unit)
 		}
 	}
+	list := newListExpression(expressions)
+	// The list spans the parentheses of any lists joined at its ends.
+	list.setSpan(spanExpressions(units))
 
-	return expression, nil
+	return list
 }
 
-func (p *Parser) parseSimpleExpression() (Expression, error) {
+// joinExpressionsAsAlternates returns the expressions as alternates, or the expression itself if there is only one.
+//
+// The alternates of a (parenthesised) alternate without repetitions are joined with the others, as "A | (B | C)" is the
+// same as "A | B | C".
+func joinExpressionsAsAlternates(expressions []Expression) Expression {
+	if len(expressions) == 1 {
+		return expressions[0]
+	}
+	alternates := make([]Expression, 0, len(expressions))
+	for _, expression := range expressions {
+		if alternate := expression.AlternateExpression(); alternate != nil && !expression.hasRepetitions() {
+			alternates = append(alternates, alternate.Expressions...)
+		} else {
+			alternates = append(alternates, expression)
+		}
+	}
+	alternate := newAlternateExpression(alternates)
+	// The alternate spans the parentheses of any alternates joined at its ends.
+	alternate.setSpan(spanExpressions(expressions))
+
+	return alternate
+}
+
+func (p *parser) parseSimpleExpression() (Expression, error) {
 	p.skipWhitespace()
+	startOffset := p.offset
+	var expression Expression
+	var err error
 	char, _ := utf8.DecodeRuneInString(p.source[p.offset:])
 	switch {
+	case char == '[' && p.isExternalReferenceStart():
+		if p.strict {
+			return nil, p.parseError(
+				diag.CodeNonStandardSyntax,
+				"references to productions in other specifications are not part of the XML 1.0 notation",
+			)
+		}
+		expression, err = p.parseExternalReferenceExpression()
 	case char == '[':
 		fallthrough
 	case char == '#':
 		// character set, check next character to see if is a forbidden list
-		return p.parseCharacterSetExpression()
+		expression, err = p.parseCharacterSetExpression()
 	case char == '"':
 		fallthrough
 	case char == '\'':
 		// literal string
-		return p.parseLiteralExpression(), nil
+		expression, err = p.parseLiteralExpression()
 	case p.isBasicLatinLetter(char):
-		return &SymbolExpression{Symbol: p.parseSymbol()}, nil
+		expression = &SymbolExpression{Symbol: p.parseSymbol()}
 	default:
-		// error
-		return nil, p.parseError(
-			"looking for start of expression but character was not the start of an expression",
+		return nil, p.expectedError(
+			diag.CodeMissingExpression,
+			symbolToken,
+			literalToken,
+			characterSetToken,
+			diag.Literal("("),
 		)
 	}
+	if err != nil {
+		return nil, err
+	}
+	expression.setSpan(p.file.Span(startOffset, p.offset))
+
+	return expression, nil
 }
 
-func (p *Parser) parseExpressionRepetitions(expression Expression) Expression {
+func (p *parser) parseExpressionRepetitions(expression Expression) Expression {
 	p.skipWhitespace()
 	char, width := utf8.DecodeRuneInString(p.source[p.offset:])
 	switch char {
@@ -193,12 +547,17 @@ func (p *Parser) parseExpressionRepetitions(expression Expression) Expression {
 		p.offset += width
 	default:
 		// No repetitions
+		return expression
 	}
+	operand := expression.Span()
+	span := source.Span{Start: operand.Start, End: p.file.Position(p.offset)}
+	expression.setSpan(span)
+	expression.addRepeat(Repetition{Symbol: char, Operand: operand, Span: span})
 
 	return expression
 }
 
-func (p *Parser) parseExpressionException(expression Expression) (Expression, error) {
+func (p *parser) parseExpressionException(expression Expression) (Expression, error) {
 	p.skipWhitespace()
 	char, width := utf8.DecodeRuneInString(p.source[p.offset:])
 	if char != '-' {
@@ -222,34 +581,47 @@ func (p *Parser) parseExpressionException(expression Expression) (Expression, er
 		expression = p.parseExpressionRepetitions(expression)
 	}
 	exceptExpression.Except = expression
+	exceptExpression.setSpan(source.Span{Start: exceptExpression.Match.Span().Start, End: expression.Span().End})
 
 	return exceptExpression, nil
 }
 
-func (p *Parser) parseLiteralExpression() *LiteralExpression {
+func (p *parser) parseLiteralExpression() (*LiteralExpression, error) {
+	// A literal is any sequence of characters apart from the enclosing quote, wrapped in either '...' or "...".
+	openingOffset := p.offset
 	terminalChar, width := utf8.DecodeRuneInString(p.source[p.offset:])
 	p.offset += width
-	expression := &LiteralExpression{}
-	var char rune
-	for char, width = p.next(); char != terminalChar; char, width = p.next() {
-		expression.Literal += string(char)
-		p.offset += width
+	length := strings.IndexRune(p.source[p.offset:], terminalChar)
+	if length < 0 {
+		return nil, p.unterminatedError(
+			diag.CodeUnterminatedLiteral,
+			"literal is not terminated (expected closing quote ("+strconv.QuoteRune(terminalChar)+"))",
+			openingOffset,
+			diag.Literal(string(terminalChar)),
+		)
 	}
-	p.offset += width
+	expression := &LiteralExpression{Literal: p.source[p.offset : p.offset+length]}
+	p.offset += length + width
+	if p.limits.LiteralLengthExceeded(length) {
+		cause := &limit.LiteralLengthError{Max: p.limits.MaxLiteralLength, Length: length}
 
-	return expression
+		return nil, p.parseErrorWithCauseAt(diag.CodeLiteralLengthLimitExceeded, cause.Error(), openingOffset, cause)
+	}
+
+	return expression, nil
 }
 
-func (p *Parser) parseCharacterSetExpression() (*CharacterSetExpression, error) {
+func (p *parser) parseCharacterSetExpression() (*CharacterSetExpression, error) {
 	char, width := utf8.DecodeRuneInString(p.source[p.offset:])
 	if char == '#' {
-		char, err := p.parseHexCharacter()
+		char, err := p.parseHexCharacter(false)
 		if err != nil {
 			return nil, err
 		}
 
 		return &CharacterSetExpression{Enumerations: []rune{char}}, nil
 	}
+	openingOffset := p.offset
 	p.offset += width
 	expression := &CharacterSetExpression{}
 	char, width = utf8.DecodeRuneInString(p.source[p.offset:])
@@ -257,302 +629,401 @@ func (p *Parser) parseCharacterSetExpression() (*CharacterSetExpression, error)
 		expression.Forbidden = true
 		p.offset += width
 	}
-	for char, width = p.next(); char != ']'; char, width = p.next() {
-		if char == '#' {
-			var err error
-			char, err = p.parseHexCharacter()
-			if err != nil {
-				return nil, err
-			}
-		} else {
-			p.offset += width
+	for {
+		if p.source[p.offset:] == "" {
+			return nil, p.unterminatedError(
+				diag.CodeUnterminatedCharacterSet,
+				"character set is not terminated (expected ']')",
+				openingOffset,
+				diag.Literal("]"),
+			)
 		}
-		first := char
-		char, width = utf8.DecodeRuneInString(p.source[p.offset:])
-		if char == '-' {
+		char, width = p.next()
+		if char == ']' {
 			p.offset += width
-			char, width = utf8.DecodeRuneInString(p.source[p.offset:])
-			if char == '#' {
-				var err error
-				char, err = p.parseHexCharacter()
-				if err != nil {
-					return nil, err
-				}
-			} else {
-				p.offset += width
-			}
-			expression.Ranges = append(expression.Ranges, Range{Low: first, High: char})
-		} else {
-			enumeration, err := p.parseCharacterSetEnumeration()
-			if err != nil {
-				return nil, err
-			}
-			expression.Enumerations = append(expression.Enumerations, first)
-			expression.Enumerations = append(expression.Enumerations, enumeration...)
+
+			return expression, nil
+		}
+		low, err := p.parseCharacterSetCharacter()
+		if err != nil {
+			return nil, err
+		}
+		// A "-" is a range separator unless it is the last character in the set, in which case it is a literal "-".
+		char, width = p.next()
+		if char != '-' || strings.HasPrefix(p.source[p.offset+width:], "]") {
+			expression.Enumerations = append(expression.Enumerations, low)
+
+			continue
+		}
+		p.offset += width
+		if p.source[p.offset:] == "" {
+			continue
 		}
+		high, err := p.parseCharacterSetCharacter()
+		if err != nil {
+			return nil, err
+		}
+		expression.Ranges = append(expression.Ranges, Range{Low: low, High: high})
+	}
+}
+
+// parseCharacterSetCharacter parses a single character within a character set, which is either a literal character or
+// a hex character. A "#" that is not followed by "x" is a literal "#".
+func (p *parser) parseCharacterSetCharacter() (rune, error) {
+	char, width := p.next()
+	if strings.HasPrefix(p.source[p.offset:], "#x") {
+		return p.parseHexCharacter(true)
 	}
 	p.offset += width
 
-	return expression, nil
+	return char, nil
 }
 
-func (p *Parser) parseHexCharacter() (rune, error) {
-	_, width := utf8.DecodeRuneInString(p.source[p.offset:])
-	p.offset += width
-	char, width := utf8.DecodeRuneInString(p.source[p.offset:])
-	if char != 'x' {
-		return 0, p.parseError("did not get x after # in hex character")
+// parseHexCharacter parses a hexadecimal character reference (#xN), the value of which must be a Unicode scalar value,
+// or a surrogate if allowSurrogate is set (as surrogates can be used to describe ranges within character sets but are
+// not characters themselves).
+func (p *parser) parseHexCharacter(allowSurrogate bool) (rune, error) {
+	// This assumes the character at offset is "#", which should have already been checked by the caller as this is
+	// internal to the parser.
+	startOffset := p.offset
+	p.offset += len("#")
+	if char, _ := p.next(); char != 'x' {
+		return 0, p.expectedError(diag.CodeInvalidHexCharacter, diag.Literal("x"))
 	}
-	p.offset += width
-	var chars []rune
-	for char, width := p.next(); char >= '0' && char <= '9'; char, width = p.next() {
+	p.offset += len("x")
+	var value rune
+	digits := 0
+	for {
+		char, width := p.next()
+		digit, ok := hexDigit(char)
+		if !ok {
+			break
+		}
+		value = value*16 + digit
+		if value > unicode.MaxRune {
+			return 0, p.parseError(
+				diag.CodeInvalidHexCharacter,
+				"hex character is greater than the maximum Unicode code point (#x10FFFF)",
+			)
+		}
 		p.offset += width
-		chars = append(chars, char)
+		digits++
 	}
-	intVal, err := strconv.ParseUint(string(chars), 16, 32)
-	if err != nil {
-		return 0, p.parseErrorWithCause("could not parse hex character", err)
+	if digits == 0 {
+		return 0, p.expectedError(diag.CodeInvalidHexCharacter, hexDigitToken)
+	}
+	if !allowSurrogate && utf16.IsSurrogate(value) {
+		return 0, p.parseErrorAt(
+			diag.CodeInvalidHexCharacter,
+			"hex character is a surrogate, which is only allowed within a character set",
+			startOffset,
+		)
 	}
 
-	return rune(intVal), nil
+	return value, nil
 }
 
-func (p *Parser) parseCharacterSetEnumeration() ([]rune, error) {
-	var chars []rune
-	var offsets []int
-	for char, width := p.next(); char != ']'; char, width = p.next() {
-		offsets = append(offsets, p.offset)
-		if char == '#' {
-			var err error
-			char, err = p.parseHexCharacter()
-			if err != nil {
-				return nil, err
-			}
-		} else {
-			p.offset += width
-		}
-		chars = append(chars, char)
-		if char == '-' {
-			p.offset = offsets[len(offsets)-2]
-			chars = chars[:len(chars)-2]
+// hexDigit returns the value of a hexadecimal digit (upper or lower case) and whether the character was one.
+func hexDigit(char rune) (rune, bool) {
+	switch {
+	case char >= '0' && char <= '9':
+		return char - '0', true
+	case char >= 'a' && char <= 'f':
+		return char - 'a' + 10, true
+	case char >= 'A' && char <= 'F':
+		return char - 'A' + 10, true
+	default:
+		return 0, false
+	}
+}
 
-			break
-		}
+// isExternalReferenceStart checks whether the source at the current offset is the start of a reference to a production
+// in another specification ([ followed by a URI) rather than a character set.
+func (p *parser) isExternalReferenceStart() bool {
+	rest, ok := strings.CutPrefix(p.source[p.offset:], "[")
+	if !ok {
+		return false
+	}
+	// A URI starts with a scheme, a letter followed by letters, digits, "+", "-" or ".", which for the URI of a
+	// production is followed by "://".
+	scheme := strings.IndexFunc(rest, func(char rune) bool {
+		return !p.isBasicLatinLetter(char) && (char < '0' || char > '9') && char != '+' && char != '-' && char != '.'
+	})
+	if scheme < 1 || !p.isBasicLatinLetter(rune(rest[0])) {
+		return false
 	}
 
-	return chars, nil
-}
-
-func (p *Parser) parseExpressionsAsList(a, b Expression) Expression {
-	// A B
-	// B is simple expression
-	// (A1 | A2 | A3) B => list(A, B)
-	// (A1 A2 A3)? B => list(A, B)
-	// A1 A2 A3 B => list(A..., B)
-	// A1 | A2 | A3 B => alt(A[:-1]..., listJoin(A[-1], B))
-	// A B => list(A, B)
-	// B is parenthesised alternate
-	// (A1 | A2 | A3) (B1 | B2 | B3) => list(A, B)
-	// (A1 A2 A3)? (B1 | B2 | B3) => list(A, B)
-	// A1 A2 A3 (B1 | B2 | B3) => list(A... B)
-	// A1 | A2 | A3 (B1 | B2 | B3) => alt(A[:-1]..., listJoin(A[-1], B))
-	// A (B1 | B2 | B3) => list(A, B)
-	// B has repetitions
-	// (A1 | A2 | A3) (B1 B2 B3)? => list(A, B)
-	// (A1 A2 A3)? (B1 B2 B3)? => list(A, B)
-	// A1 A2 A3 (B1 B2 B3)? => list(A..., B)
-	// A1 | A2 | A3 (B1 B2 B3)? => alt(A[:-1]..., listJoin(A[-1], B))
-	// A (B1 B2 B3)? => list(A, B)
-	// B is list without repetitions
-	// (A1 | A2 | A3) B1 B2 B3 => list(A, B...)
-	// (A1 A2 A3)? B1 B2 B3 => list(A, B...)
-	// A1 A2 A3 B1 B2 B3 => list(A..., B...)
-	// A1 | A2 | A3 B1 B2 B3 => alt(A[:-1]..., listJoin(A[-1], B))
-	// A B1 B2 B3 => list(A, B...)
-	// B is alternate without parentheses or repetitions
-	// (A1 | A2 | A3) B1 | B2 | B3 => alt(listJoin(A, B[0]), B[1:]...)
-	// (A1 A2 A3)? B1 | B2 | B3 => alt(listJoin(A, B[0]), B[1:]...)
-	// A1 A2 A3 B1 | B2 | B3 => alt(listJoin(A, B[0]), B[1:]...)
-	// A B1 | B2 | B3 => alt(listJoin(A, B[0]), B[1:]...)
-	// A1 | A2 | A3 B1 | B2 | B3 => alt(A[:-1]..., listJoin(A[-1], B[0]), B[1:]...)
-	//
-	// Reordered
-	// Set 1
-	// (A1 | A2 | A3) B => list(A, B)
-	// (A1 A2 A3)? B => list(A, B)
-	// A B => list(A, B)
-	// (A1 | A2 | A3) (B1 | B2 | B3) => list(A, B)
-	// (A1 A2 A3)? (B1 | B2 | B3) => list(A, B)
-	// A (B1 | B2 | B3) => list(A, B)
-	// (A1 | A2 | A3) (B1 B2 B3)? => list(A, B)
-	// (A1 A2 A3)? (B1 B2 B3)? => list(A, B)
-	// A (B1 B2 B3)? => list(A, B)
-	// Set 2a
-	// A1 A2 A3 B => list(A..., B)
-	// A1 A2 A3 (B1 | B2 | B3) => list(A... B)
-	// A1 A2 A3 (B1 B2 B3)? => list(A..., B)
-	// Set 2b
-	// (A1 | A2 | A3) B1 B2 B3 => list(A, B...)
-	// (A1 A2 A3)? B1 B2 B3 => list(A, B...)
-	// A B1 B2 B3 => list(A, B...)
-	// Set 2c
-	// A1 A2 A3 B1 B2 B3 => list(A..., B...)
-	// Set 3a
-	// A1 | A2 | A3 B => alt(A[:-1]..., listJoin(A[-1], B))
-	// A1 | A2 | A3 (B1 | B2 | B3) => alt(A[:-1]..., listJoin(A[-1], B))
-	// A1 | A2 | A3 (B1 B2 B3)? => alt(A[:-1]..., listJoin(A[-1], B))
-	// A1 | A2 | A3 B1 B2 B3 => alt(A[:-1]..., listJoin(A[-1], B))
-	// Set 3b
-	// (A1 | A2 | A3) B1 | B2 | B3 => alt(listJoin(A, B[0]), B[1:]...)
-	// (A1 A2 A3)? B1 | B2 | B3 => alt(listJoin(A, B[0]), B[1:]...)
-	// A1 A2 A3 B1 | B2 | B3 => alt(listJoin(A, B[0]), B[1:]...)
-	// A B1 | B2 | B3 => alt(listJoin(A, B[0]), B[1:]...)
-	// Set 3c
-	// A1 | A2 | A3 B1 | B2 | B3 => alt(A[:-1]..., listJoin(A[-1], B[0]), B[1:]...)
-	aAsList := a.ListExpression()
-	bAsList := b.ListExpression()
-	aAsAlternate := a.AlternateExpression()
-	bAsAlternate := b.AlternateExpression()
-	aIsSimpleExpression := aAsAlternate == nil && aAsList == nil
-	bIsSimpleExpression := bAsAlternate == nil && bAsList == nil
-	// Set 1
-	// (A1 | A2 | A3) B => list(A, B)
-	// (A1 A2 A3)? B => list(A, B)
-	// A B => list(A, B)
-	// (A1 | A2 | A3) (B1 | B2 | B3) => list(A, B)
-	// (A1 A2 A3)? (B1 | B2 | B3) => list(A, B)
-	// A (B1 | B2 | B3) => list(A, B)
-	// (A1 | A2 | A3) (B1 B2 B3)? => list(A, B)
-	// (A1 A2 A3)? (B1 B2 B3)? => list(A, B)
-	// A (B1 B2 B3)? => list(A, B)
-	if ((aAsAlternate != nil && a.isParenthesised()) || a.hasRepetitions() || (aIsSimpleExpression)) &&
-		((bAsAlternate != nil && b.isParenthesised()) || b.hasRepetitions() || (bIsSimpleExpression)) {
-		return &ListExpression{Expressions: []Expression{a, b}}
-	}
-	// Set 2a
-	// A1 A2 A3 B => list(A..., B)
-	// A1 A2 A3 (B1 | B2 | B3) => list(A... B)
-	// A1 A2 A3 (B1 B2 B3)? => list(A..., B)
-	// Set 2b
-	// (A1 | A2 | A3) B1 B2 B3 => list(A, B...)
-	// (A1 A2 A3)? B1 B2 B3 => list(A, B...)
-	// A B1 B2 B3 => list(A, B...)
-	// Set 2c
-	// A1 A2 A3 B1 B2 B3 => list(A..., B...)
-	if (aAsList != nil && ((bAsAlternate != nil && b.isParenthesised()) || b.hasRepetitions() || (bIsSimpleExpression))) ||
-		(bAsList != nil && ((aAsAlternate != nil && a.isParenthesised()) || a.hasRepetitions() || (aIsSimpleExpression))) {
-		var expressions []Expression
-		if aAsList != nil && !a.hasRepetitions() {
-			expressions = append(expressions, aAsList.Expressions...)
-		} else {
-			expressions = append(expressions, a)
-		}
-		if bAsList != nil && !b.hasRepetitions() {
-			expressions = append(expressions, bAsList.Expressions...)
-		} else {
-			expressions = append(expressions, b)
-		}
+	return strings.HasPrefix(rest[scheme:], "://")
+}
 
-		return &ListExpression{Expressions: expressions}
-	}
-	// Set 3a
-	// A1 | A2 | A3 B => alt(A[:-1]..., listJoin(A[-1], B))
-	// A1 | A2 | A3 (B1 | B2 | B3) => alt(A[:-1]..., listJoin(A[-1], B))
-	// A1 | A2 | A3 (B1 B2 B3)? => alt(A[:-1]..., listJoin(A[-1], B))
-	// A1 | A2 | A3 B1 B2 B3 => alt(A[:-1]..., listJoin(A[-1], B))
-	// Set 3b
-	// (A1 | A2 | A3) B1 | B2 | B3 => alt(listJoin(A, B[0]), B[1:]...)
-	// (A1 A2 A3)? B1 | B2 | B3 => alt(listJoin(A, B[0]), B[1:]...)
-	// A1 A2 A3 B1 | B2 | B3 => alt(listJoin(A, B[0]), B[1:]...)
-	// A B1 | B2 | B3 => alt(listJoin(A, B[0]), B[1:]...)
-	// Set 3c
-	// A1 | A2 | A3 B1 | B2 | B3 => alt(A[:-1]..., listJoin(A[-1], B[0]), B[1:]...)
-	if aAsAlternate != nil && bAsAlternate != nil {
-		return &AlternateExpression{Expressions: append(
-			append(
-				aAsAlternate.Expressions[:len(aAsAlternate.Expressions)-1],
-				p.parseExpressionsAsList(
-					aAsAlternate.Expressions[len(aAsAlternate.Expressions)-1],
-					bAsAlternate.Expressions[0],
-				),
-			),
-			bAsAlternate.Expressions[1:]...,
-		)}
-	} else if aAsAlternate != nil {
-		return &AlternateExpression{Expressions: append(
-			aAsAlternate.Expressions[:len(aAsAlternate.Expressions)-1],
-			p.parseExpressionsAsList(aAsAlternate.Expressions[len(aAsAlternate.Expressions)-1], b),
-		)}
-	}
-
-	return &AlternateExpression{Expressions: append(
-		[]Expression{p.parseExpressionsAsList(a, bAsAlternate.Expressions[0])},
-		bAsAlternate.Expressions[1:]...,
-	)}
-}
-
-func (p *Parser) parseExpressionsAsAlternates(a, b Expression) Expression {
-	aAsAlternate := a.AlternateExpression()
-	bAsAlternate := b.AlternateExpression()
-	var expressions []Expression
-	if aAsAlternate != nil && !a.hasRepetitions() {
-		expressions = append(expressions, aAsAlternate.Expressions...)
-	} else {
-		expressions = append(expressions, a)
+func (p *parser) parseExternalReferenceExpression() (*ExternalReferenceExpression, error) {
+	// An external reference is a URI wrapped in [...].
+	// This assumes isExternalReferenceStart has already been checked, as this is internal to the parser this avoids
+	// unreachable error handling code.
+	openingOffset := p.offset
+	p.offset += len("[")
+	length := strings.IndexRune(p.source[p.offset:], ']')
+	if length < 0 {
+		return nil, p.unterminatedError(
+			diag.CodeUnterminatedExternalReference,
+			"external reference is not terminated (expected ']')",
+			openingOffset,
+			diag.Literal("]"),
+		)
 	}
-	if bAsAlternate != nil && !b.hasRepetitions() {
-		expressions = append(expressions, bAsAlternate.Expressions...)
-	} else {
-		expressions = append(expressions, b)
+	uri := p.source[p.offset : p.offset+length]
+	p.offset += length + len("]")
+
+	return &ExternalReferenceExpression{URI: uri}, nil
+}
+
+// isConstraintStart checks whether the source at the current offset is the start of a well-formedness or validity
+// constraint annotation ([ wfc: ... ] or [ vc: ... ]) rather than a character set.
+func (p *parser) isConstraintStart() bool {
+	rest, ok := strings.CutPrefix(p.source[p.offset:], "[")
+	if !ok {
+		return false
+	}
+	rest = strings.TrimLeftFunc(rest, unicode.IsSpace)
+	for _, kind := range []ConstraintKind{WellFormednessConstraint, ValidityConstraint} {
+		prefix := string(kind) + ":"
+		if len(rest) >= len(prefix) && strings.EqualFold(rest[:len(prefix)], prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p *parser) parseConstraint() (Constraint, error) {
+	// A constraint is a kind (wfc or vc, case insensitive) and a name separated by ":" and wrapped in [...].
+	// This assumes isConstraintStart has already been checked, as this is internal to the parser this avoids
+	// unreachable error handling code.
+	startOffset := p.offset
+	p.offset += len("[")
+	p.skipWhitespace()
+	colon := strings.IndexRune(p.source[p.offset:], ':')
+	kind := ConstraintKind(strings.ToLower(p.source[p.offset : p.offset+colon]))
+	p.offset += colon + len(":")
+	length := strings.IndexRune(p.source[p.offset:], ']')
+	if length < 0 {
+		return Constraint{}, p.unterminatedError(
+			diag.CodeUnterminatedConstraint,
+			"constraint annotation is not terminated (expected ']')",
+			startOffset,
+			diag.Literal("]"),
+		)
 	}
+	name := p.source[p.offset : p.offset+length]
+	p.offset += length + len("]")
 
-	return &AlternateExpression{Expressions: expressions}
+	return Constraint{Kind: kind, Name: strings.TrimSpace(name), Span: p.file.Span(startOffset, p.offset)}, nil
 }
 
-func (p *Parser) isRuleEnd() bool {
+func (p *parser) isRuleEnd() bool {
 	p.skipWhitespace()
 	if p.source[p.offset:] == "" {
 		return true
 	}
 	char, _ := utf8.DecodeRuneInString(p.source[p.offset:])
-	if !p.isBasicLatinLetter(char) {
+	if !p.isBasicLatinLetter(char) && char != '[' {
 		return false
 	}
 	startOffset := p.offset
-	startLine := p.line
+	startComments := len(p.comments)
+	startTriviaStart, startTriviaEnd := p.triviaStart, p.triviaEnd
+	defer func() {
+		p.offset = startOffset
+		p.comments = p.comments[:startComments]
+		p.triviaStart, p.triviaEnd = startTriviaStart, startTriviaEnd
+	}()
+	if _, ok := p.parseProductionNumber(); ok {
+		p.skipWhitespace()
+	}
+	if char, _ := p.next(); !p.isBasicLatinLetter(char) {
+		return false
+	}
 	p.parseSymbol()
 	p.skipWhitespace()
-	potentialDefiningSymbolOffset := p.offset
-	p.offset = startOffset
-	p.line = startLine
-	if potentialDefiningSymbolOffset+3 >= len(p.source) {
-		return false
+
+	return strings.HasPrefix(p.source[p.offset:], "::=")
+}
+
+// parseProductionNumber parses an optional production number (such as "[4]" or "[28a]") preceding a rule symbol.
+//
+// If the source at the current offset is not a production number then nothing is consumed and false is returned.
+func (p *parser) parseProductionNumber() (string, bool) {
+	rest, ok := strings.CutPrefix(p.source[p.offset:], "[")
+	if !ok {
+		return "", false
+	}
+	trimmed := strings.TrimLeftFunc(rest, unicode.IsSpace)
+	digits := 0
+	for digits < len(trimmed) && trimmed[digits] >= '0' && trimmed[digits] <= '9' {
+		digits++
 	}
+	if digits == 0 {
+		return "", false
+	}
+	length := digits
+	for length < len(trimmed) && p.isBasicLatinLetter(rune(trimmed[length])) {
+		length++
+	}
+	number := trimmed[:length]
+	after := strings.TrimLeftFunc(trimmed[length:], unicode.IsSpace)
+	if !strings.HasPrefix(after, "]") {
+		return "", false
+	}
+	p.offset += len(p.source[p.offset:]) - len(after) + len("]")
 
-	return p.source[potentialDefiningSymbolOffset:potentialDefiningSymbolOffset+3] == "::="
+	return number, true
 }
 
-func (p *Parser) isBasicLatinLetter(char rune) bool {
+func (p *parser) isBasicLatinLetter(char rune) bool {
 	return (char >= 'A' && char <= 'Z') || (char >= 'a' && char <= 'z')
 }
 
-func (p *Parser) skipWhitespace() {
-	for char, width := p.next(); unicode.IsSpace(char); char, width = p.next() {
-		p.offset += width
-		if char == '\n' {
-			p.line++
+func (p *parser) isSymbolCharacter(char rune) bool {
+	return p.isBasicLatinLetter(char) || (char >= '0' && char <= '9') || char == '_'
+}
+
+// skipWhitespace skips whitespace and comments, which are allowed anywhere whitespace is, and records the comments so
+// they can later be attached to a rule or the syntax.
+func (p *parser) skipWhitespace() {
+	if p.offset != p.triviaEnd {
+		p.triviaStart = len(p.comments)
+	}
+	sawNewline := false
+	for {
+		char, width := p.next()
+		switch {
+		case unicode.IsSpace(char):
+			p.offset += width
+			sawNewline = sawNewline || char == '\n'
+		case p.isCommentStart():
+			startOffset := p.offset
+			p.parseComment(sawNewline)
+			sawNewline = sawNewline || strings.Contains(p.source[startOffset:p.offset], "\n")
+		default:
+			p.triviaEnd = p.offset
+
+			return
+		}
+	}
+}
+
+func (p *parser) isCommentStart() bool {
+	return strings.HasPrefix(p.source[p.offset:], "/*")
+}
+
+func (p *parser) parseComment(ownLine bool) {
+	// A comment is any sequence of characters wrapped in /*...*/, comments cannot be nested.
+	// This assumes the source at the current offset starts with "/*" which should have been checked before calling
+	// this function.
+	startOffset := p.offset
+	p.offset += len("/*")
+	length := strings.Index(p.source[p.offset:], "*/")
+	if length < 0 {
+		if p.err == nil {
+			p.err = p.unterminatedError(
+				diag.CodeUnterminatedComment,
+				"comment is not terminated (expected '*/')",
+				startOffset,
+				diag.Literal("*/"),
+			)
 		}
+		length = len(p.source[p.offset:])
 	}
+	text := p.source[p.offset : p.offset+length]
+	p.offset = min(p.offset+length+len("*/"), len(p.source))
+	p.comments = append(p.comments, comment{text: strings.TrimSpace(text), ownLine: ownLine})
+}
+
+// takeComments removes the first n recorded comments and returns their text.
+func (p *parser) takeComments(n int) []string {
+	var comments []string
+	for _, c := range p.comments[:n] {
+		comments = append(comments, c.text)
+	}
+	p.comments = append(p.comments[:0], p.comments[n:]...)
+	p.triviaStart = max(p.triviaStart-n, 0)
+
+	return comments
 }
 
-func (p *Parser) next() (rune, int) {
+func (p *parser) next() (rune, int) {
 	return utf8.DecodeRuneInString(p.source[p.offset:])
 }
 
-func (p *Parser) parseError(msg string) *ParseError {
-	return NewParseError(msg, p.line, p.offset, nil)
+func (p *parser) parseError(code diag.Code, msg string) *ParseError {
+	return p.parseErrorAt(code, msg, p.offset)
+}
+
+func (p *parser) parseErrorAt(code diag.Code, msg string, offset int) *ParseError {
+	return p.parseErrorWithCauseAt(code, msg, offset, nil)
+}
+
+func (p *parser) parseErrorWithCause(code diag.Code, msg string, cause error) *ParseError {
+	return p.parseErrorWithCauseAt(code, msg, p.offset, cause)
 }
 
-func (p *Parser) parseErrorWithCause(msg string, cause error) *ParseError {
-	return NewParseError(msg, p.line, p.offset, cause)
+func (p *parser) parseErrorWithCauseAt(code diag.Code, msg string, offset int, cause error) *ParseError {
+	position := p.file.Position(offset)
+	err := NewParseError(msg, position.Line, offset, cause)
+	err.Code = code
+	err.Filename = position.Filename
+	err.Column = position.Column
+	err.Symbol = p.symbol
+
+	return err
+}
+
+// checkRule is used before parsing each rule (given its number) to check that the context has not been cancelled and
+// the limit on the number of rules has not been exceeded.
+func (p *parser) checkRule(ctx context.Context, rule int) *ParseError {
+	if err := ctx.Err(); err != nil {
+		return p.parseErrorWithCause(diag.CodeCancelled, "parsing was stopped ("+err.Error()+")", err)
+	}
+	if p.limits.RulesExceeded(rule) {
+		cause := &limit.RuleCountError{Max: p.limits.MaxRules}
+
+		return p.parseErrorWithCause(diag.CodeRuleLimitExceeded, cause.Error(), cause)
+	}
+
+	return nil
+}
+
+// expectedError is a utility function used to create a ParseError for an unexpected token at the current offset, where
+// one of the expected tokens would have been acceptable.
+func (p *parser) expectedError(code diag.Code, expected ...string) *ParseError {
+	found := diag.FoundToken(p.source[p.offset:])
+	err := p.parseError(code, diag.ExpectedMessage(expected, found))
+	err.Expected = expected
+	err.Found = found
+
+	return err
+}
+
+// unterminatedError is a utility function used to create a ParseError for a construct opened at the given offset that
+// runs to the end of the grammar without being closed by the expected token.
+func (p *parser) unterminatedError(code diag.Code, msg string, openingOffset int, expected string) *ParseError {
+	err := p.parseErrorAt(code, msg, openingOffset)
+	err.Expected = []string{expected}
+	err.Found = diag.EndOfInput
+
+	return err
+}
+
+// expressionContinuations returns the tokens that could continue a parsed expression: another expression in a list or
+// an alternate.
+func expressionContinuations() []string {
+	return []string{symbolToken, literalToken, characterSetToken, diag.Literal("("), diag.Literal("|")}
+}
+
+// constraintTokens returns the tokens that start a constraint annotation.
+func constraintTokens() []string {
+	return []string{
+		diag.Literal("[" + string(WellFormednessConstraint) + ":"),
+		diag.Literal("[" + string(ValidityConstraint) + ":"),
+	}
 }
@@ -1,9 +1,24 @@
 package w3c_test
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
 	"testing"
+	"testing/fstest"
+	"testing/iotest"
 
+	"github.com/alec-w/ebnf-go/diag"
+	"github.com/alec-w/ebnf-go/limit"
+	"github.com/alec-w/ebnf-go/source"
 	"github.com/alec-w/ebnf-go/w3c"
 )
 
@@ -38,14 +53,36 @@ func assertSlicesEqual[T any](
 
 func assertSyntaxesEqual(t *testing.T, expected, actual w3c.Syntax) bool {
 	t.Helper()
+	var failed bool
+	if !assertSlicesEqual(
+		t,
+		expected.TrailingComments,
+		actual.TrailingComments,
+		"trailing comments",
+		"trailing comment",
+		assertCommentsEqual,
+	) {
+		t.Error("Syntax trailing comments were not equal.")
+		failed = true
+	}
 	if assertSlicesEqual(t, expected.Rules, actual.Rules, "rules", "rule", assertRulesEqual) {
-		return true
+		return !failed
 	}
 	t.Error("Syntax rules were not equal.")
 
 	return false
 }
 
+func assertCommentsEqual(t *testing.T, expected, actual string) bool {
+	t.Helper()
+	if expected == actual {
+		return true
+	}
+	t.Errorf("Expected comment %q but got %q.", expected, actual)
+
+	return false
+}
+
 func assertRulesEqual(t *testing.T, expected, actual w3c.Rule) bool {
 	t.Helper()
 	var failed bool
@@ -53,14 +90,50 @@ func assertRulesEqual(t *testing.T, expected, actual w3c.Rule) bool {
 		t.Errorf("Expected rule to have symbol %q but got %q.", expected.Symbol, actual.Symbol)
 		failed = true
 	}
+	if expected.Number != actual.Number {
+		t.Errorf("Expected rule to have number %q but got %q.", expected.Number, actual.Number)
+		failed = true
+	}
 	if expected.Line != actual.Line {
 		t.Errorf("Expected rule to be from line %d but got %d.", expected.Line, actual.Line)
 		failed = true
 	}
+	if !assertSlicesEqual(
+		t,
+		expected.Comments,
+		actual.Comments,
+		"comments",
+		"comment",
+		assertCommentsEqual,
+	) {
+		t.Error("Rule comments were not equal.")
+		failed = true
+	}
+	if !assertSlicesEqual(
+		t,
+		expected.Constraints,
+		actual.Constraints,
+		"constraints",
+		"constraint",
+		assertConstraintsEqual,
+	) {
+		t.Error("Rule constraints were not equal.")
+		failed = true
+	}
 
 	return assertExpressionsEqual(t, expected.Expression, actual.Expression) && !failed
 }
 
+func assertConstraintsEqual(t *testing.T, expected, actual w3c.Constraint) bool {
+	t.Helper()
+	if expected.Kind == actual.Kind && expected.Name == actual.Name {
+		return true
+	}
+	t.Errorf("Expected constraint %s: %q but got %s: %q.", expected.Kind, expected.Name, actual.Kind, actual.Name)
+
+	return false
+}
+
 func assertExpressionsEqual(t *testing.T, expected, actual w3c.Expression) bool {
 	t.Helper()
 	var failed bool
@@ -113,6 +186,14 @@ func assertExpressionsEqual(t *testing.T, expected, actual w3c.Expression) bool
 		t.Errorf("Alternate expressions were not equal")
 		failed = true
 	}
+	if !assertExternalReferenceExpressionsEqual(
+		t,
+		expected.ExternalReferenceExpression(),
+		actual.ExternalReferenceExpression(),
+	) {
+		t.Errorf("External reference expressions were not equal")
+		failed = true
+	}
 	if expected.Optional() != actual.Optional() {
 		if expected.Optional() {
 			t.Errorf("Expected expression to be optional")
@@ -195,6 +276,36 @@ func assertSymbolExpressionsEqual(t *testing.T, expected, actual *w3c.SymbolExpr
 	return false
 }
 
+func assertExternalReferenceExpressionsEqual(
+	t *testing.T,
+	expected, actual *w3c.ExternalReferenceExpression,
+) bool {
+	t.Helper()
+	if expected == nil && actual == nil {
+		return true
+	}
+	if expected == nil {
+		t.Error("Got unexpected external reference expression.")
+
+		return false
+	}
+	if actual == nil {
+		t.Error("Expected external reference expression.")
+
+		return false
+	}
+	if expected.URI == actual.URI {
+		return true
+	}
+	t.Errorf(
+		"Expected external reference expression to have URI %q but got %q.",
+		expected.URI,
+		actual.URI,
+	)
+
+	return false
+}
+
 func assertCharacterSetExpressionsEqual(
 	t *testing.T,
 	expected, actual *w3c.CharacterSetExpression,
@@ -570,6 +681,85 @@ func TestParserParse(t *testing.T) {
 				},
 			}},
 		},
+		{
+			name:    "symbols with digits and underscores",
+			grammar: "PN_CHARS_U ::= PN_CHARS_BASE | '_'\nVAR1 ::= '?' PN_CHARS_U",
+			expectedSyntax: w3c.Syntax{Rules: []w3c.Rule{
+				{
+					Symbol: "PN_CHARS_U", Line: 1, Expression: &w3c.AlternateExpression{Expressions: []w3c.Expression{
+						&w3c.SymbolExpression{Symbol: "PN_CHARS_BASE"},
+						&w3c.LiteralExpression{Literal: "_"},
+					}},
+				},
+				{
+					Symbol: "VAR1", Line: 2, Expression: &w3c.ListExpression{Expressions: []w3c.Expression{
+						&w3c.LiteralExpression{Literal: "?"},
+						&w3c.SymbolExpression{Symbol: "PN_CHARS_U"},
+					}},
+				},
+			}},
+		},
+		{
+			name:    "character set with literal hash",
+			grammar: "PubidChar ::= [-'()#@$_%]",
+			expectedSyntax: w3c.Syntax{Rules: []w3c.Rule{
+				{
+					Symbol: "PubidChar", Line: 1, Expression: &w3c.CharacterSetExpression{
+						Enumerations: []rune{'-', '\'', '(', ')', '#', '@', '$', '_', '%'},
+					},
+				},
+			}},
+		},
+		{
+			name:    "external reference",
+			grammar: "NCName ::= [http://www.w3.org/TR/REC-xml-names/#NT-NCName]+ /* xgc: xml-version */",
+			expectedSyntax: w3c.Syntax{Rules: []w3c.Rule{
+				{
+					Symbol:   "NCName",
+					Line:     1,
+					Comments: []string{"xgc: xml-version"},
+					Expression: &w3c.ExternalReferenceExpression{
+						URI:         "http://www.w3.org/TR/REC-xml-names/#NT-NCName",
+						Repetitions: w3c.Repetitions{OneOrMore: true},
+					},
+				},
+			}},
+		},
+		{
+			name:    "hex character with upper and lower case digits",
+			grammar: "testRule ::= #x20aC",
+			expectedSyntax: w3c.Syntax{Rules: []w3c.Rule{
+				{
+					Symbol: "testRule", Line: 1, Expression: &w3c.CharacterSetExpression{
+						Enumerations: []rune{'€'},
+					},
+				},
+			}},
+		},
+		{
+			name:    "character set with hex letter digits",
+			grammar: "testRule ::= [#x9#xA#xD#x20-#xD7FF#x10000-#x10FFFF]",
+			expectedSyntax: w3c.Syntax{Rules: []w3c.Rule{
+				{
+					Symbol: "testRule", Line: 1, Expression: &w3c.CharacterSetExpression{
+						Enumerations: []rune{'\t', '\n', '\r'},
+						Ranges:       []w3c.Range{{Low: ' ', High: 0xD7FF}, {Low: 0x10000, High: 0x10FFFF}},
+					},
+				},
+			}},
+		},
+		{
+			name:    "character set range of surrogates",
+			grammar: "testRule ::= [^#xD800-#xDFFF]",
+			expectedSyntax: w3c.Syntax{Rules: []w3c.Rule{
+				{
+					Symbol: "testRule", Line: 1, Expression: &w3c.CharacterSetExpression{
+						Forbidden: true,
+						Ranges:    []w3c.Range{{Low: 0xD800, High: 0xDFFF}},
+					},
+				},
+			}},
+		},
 		{
 			name:    "non-zero positive integer definition",
 			grammar: "testRule ::= [1-9] [0-9]*",
@@ -687,6 +877,196 @@ PrintStatement ::= PrintKeyword RS ( StringLiteral | IntegerAdditionExpr )`,
 				},
 			}},
 		},
+		{
+			name: "comments on rules and syntax",
+			grammar: `/* Leading comment */
+first ::= 'one' /* same line */
+/* Before second */
+second ::= 'two' /* inside */ 'three'
+/* Trailing
+   comment */`,
+			expectedSyntax: w3c.Syntax{
+				Rules: []w3c.Rule{
+					{
+						Symbol:     "first",
+						Line:       2,
+						Comments:   []string{"Leading comment", "same line"},
+						Expression: &w3c.LiteralExpression{Literal: "one"},
+					},
+					{
+						Symbol:   "second",
+						Line:     4,
+						Comments: []string{"Before second", "inside"},
+						Expression: &w3c.ListExpression{Expressions: []w3c.Expression{
+							&w3c.LiteralExpression{Literal: "two"},
+							&w3c.LiteralExpression{Literal: "three"},
+						}},
+					},
+				},
+				TrailingComments: []string{"Trailing\n   comment"},
+			},
+		},
+		{
+			name:    "comments between symbol and defining symbol and within parentheses",
+			grammar: "testRule /* a */ ::= ( 'one' /* b */ | 'two' )",
+			expectedSyntax: w3c.Syntax{Rules: []w3c.Rule{
+				{
+					Symbol:   "testRule",
+					Line:     1,
+					Comments: []string{"a", "b"},
+					Expression: &w3c.AlternateExpression{Expressions: []w3c.Expression{
+						&w3c.LiteralExpression{Literal: "one"},
+						&w3c.LiteralExpression{Literal: "two"},
+					}},
+				},
+			}},
+		},
+		{
+			name: "constraint annotations",
+			grammar: `element ::= EmptyElemTag | STag content ETag [ WFC: Element Type Match ] [vc:Element Valid]
+CharRef ::= '&#' [0-9]+ ';' [ wfc: Legal Character ]`,
+			expectedSyntax: w3c.Syntax{Rules: []w3c.Rule{
+				{
+					Symbol: "element", Line: 1, Expression: &w3c.AlternateExpression{Expressions: []w3c.Expression{
+						&w3c.SymbolExpression{Symbol: "EmptyElemTag"},
+						&w3c.ListExpression{Expressions: []w3c.Expression{
+							&w3c.SymbolExpression{Symbol: "STag"},
+							&w3c.SymbolExpression{Symbol: "content"},
+							&w3c.SymbolExpression{Symbol: "ETag"},
+						}},
+					}},
+					Constraints: []w3c.Constraint{
+						{Kind: w3c.WellFormednessConstraint, Name: "Element Type Match"},
+						{Kind: w3c.ValidityConstraint, Name: "Element Valid"},
+					},
+				},
+				{
+					Symbol: "CharRef", Line: 2, Expression: &w3c.ListExpression{Expressions: []w3c.Expression{
+						&w3c.LiteralExpression{Literal: "&#"},
+						&w3c.CharacterSetExpression{
+							Ranges:      []w3c.Range{{Low: '0', High: '9'}},
+							Repetitions: w3c.Repetitions{OneOrMore: true},
+						},
+						&w3c.LiteralExpression{Literal: ";"},
+					}},
+					Constraints: []w3c.Constraint{{Kind: w3c.WellFormednessConstraint, Name: "Legal Character"}},
+				},
+			}},
+		},
+		{
+			name: "production numbers",
+			grammar: `[4] NameStartChar ::= [A-Z]
+[ 4a ] NameChar ::= NameStartChar | [0-9]
+[5]Name ::= NameStartChar (NameChar)*`,
+			expectedSyntax: w3c.Syntax{Rules: []w3c.Rule{
+				{
+					Number: "4", Symbol: "NameStartChar", Line: 1, Expression: &w3c.CharacterSetExpression{
+						Ranges: []w3c.Range{{Low: 'A', High: 'Z'}},
+					},
+				},
+				{
+					Number: "4a", Symbol: "NameChar", Line: 2, Expression: &w3c.AlternateExpression{
+						Expressions: []w3c.Expression{
+							&w3c.SymbolExpression{Symbol: "NameStartChar"},
+							&w3c.CharacterSetExpression{Ranges: []w3c.Range{{Low: '0', High: '9'}}},
+						},
+					},
+				},
+				{
+					Number: "5", Symbol: "Name", Line: 3, Expression: &w3c.ListExpression{Expressions: []w3c.Expression{
+						&w3c.SymbolExpression{Symbol: "NameStartChar"},
+						&w3c.SymbolExpression{
+							Symbol:      "NameChar",
+							Repetitions: w3c.Repetitions{ZeroOrMore: true},
+						},
+					}},
+				},
+			}},
+		},
+		{
+			name:    "parenthesised list followed by list",
+			grammar: "testRule ::= ('one' 'two') 'three' 'four'",
+			expectedSyntax: w3c.Syntax{Rules: []w3c.Rule{
+				{
+					Symbol: "testRule", Line: 1, Expression: &w3c.ListExpression{Expressions: []w3c.Expression{
+						&w3c.LiteralExpression{Literal: "one"},
+						&w3c.LiteralExpression{Literal: "two"},
+						&w3c.LiteralExpression{Literal: "three"},
+						&w3c.LiteralExpression{Literal: "four"},
+					}},
+				},
+			}},
+		},
+		{
+			name:    "list ending in parenthesised list",
+			grammar: "testRule ::= 'one' ('two' 'three')",
+			expectedSyntax: w3c.Syntax{Rules: []w3c.Rule{
+				{
+					Symbol: "testRule", Line: 1, Expression: &w3c.ListExpression{Expressions: []w3c.Expression{
+						&w3c.LiteralExpression{Literal: "one"},
+						&w3c.LiteralExpression{Literal: "two"},
+						&w3c.LiteralExpression{Literal: "three"},
+					}},
+				},
+			}},
+		},
+		{
+			name:    "list ending in parenthesised list with repetitions",
+			grammar: "testRule ::= 'one' ('two' 'three')?",
+			expectedSyntax: w3c.Syntax{Rules: []w3c.Rule{
+				{
+					Symbol: "testRule", Line: 1, Expression: &w3c.ListExpression{Expressions: []w3c.Expression{
+						&w3c.LiteralExpression{Literal: "one"},
+						&w3c.ListExpression{
+							Expressions: []w3c.Expression{
+								&w3c.LiteralExpression{Literal: "two"},
+								&w3c.LiteralExpression{Literal: "three"},
+							},
+							Repetitions: w3c.Repetitions{Optional: true},
+						},
+					}},
+				},
+			}},
+		},
+		{
+			name:    "parenthesised alternate followed by alternate",
+			grammar: "testRule ::= ('one' | 'two') 'three' | 'four'",
+			expectedSyntax: w3c.Syntax{Rules: []w3c.Rule{
+				{
+					Symbol: "testRule", Line: 1, Expression: &w3c.AlternateExpression{Expressions: []w3c.Expression{
+						&w3c.ListExpression{Expressions: []w3c.Expression{
+							&w3c.AlternateExpression{Expressions: []w3c.Expression{
+								&w3c.LiteralExpression{Literal: "one"},
+								&w3c.LiteralExpression{Literal: "two"},
+							}},
+							&w3c.LiteralExpression{Literal: "three"},
+						}},
+						&w3c.LiteralExpression{Literal: "four"},
+					}},
+				},
+			}},
+		},
+		{
+			name:    "list ending in parenthesised alternate followed by alternate",
+			grammar: "testRule ::= 'one' ('two' 'three' | 'four') | 'five'",
+			expectedSyntax: w3c.Syntax{Rules: []w3c.Rule{
+				{
+					Symbol: "testRule", Line: 1, Expression: &w3c.AlternateExpression{Expressions: []w3c.Expression{
+						&w3c.ListExpression{Expressions: []w3c.Expression{
+							&w3c.LiteralExpression{Literal: "one"},
+							&w3c.AlternateExpression{Expressions: []w3c.Expression{
+								&w3c.ListExpression{Expressions: []w3c.Expression{
+									&w3c.LiteralExpression{Literal: "two"},
+									&w3c.LiteralExpression{Literal: "three"},
+								}},
+								&w3c.LiteralExpression{Literal: "four"},
+							}},
+						}},
+						&w3c.LiteralExpression{Literal: "five"},
+					}},
+				},
+			}},
+		},
 	}
 
 	for _, tc := range tcs {
@@ -701,3 +1081,751 @@ PrintStatement ::= PrintKeyword RS ( StringLiteral | IntegerAdditionExpr )`,
 		})
 	}
 }
+
+func TestParserParseErrors(t *testing.T) {
+	t.Parallel()
+	tcs := []struct {
+		name           string
+		grammar        string
+		expectedLine   int
+		expectedOffset int
+		expectedCode   diag.Code
+	}{
+		{
+			name:           "unterminated single quoted literal",
+			grammar:        "testRule ::= 'one",
+			expectedLine:   1,
+			expectedOffset: 13,
+			expectedCode:   diag.CodeUnterminatedLiteral,
+		},
+		{
+			name:           "unterminated double quoted literal",
+			grammar:        "testRule ::= \"one",
+			expectedLine:   1,
+			expectedOffset: 13,
+			expectedCode:   diag.CodeUnterminatedLiteral,
+		},
+		{
+			name:           "unterminated character set",
+			grammar:        "testRule ::=\n [a-z",
+			expectedLine:   2,
+			expectedOffset: 14,
+			expectedCode:   diag.CodeUnterminatedCharacterSet,
+		},
+		{
+			name:           "unterminated character set range",
+			grammar:        "testRule ::= [a-",
+			expectedLine:   1,
+			expectedOffset: 13,
+			expectedCode:   diag.CodeUnterminatedCharacterSet,
+		},
+		{
+			name:           "unterminated comment",
+			grammar:        "testRule ::= 'one' /* two",
+			expectedLine:   1,
+			expectedOffset: 19,
+			expectedCode:   diag.CodeUnterminatedComment,
+		},
+		{
+			name:           "unterminated constraint",
+			grammar:        "testRule ::= 'one' [wfc: two",
+			expectedLine:   1,
+			expectedOffset: 19,
+			expectedCode:   diag.CodeUnterminatedConstraint,
+		},
+		{
+			name:           "truncated defining symbol",
+			grammar:        "testRule ::",
+			expectedLine:   1,
+			expectedOffset: 11,
+			expectedCode:   diag.CodeMissingDefiningSymbol,
+		},
+		{
+			name:           "missing expression",
+			grammar:        "testRule ::=",
+			expectedLine:   1,
+			expectedOffset: 12,
+			expectedCode:   diag.CodeMissingExpression,
+		},
+		{
+			name:           "unclosed parenthesis",
+			grammar:        "testRule ::= ('one'",
+			expectedLine:   1,
+			expectedOffset: 19,
+			expectedCode:   diag.CodeUnterminatedSequence,
+		},
+		{
+			name:           "truncated hex character",
+			grammar:        "testRule ::= #",
+			expectedLine:   1,
+			expectedOffset: 14,
+			expectedCode:   diag.CodeInvalidHexCharacter,
+		},
+		{
+			name:           "hex character greater than maximum code point",
+			grammar:        "testRule ::= [#x10FFFF#x110000]",
+			expectedLine:   1,
+			expectedOffset: 29,
+			expectedCode:   diag.CodeInvalidHexCharacter,
+		},
+		{
+			name:           "surrogate hex character outside character set",
+			grammar:        "testRule ::= 'a' #xDFFF",
+			expectedLine:   1,
+			expectedOffset: 17,
+			expectedCode:   diag.CodeInvalidHexCharacter,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			parser := w3c.New()
+			_, err := parser.Parse(tc.grammar)
+			var parseErr *w3c.ParseError
+			if !errors.As(err, &parseErr) {
+				t.Fatalf("Expected parse error but got %v.", err)
+			}
+			if !errors.Is(err, tc.expectedCode) {
+				t.Errorf("Expected error with code %s but got %s (%s).", tc.expectedCode, parseErr.Code, err)
+			}
+			if parseErr.Line != tc.expectedLine {
+				t.Errorf("Expected error on line %d but got %d (%s).", tc.expectedLine, parseErr.Line, err)
+			}
+			if parseErr.Offset != tc.expectedOffset {
+				t.Errorf("Expected error at offset %d but got %d (%s).", tc.expectedOffset, parseErr.Offset, err)
+			}
+		})
+	}
+}
+
+func TestParserParseExpectedTokens(t *testing.T) {
+	t.Parallel()
+	continuations := []string{"symbol", "literal", "character set", `"("`, `"|"`}
+	tcs := []struct {
+		name             string
+		grammar          string
+		expectedExpected []string
+		expectedFound    string
+	}{
+		{
+			name:             "missing symbol",
+			grammar:          "'one'",
+			expectedExpected: []string{"production number", "symbol"},
+			expectedFound:    `"'"`,
+		},
+		{
+			name:             "missing symbol after production number",
+			grammar:          "[1] 'one'",
+			expectedExpected: []string{"symbol"},
+			expectedFound:    `"'"`,
+		},
+		{
+			name:             "truncated defining symbol",
+			grammar:          "testRule ::",
+			expectedExpected: []string{`"="`},
+			expectedFound:    "end of input",
+		},
+		{
+			name:             "missing expression",
+			grammar:          "testRule ::= |",
+			expectedExpected: []string{"symbol", "literal", "character set", `"("`},
+			expectedFound:    `"|"`,
+		},
+		{
+			name:             "unclosed parenthesis",
+			grammar:          "testRule ::= ('one'",
+			expectedExpected: append(slices.Clone(continuations), `")"`),
+			expectedFound:    "end of input",
+		},
+		{
+			// A closing parenthesis ends the rule, so is reported where the next rule is expected to start.
+			name:             "unexpected closing parenthesis",
+			grammar:          "testRule ::= 'one' )",
+			expectedExpected: []string{"production number", "symbol"},
+			expectedFound:    `")"`,
+		},
+		{
+			name:             "unexpected character in parenthesis",
+			grammar:          "testRule ::= ('one' ;)",
+			expectedExpected: append(slices.Clone(continuations), `"[wfc:"`, `"[vc:"`, `")"`, "end of input"),
+			expectedFound:    `";"`,
+		},
+		{
+			name:             "missing hex digits",
+			grammar:          "testRule ::= #xg",
+			expectedExpected: []string{"hex digit"},
+			expectedFound:    `"g"`,
+		},
+		{
+			name:             "unterminated character set",
+			grammar:          "testRule ::= [a-z",
+			expectedExpected: []string{`"]"`},
+			expectedFound:    "end of input",
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			parser := w3c.New()
+			_, err := parser.Parse(tc.grammar)
+			var parseErr *w3c.ParseError
+			if !errors.As(err, &parseErr) {
+				t.Fatalf("Expected parse error but got %v.", err)
+			}
+			if !slices.Equal(parseErr.Expected, tc.expectedExpected) {
+				t.Errorf("Expected tokens %v to be expected but got %v.", tc.expectedExpected, parseErr.Expected)
+			}
+			if parseErr.Found != tc.expectedFound {
+				t.Errorf("Expected %s to be found but got %s.", tc.expectedFound, parseErr.Found)
+			}
+		})
+	}
+}
+
+func TestParserParseSpans(t *testing.T) {
+	t.Parallel()
+	grammar := "\uFEFF[1] first ::= ('one' | two)* [wfc: x]\r\nsecond ::= [a-z]+ - 'é' #x31\n" +
+		"third ::= ('x' 'y') 'z' | ('w' | 'v')"
+	parser := w3c.New()
+	syntax, err := parser.Parse(grammar)
+	if err != nil {
+		t.Fatalf("Got unexpected error %s", err)
+	}
+	if len(syntax.Rules) != 3 {
+		t.Fatalf("Expected 3 rules but got %d.", len(syntax.Rules))
+	}
+	first := syntax.Rules[0].Expression
+	second := syntax.Rules[1].Expression.ListExpression()
+	third := syntax.Rules[2].Expression
+	tcs := []struct {
+		name     string
+		span     source.Span
+		expected string
+		line     int
+		column   int
+	}{
+		{
+			name:     "first rule",
+			span:     syntax.Rules[0].Span,
+			expected: "[1] first ::= ('one' | two)* [wfc: x]",
+			line:     1,
+			column:   1,
+		},
+		{name: "parenthesised expression", span: first.Span(), expected: "('one' | two)*", line: 1, column: 15},
+		{
+			name:     "repetition operand",
+			span:     first.Repeats()[0].Operand,
+			expected: "('one' | two)",
+			line:     1,
+			column:   15,
+		},
+		{name: "repetition", span: first.Repeats()[0].Span, expected: "('one' | two)*", line: 1, column: 15},
+		{
+			name:     "alternate",
+			span:     first.AlternateExpression().Expressions[1].Span(),
+			expected: "two",
+			line:     1,
+			column:   24,
+		},
+		{name: "constraint", span: syntax.Rules[0].Constraints[0].Span, expected: "[wfc: x]", line: 1, column: 30},
+		{name: "second rule", span: syntax.Rules[1].Span, expected: "second ::= [a-z]+ - 'é' #x31", line: 2, column: 1},
+		{name: "exception", span: second.Expressions[0].Span(), expected: "[a-z]+ - 'é'", line: 2, column: 12},
+		{name: "hex character", span: second.Expressions[1].Span(), expected: "#x31", line: 2, column: 25},
+		{
+			name:     "joined alternate",
+			span:     third.Span(),
+			expected: "('x' 'y') 'z' | ('w' | 'v')",
+			line:     3,
+			column:   11,
+		},
+		{
+			name:     "joined list",
+			span:     third.AlternateExpression().Expressions[0].Span(),
+			expected: "('x' 'y') 'z'",
+			line:     3,
+			column:   11,
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			if actual := grammar[tc.span.Start.Offset:tc.span.End.Offset]; actual != tc.expected {
+				t.Errorf("Expected span to cover %q but got %q.", tc.expected, actual)
+			}
+			if tc.span.Start.Line != tc.line || tc.span.Start.Column != tc.column {
+				t.Errorf(
+					"Expected span to start at %d:%d but got %d:%d.",
+					tc.line,
+					tc.column,
+					tc.span.Start.Line,
+					tc.span.Start.Column,
+				)
+			}
+		})
+	}
+}
+
+func TestParserParseWithRecovery(t *testing.T) {
+	t.Parallel()
+	grammar := `first ::= 'a'
+second ::= 'b' ) 'c'
+/* Before third */
+third ::= 'd' | 'e'
+fourth ::= [a-z
+fifth ::= 'f'`
+	parser := w3c.New(w3c.WithRecovery())
+	syntax, err := parser.Parse(grammar)
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		t.Fatalf("Expected joined errors but got %v.", err)
+	}
+	errs := joined.Unwrap()
+	expectedErrors := []struct {
+		line   int
+		offset int
+	}{
+		{line: 2, offset: 29},
+		{line: 5, offset: 85},
+	}
+	if len(errs) != len(expectedErrors) {
+		t.Fatalf("Expected %d errors but got %d (%v).", len(expectedErrors), len(errs), err)
+	}
+	for i, expected := range expectedErrors {
+		var parseErr *w3c.ParseError
+		if !errors.As(errs[i], &parseErr) {
+			t.Errorf("Expected error %d to be a parse error but got %v.", i+1, errs[i])
+
+			continue
+		}
+		if parseErr.Line != expected.line || parseErr.Offset != expected.offset {
+			t.Errorf(
+				"Expected error %d on line %d at offset %d but got line %d at offset %d.",
+				i+1,
+				expected.line,
+				expected.offset,
+				parseErr.Line,
+				parseErr.Offset,
+			)
+		}
+	}
+	assertSyntaxesEqual(t, w3c.Syntax{Rules: []w3c.Rule{
+		{Symbol: "first", Line: 1, Expression: &w3c.LiteralExpression{Literal: "a"}},
+		{Symbol: "second", Line: 2, Expression: &w3c.LiteralExpression{Literal: "b"}},
+		{
+			Symbol:   "third",
+			Line:     4,
+			Comments: []string{"Before third"},
+			Expression: &w3c.AlternateExpression{Expressions: []w3c.Expression{
+				&w3c.LiteralExpression{Literal: "d"},
+				&w3c.LiteralExpression{Literal: "e"},
+			}},
+		},
+		{Symbol: "fifth", Line: 6, Expression: &w3c.LiteralExpression{Literal: "f"}},
+	}}, syntax)
+}
+
+func TestParserParseWithRecoveryAtRuleStart(t *testing.T) {
+	t.Parallel()
+	// The unclosed parenthesis is reported at the start of the following rule, which is kept.
+	grammar := "a ::= b\nc ::= ( d\ne ::= f\ng ::= 'h\ni ::= j"
+	syntax, err := w3c.New(w3c.WithRecovery()).Parse(grammar)
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok || len(joined.Unwrap()) != 2 {
+		t.Fatalf("Expected 2 joined errors but got %v.", err)
+	}
+	assertSyntaxesEqual(t, w3c.Syntax{Rules: []w3c.Rule{
+		{Symbol: "a", Line: 1, Expression: &w3c.SymbolExpression{Symbol: "b"}},
+		{Symbol: "e", Line: 3, Expression: &w3c.SymbolExpression{Symbol: "f"}},
+		{Symbol: "i", Line: 5, Expression: &w3c.SymbolExpression{Symbol: "j"}},
+	}}, syntax)
+}
+
+func TestParserParseFile(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{
+		"grammars/digits.ebnf": {Data: []byte("digit ::= [0-9]\nnumber ::= digit+\n")},
+		"grammars/broken.ebnf": {Data: []byte("digit ::= [0-9]\nnumber ::= |\n")},
+	}
+	t.Run("positions", func(t *testing.T) {
+		t.Parallel()
+		syntax, err := w3c.New().ParseFile(fsys, "grammars/digits.ebnf")
+		if err != nil {
+			t.Fatalf("Got unexpected error %s.", err)
+		}
+		for _, span := range []source.Span{syntax.Rules[0].Span, syntax.Rules[1].Expression.Span()} {
+			if span.Start.Filename != "grammars/digits.ebnf" || span.End.Filename != "grammars/digits.ebnf" {
+				t.Errorf("Expected span to be in file %q. Got %+v.", "grammars/digits.ebnf", span)
+			}
+		}
+	})
+	t.Run("parse error", func(t *testing.T) {
+		t.Parallel()
+		_, err := w3c.New().ParseFile(fsys, "grammars/broken.ebnf")
+		var parseErr *w3c.ParseError
+		if !errors.As(err, &parseErr) {
+			t.Fatalf("Expected a parse error. Got %v.", err)
+		}
+		if parseErr.Filename != "grammars/broken.ebnf" {
+			t.Errorf("Expected parse error in file %q. Got %q.", "grammars/broken.ebnf", parseErr.Filename)
+		}
+		if !strings.HasPrefix(parseErr.Error(), "grammars/broken.ebnf: ") {
+			t.Errorf("Expected parse error message to start with the file name. Got %q.", parseErr.Error())
+		}
+		expectedPosition := source.Position{Filename: "grammars/broken.ebnf", Offset: 27, Line: 2, Column: 12}
+		if actual := parseErr.Diagnostic().Position; actual != expectedPosition {
+			t.Errorf("Expected diagnostic at %#v. Got %#v.", expectedPosition, actual)
+		}
+	})
+	t.Run("missing file", func(t *testing.T) {
+		t.Parallel()
+		_, err := w3c.New().ParseFile(fsys, "grammars/missing.ebnf")
+		var readErr *w3c.ReadError
+		if !errors.As(err, &readErr) {
+			t.Fatalf("Expected a read error. Got %v.", err)
+		}
+		if readErr.Filename != "grammars/missing.ebnf" {
+			t.Errorf("Expected read error for file %q. Got %q.", "grammars/missing.ebnf", readErr.Filename)
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("Expected read error to wrap %s. Got %s.", fs.ErrNotExist, err)
+		}
+	})
+}
+
+func TestParserParseReader(t *testing.T) {
+	t.Parallel()
+	t.Run("positions", func(t *testing.T) {
+		t.Parallel()
+		syntax, err := w3c.New().ParseReader("digits.ebnf", strings.NewReader("digit ::= [0-9]"))
+		if err != nil {
+			t.Fatalf("Got unexpected error %s.", err)
+		}
+		expected := source.Position{Filename: "digits.ebnf", Offset: 0, Line: 1, Column: 1}
+		if actual := syntax.Rules[0].Span.Start; actual != expected {
+			t.Errorf("Expected rule to start at %#v. Got %#v.", expected, actual)
+		}
+	})
+	t.Run("read error", func(t *testing.T) {
+		t.Parallel()
+		readErr := errors.New("read failed")
+		_, err := w3c.New().ParseReader("digits.ebnf", iotest.ErrReader(readErr))
+		var actual *w3c.ReadError
+		if !errors.As(err, &actual) {
+			t.Fatalf("Expected a read error. Got %v.", err)
+		}
+		if actual.Filename != "digits.ebnf" {
+			t.Errorf("Expected read error for file %q. Got %q.", "digits.ebnf", actual.Filename)
+		}
+		if !errors.Is(err, readErr) {
+			t.Errorf("Expected read error to wrap %s. Got %s.", readErr, err)
+		}
+	})
+}
+
+func TestParserParseLimits(t *testing.T) {
+	t.Parallel()
+	deep := limit.DefaultMaxDepth + 1
+	tcs := []struct {
+		name           string
+		grammar        string
+		limits         limit.Limits
+		expectedCode   diag.Code
+		expectedOffset int
+		expectedCause  error
+	}{
+		{
+			name:           "default depth",
+			grammar:        "a ::= " + strings.Repeat("(", deep) + "'b'" + strings.Repeat(")", deep),
+			limits:         limit.Default(),
+			expectedCode:   diag.CodeDepthLimitExceeded,
+			expectedOffset: 6 + limit.DefaultMaxDepth,
+			expectedCause:  &limit.DepthError{Max: limit.DefaultMaxDepth},
+		},
+		{
+			name:           "depth",
+			grammar:        "a ::= ('b' ('c' ('d')))",
+			limits:         limit.Limits{MaxDepth: 2},
+			expectedCode:   diag.CodeDepthLimitExceeded,
+			expectedOffset: 16,
+			expectedCause:  &limit.DepthError{Max: 2},
+		},
+		{
+			name:           "literal length",
+			grammar:        "a ::= 'bcd' | \"bcde\"",
+			limits:         limit.Limits{MaxLiteralLength: 3},
+			expectedCode:   diag.CodeLiteralLengthLimitExceeded,
+			expectedOffset: 14,
+			expectedCause:  &limit.LiteralLengthError{Max: 3, Length: 4},
+		},
+		{
+			name:           "rule count",
+			grammar:        "a ::= 'a'\nb ::= 'b'\n/* c */ c ::= 'c'",
+			limits:         limit.Limits{MaxRules: 2},
+			expectedCode:   diag.CodeRuleLimitExceeded,
+			expectedOffset: 28,
+			expectedCause:  &limit.RuleCountError{Max: 2},
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := w3c.New(w3c.WithLimits(tc.limits)).Parse(tc.grammar)
+			if !errors.Is(err, tc.expectedCode) {
+				t.Fatalf("Expected error with code %s. Got %v.", tc.expectedCode, err)
+			}
+			var parseErr *w3c.ParseError
+			if !errors.As(err, &parseErr) {
+				t.Fatalf("Expected a parse error. Got %v.", err)
+			}
+			if parseErr.Offset != tc.expectedOffset {
+				t.Errorf("Expected error at offset %d. Got %d.", tc.expectedOffset, parseErr.Offset)
+			}
+			if cause := parseErr.Unwrap(); cause == nil || cause.Error() != tc.expectedCause.Error() {
+				t.Errorf("Expected error caused by %q. Got %v.", tc.expectedCause, cause)
+			}
+		})
+	}
+}
+
+func TestParserParseLimitsStopRecovery(t *testing.T) {
+	t.Parallel()
+	parser := w3c.New(w3c.WithRecovery(), w3c.WithLimits(limit.Limits{MaxRules: 2, MaxLiteralLength: 1}))
+	syntax, err := parser.Parse("a ::= 'aa'\nb ::= 'b'\nc ::= 'c'\nd ::= 'd'")
+	if len(syntax.Rules) != 1 || syntax.Rules[0].Symbol != "b" {
+		t.Errorf("Expected only rule b to be parsed. Got %v.", syntax.Rules)
+	}
+	if len(diag.Diagnostics(err)) != 2 {
+		t.Errorf("Expected 2 errors. Got %v.", err)
+	}
+	var ruleCountErr *limit.RuleCountError
+	if !errors.As(err, &ruleCountErr) {
+		t.Errorf("Expected rule count error. Got %v.", err)
+	}
+}
+
+func TestParserParseInputSizeLimit(t *testing.T) {
+	t.Parallel()
+	for name, parse := range map[string]func(parser *w3c.Parser) error{
+		"parse": func(parser *w3c.Parser) error {
+			_, err := parser.Parse("a ::= 'bc'")
+
+			return err
+		},
+		"parse reader": func(parser *w3c.Parser) error {
+			_, err := parser.ParseReader("a.ebnf", strings.NewReader("a ::= 'bc'"))
+
+			return err
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			var inputSizeErr *limit.InputSizeError
+			if err := parse(w3c.New(w3c.WithLimits(limit.Limits{MaxInputSize: 8}))); !errors.As(err, &inputSizeErr) {
+				t.Fatalf("Expected input size error. Got %v.", err)
+			}
+			if inputSizeErr.Max != 8 {
+				t.Errorf("Expected limit of 8 bytes. Got %d.", inputSizeErr.Max)
+			}
+			if !errors.Is(inputSizeErr, diag.CodeInputSizeLimitExceeded) {
+				t.Errorf("Expected error with code %s. Got %v.", diag.CodeInputSizeLimitExceeded, inputSizeErr)
+			}
+		})
+	}
+}
+
+func TestParserParseContext(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	_, err := w3c.New().ParseContext(ctx, "a ::= 'a'")
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected error to wrap %s. Got %v.", context.Canceled, err)
+	}
+	if !errors.Is(err, diag.CodeCancelled) {
+		t.Errorf("Expected error with code %s. Got %v.", diag.CodeCancelled, err)
+	}
+}
+
+func TestParserParseStrict(t *testing.T) {
+	t.Parallel()
+	grammar := "a ::= [http://www.w3.org/TR/xml/#NT-Char] - 'b'"
+	if _, err := w3c.New().Parse(grammar); err != nil {
+		t.Fatalf("Expected no error parsing without strict but got %s.", err)
+	}
+	_, err := w3c.New(w3c.WithStrict()).Parse(grammar)
+	var parseErr *w3c.ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("Expected a parse error. Got %v.", err)
+	}
+	if parseErr.Code != diag.CodeNonStandardSyntax {
+		t.Errorf("Expected error with code %s. Got %s.", diag.CodeNonStandardSyntax, parseErr.Code)
+	}
+	if parseErr.Offset != 6 {
+		t.Errorf("Expected error at offset 6. Got %d.", parseErr.Offset)
+	}
+	if parseErr.Symbol != "a" {
+		t.Errorf("Expected error in rule %q. Got %q.", "a", parseErr.Symbol)
+	}
+}
+
+func TestParserParseWithFilename(t *testing.T) {
+	t.Parallel()
+	parser := w3c.New(w3c.WithFilename("grammar.ebnf"))
+	syntax, err := parser.Parse("a ::= 'a'")
+	if err != nil {
+		t.Fatalf("Expected no error but got %s.", err)
+	}
+	if filename := syntax.Rules[0].Span.Start.Filename; filename != "grammar.ebnf" {
+		t.Errorf("Expected file name %q. Got %q.", "grammar.ebnf", filename)
+	}
+	_, err = parser.ParseContext(t.Context(), "a ::= 'a")
+	var parseErr *w3c.ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("Expected a parse error. Got %v.", err)
+	}
+	if parseErr.Filename != "grammar.ebnf" {
+		t.Errorf("Expected file name %q. Got %q.", "grammar.ebnf", parseErr.Filename)
+	}
+	syntax, err = parser.ParseReader("other.ebnf", strings.NewReader("a ::= 'a'"))
+	if err != nil {
+		t.Fatalf("Expected no error but got %s.", err)
+	}
+	if filename := syntax.Rules[0].Span.Start.Filename; filename != "other.ebnf" {
+		t.Errorf("Expected file name %q. Got %q.", "other.ebnf", filename)
+	}
+}
+
+// TestParserConcurrentUse shares a Parser between goroutines, which should be run with the race detector enabled.
+func TestParserConcurrentUse(t *testing.T) {
+	t.Parallel()
+	grammars := []string{
+		"a ::= 'a'",
+		"/* comment */ [1] a ::= (b | c)+ /* trailing */\n[2] b ::= [^#x20] [ wfc: Constraint ]\n[3] c ::= 'c'?",
+		"a ::= ( 'a'\nb ::= 'b'",
+		"a ::= 'a' b ::=",
+		"a ::= [http://www.w3.org/TR/xml/#NT-Char] - 'b' /* unterminated",
+	}
+	parser := w3c.New(w3c.WithRecovery(), w3c.WithLimits(limit.Limits{MaxRules: 3}))
+	type result struct {
+		syntax string
+		err    string
+	}
+	parse := func(parser *w3c.Parser, grammar string) result {
+		syntax, err := parser.Parse(grammar)
+		actual, marshalErr := json.Marshal(syntax)
+		if marshalErr != nil {
+			t.Errorf("Expected no error marshalling syntax but got %s.", marshalErr)
+		}
+		res := result{syntax: string(actual)}
+		if err != nil {
+			res.err = err.Error()
+		}
+
+		return res
+	}
+	expected := make([]result, len(grammars))
+	for i, grammar := range grammars {
+		expected[i] = parse(w3c.New(w3c.WithRecovery(), w3c.WithLimits(limit.Limits{MaxRules: 3})), grammar)
+	}
+	const goroutines = 8
+	var wg sync.WaitGroup
+	for range goroutines {
+		wg.Go(func() {
+			for i, grammar := range grammars {
+				if actual := parse(parser, grammar); actual != expected[i] {
+					t.Errorf("Expected result %+v parsing %q. Got %+v.", expected[i], grammar, actual)
+				}
+			}
+		})
+	}
+	wg.Wait()
+}
+
+var update = flag.Bool("update", false, "update the golden files of the corpus tests")
+
+func TestParserParseCorpus(t *testing.T) {
+	t.Parallel()
+	grammars, err := filepath.Glob(filepath.Join("testdata", "corpus", "*.ebnf"))
+	if err != nil {
+		t.Fatalf("Expected no error finding corpus grammars but got %s.", err)
+	}
+	if len(grammars) == 0 {
+		t.Fatal("Expected corpus grammars but found none.")
+	}
+	for _, grammar := range grammars {
+		t.Run(filepath.Base(grammar), func(t *testing.T) {
+			t.Parallel()
+			content, err := os.ReadFile(grammar)
+			if err != nil {
+				t.Fatalf("Expected no error reading grammar but got %s.", err)
+			}
+			parser := w3c.New()
+			syntax, err := parser.Parse(string(content))
+			if err != nil {
+				t.Fatalf("Expected no error parsing grammar but got %s.", err)
+			}
+			actual, err := json.MarshalIndent(syntax, "", "  ")
+			if err != nil {
+				t.Fatalf("Expected no error marshalling syntax but got %s.", err)
+			}
+			actual = append(actual, '\n')
+			golden := strings.TrimSuffix(grammar, ".ebnf") + ".json"
+			if *update {
+				if err := os.WriteFile(golden, actual, 0o600); err != nil {
+					t.Fatalf("Expected no error updating golden file but got %s.", err)
+				}
+			}
+			expected, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("Expected no error reading golden file but got %s.", err)
+			}
+			if !bytes.Equal(expected, actual) {
+				t.Errorf("Syntax of %s does not match %s, run the tests with -update to regenerate it.", grammar, golden)
+			}
+		})
+	}
+}
+
+func FuzzParserParse(f *testing.F) {
+	for _, seed := range []string{
+		"testRule ::= 'word'",
+		"testRule ::= 'one' | 'two' 'three'",
+		"testRule ::= ('one' | 'two')* - 'three'",
+		"testRule ::= [^#x20a-z] #x31",
+		"[1] testRule ::= 'one' /* comment */ [ wfc: Constraint ]",
+		"testRule ::= [",
+		"testRule ::= '",
+	} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, grammar string) {
+		for _, parser := range []*w3c.Parser{w3c.New(), w3c.New(w3c.WithRecovery())} {
+			_, err := parser.Parse(grammar)
+			if err == nil {
+				continue
+			}
+			var parseErr *w3c.ParseError
+			if !errors.As(err, &parseErr) {
+				t.Fatalf("Expected parse error but got %v.", err)
+			}
+			if parseErr.Offset < 0 || parseErr.Offset > len(grammar) {
+				t.Errorf("Error offset %d is outside of the grammar (length %d).", parseErr.Offset, len(grammar))
+			}
+			if parseErr.Line < 1 || parseErr.Line > strings.Count(grammar, "\n")+1 {
+				t.Errorf("Error line %d is outside of the grammar.", parseErr.Line)
+			}
+		}
+	})
+}
+
+func BenchmarkParserParseLongLine(b *testing.B) {
+	grammar := "a ::= b" + strings.Repeat(" | b", 80000)
+	parser := w3c.New()
+	for b.Loop() {
+		if _, err := parser.Parse(grammar); err != nil {
+			b.Fatalf("Expected no error but got %s.", err)
+		}
+	}
+}
@@ -0,0 +1,361 @@
+package w3c
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// DefaultLineWidth is the line width a Printer keeps rules within unless configured otherwise.
+const DefaultLineWidth = 80
+
+// Printer prints a Syntax as a grammar in the W3C notation, such that parsing the printed grammar gives the same Syntax
+// (apart from the positions of its expressions).
+//
+// Each rule is printed on its own line, preceded by its comments on their own lines and followed by its constraints.
+// Parentheses are only printed where they are needed for the grammar to parse as the same Syntax, i.e. around an
+// alternate or list within a list, an alternate within an alternate, an expression with repetitions that is not a
+// single symbol, literal, character set or external reference, and an exception within an exception or (if its
+// exception is parenthesised) within a list or alternate. A rule that is wider than the line width and is an
+// alternate is broken with each alternative on its own line.
+//
+// A Printer is safe for concurrent use by multiple goroutines.
+type Printer struct {
+	lineWidth int
+	align     bool
+}
+
+// PrinterOption configures a Printer.
+type PrinterOption func(*Printer)
+
+// WithLineWidth configures the width (in characters) a Printer tries to keep lines within, which is DefaultLineWidth
+// unless configured otherwise. A width of 0 means that rules are never broken across lines.
+func WithLineWidth(width int) PrinterOption {
+	return func(p *Printer) {
+		p.lineWidth = width
+	}
+}
+
+// WithAlignedDefiningSymbols configures a Printer to pad the production numbers and symbols of rules so that the
+// symbols and the defining symbols ("::=") of every rule are aligned.
+func WithAlignedDefiningSymbols() PrinterOption {
+	return func(p *Printer) {
+		p.align = true
+	}
+}
+
+// NewPrinter instantiates a Printer.
+func NewPrinter(opts ...PrinterOption) *Printer {
+	printer := &Printer{lineWidth: DefaultLineWidth}
+	for _, opt := range opts {
+		opt(printer)
+	}
+
+	return printer
+}
+
+// Print writes the syntax to w as a grammar in the W3C notation.
+func (p *Printer) Print(w io.Writer, syntax Syntax) error {
+	if _, err := io.WriteString(w, p.Format(syntax)); err != nil {
+		return NewPrintError(err)
+	}
+
+	return nil
+}
+
+// Format returns the syntax as a grammar in the W3C notation.
+func (p *Printer) Format(syntax Syntax) string {
+	numberWidth, symbolWidth := 0, 0
+	if p.align {
+		for _, rule := range syntax.Rules {
+			numberWidth = max(numberWidth, utf8.RuneCountInString(formatNumber(rule.Number)))
+			symbolWidth = max(symbolWidth, utf8.RuneCountInString(rule.Symbol))
+		}
+	}
+	out := new(strings.Builder)
+	for _, rule := range syntax.Rules {
+		for _, comment := range rule.Comments {
+			out.WriteString(formatComment(comment) + "\n")
+		}
+		for _, line := range p.formatRule(rule, numberWidth, symbolWidth) {
+			out.WriteString(line + "\n")
+		}
+	}
+	for _, comment := range syntax.TrailingComments {
+		out.WriteString(formatComment(comment) + "\n")
+	}
+
+	return out.String()
+}
+
+// formatRule returns the lines of a rule, with its production number and symbol padded to the given widths.
+func (p *Printer) formatRule(rule Rule, numberWidth, symbolWidth int) []string {
+	head := ""
+	if number := formatNumber(rule.Number); number != "" || numberWidth > 0 {
+		head = pad(number, numberWidth) + " "
+	}
+	head += pad(rule.Symbol, symbolWidth) + " ::= "
+	constraints := ""
+	for _, constraint := range rule.Constraints {
+		constraints += " [ " + string(constraint.Kind) + ": " + constraint.Name + " ]"
+	}
+	line := head + formatExpression(rule.Expression) + constraints
+	alternate := rule.Expression.AlternateExpression()
+	if p.fits(line) || alternate == nil || alternate.hasRepetitions() {
+		return []string{line}
+	}
+	// The alternatives are aligned below the start of the expression, with the separators below the defining symbol.
+	separator := strings.Repeat(" ", utf8.RuneCountInString(head)-len("| ")) + "| "
+	lines := make([]string, 0, len(alternate.Expressions))
+	for i, expression := range alternate.Expressions {
+		prefix := head
+		if i > 0 {
+			prefix = separator
+		}
+		lines = append(lines, prefix+formatAlternative(expression))
+	}
+	lines[len(lines)-1] += constraints
+
+	return lines
+}
+
+// fits checks whether a line is within the line width.
+func (p *Printer) fits(line string) bool {
+	return p.lineWidth <= 0 || utf8.RuneCountInString(line) <= p.lineWidth
+}
+
+// formatExpression returns an expression, including its repetitions.
+func formatExpression(expression Expression) string {
+	var out string
+	switch {
+	case expression.ListExpression() != nil:
+		list := expression.ListExpression()
+		items := make([]string, 0, len(list.Expressions))
+		for _, item := range list.Expressions {
+			formatted := formatExpression(item)
+			if item.ListExpression() != nil || item.AlternateExpression() != nil || hasParenthesisedException(item) {
+				if !item.hasRepetitions() {
+					formatted = "(" + formatted + ")"
+				}
+			}
+			items = append(items, formatted)
+		}
+		out = strings.Join(items, " ")
+	case expression.AlternateExpression() != nil:
+		alternate := expression.AlternateExpression()
+		alternatives := make([]string, 0, len(alternate.Expressions))
+		for _, alternative := range alternate.Expressions {
+			alternatives = append(alternatives, formatAlternative(alternative))
+		}
+		out = strings.Join(alternatives, " | ")
+	case expression.ExceptionExpression() != nil:
+		exception := expression.ExceptionExpression()
+		match := formatExpression(exception.Match)
+		if isCompound(exception.Match) && !exception.Match.hasRepetitions() {
+			match = "(" + match + ")"
+		}
+		except := formatExpression(exception.Except)
+		if isCompound(exception.Except) && !exception.Except.hasRepetitions() {
+			except = "(" + except + ")"
+		}
+		out = match + " - " + except
+	case expression.SymbolExpression() != nil:
+		out = expression.SymbolExpression().Symbol
+	case expression.LiteralExpression() != nil:
+		out = formatLiteral(expression.LiteralExpression().Literal)
+	case expression.CharacterSetExpression() != nil:
+		out = formatCharacterSet(expression.CharacterSetExpression())
+	case expression.ExternalReferenceExpression() != nil:
+		out = "[" + expression.ExternalReferenceExpression().URI + "]"
+	}
+
+	return formatRepetitions(expression, out)
+}
+
+// formatAlternative returns an alternative of an alternate expression.
+func formatAlternative(alternative Expression) string {
+	formatted := formatExpression(alternative)
+	if !alternative.hasRepetitions() &&
+		(alternative.AlternateExpression() != nil || hasParenthesisedException(alternative)) {
+		return "(" + formatted + ")"
+	}
+
+	return formatted
+}
+
+// formatRepetitions returns the formatted expression followed by its repetition symbols.
+//
+// An expression is only expected to have one kind of repetitions, but one parsed from an expression with repetitions
+// within parentheses with repetitions (e.g. (A?)*) has both, so each is applied in turn.
+func formatRepetitions(expression Expression, formatted string) string {
+	if !expression.hasRepetitions() {
+		return formatted
+	}
+	if isCompound(expression) {
+		formatted = "(" + formatted + ")"
+	}
+	applied := false
+	for _, repetition := range []struct {
+		set    bool
+		symbol string
+	}{
+		{set: expression.Optional(), symbol: "?"},
+		{set: expression.ZeroOrMore(), symbol: "*"},
+		{set: expression.OneOrMore(), symbol: "+"},
+	} {
+		if !repetition.set {
+			continue
+		}
+		if applied {
+			formatted = "(" + formatted + ")"
+		}
+		formatted += repetition.symbol
+		applied = true
+	}
+
+	return formatted
+}
+
+// isCompound checks whether an expression is made up of other expressions.
+func isCompound(expression Expression) bool {
+	return expression.ListExpression() != nil || expression.AlternateExpression() != nil ||
+		expression.ExceptionExpression() != nil
+}
+
+// hasParenthesisedException checks whether an expression is an exception with its exception printed in parentheses.
+//
+// Everything after a parenthesised exception is parsed as part of it, so such an exception must itself be parenthesised
+// within a list or alternate.
+func hasParenthesisedException(expression Expression) bool {
+	exception := expression.ExceptionExpression()
+	if exception == nil || expression.hasRepetitions() {
+		return false
+	}
+	except := exception.Except
+
+	// An expression with more than one kind of repetitions is printed with parentheses for each but the last.
+	return isCompound(except) || countRepetitions(except) > 1
+}
+
+// countRepetitions returns the number of kinds of repetitions of an expression.
+func countRepetitions(expression Expression) int {
+	count := 0
+	for _, set := range []bool{expression.Optional(), expression.ZeroOrMore(), expression.OneOrMore()} {
+		if set {
+			count++
+		}
+	}
+
+	return count
+}
+
+// formatLiteral returns a literal wrapped in quotes, which are double quotes if it contains a single quote.
+func formatLiteral(literal string) string {
+	if strings.Contains(literal, "'") {
+		return `"` + literal + `"`
+	}
+
+	return "'" + literal + "'"
+}
+
+// formatCharacterSet returns a character set, with its enumerations followed by its ranges.
+//
+// A set of a single character that is printed as a hex character (such as #x9) is printed without brackets, as it is
+// usually written, while other single characters (such as [a]) keep their brackets. A surrogate keeps its brackets too,
+// as it is only allowed within a character set.
+func formatCharacterSet(set *CharacterSetExpression) string {
+	if !set.Forbidden && len(set.Ranges) == 0 && len(set.Enumerations) == 1 {
+		char := set.Enumerations[0]
+		if formatCharacter(char, false) != string(char) && !utf16.IsSurrogate(char) {
+			return formatHexCharacter(char)
+		}
+	}
+	formatted := formatCharacters(set, false)
+	// A set that looks like a production number, constraint or external reference would be parsed as one, which is
+	// avoided by printing its first character as a hex character.
+	probe := &parser{source: formatted}
+	if _, ok := probe.parseProductionNumber(); ok || probe.isConstraintStart() || probe.isExternalReferenceStart() {
+		return formatCharacters(set, true)
+	}
+
+	return formatted
+}
+
+// formatCharacters returns the characters of a character set wrapped in brackets, printing the first as a hex character
+// if hexFirst is set.
+func formatCharacters(set *CharacterSetExpression, hexFirst bool) string {
+	out := new(strings.Builder)
+	out.WriteString("[")
+	if set.Forbidden {
+		out.WriteString("^")
+	}
+	first, afterHex := true, false
+	format := func(char rune) string {
+		formatted := formatCharacter(char, first && !set.Forbidden)
+		// A hex digit following a hex character would be parsed as part of it.
+		if first && hexFirst || afterHex && formatted == string(char) && isHexDigit(char) {
+			formatted = formatHexCharacter(char)
+		}
+		first, afterHex = false, formatted != string(char)
+
+		return formatted
+	}
+	for _, char := range set.Enumerations {
+		out.WriteString(format(char))
+	}
+	for _, r := range set.Ranges {
+		out.WriteString(format(r.Low) + "-" + format(r.High))
+	}
+	out.WriteString("]")
+
+	return out.String()
+}
+
+// formatCharacter returns a character of a character set, as a hex character if it is not a printable ASCII character
+// (so that the set is readable whatever the character, such as a combining mark or private use character) or would
+// otherwise be parsed as part of the syntax of the set, i.e. "]", "-", "#", space, and "^" if it is the first character
+// of the set.
+func formatCharacter(char rune, first bool) string {
+	if char <= ' ' || char > '~' || strings.ContainsRune("]-#", char) || first && char == '^' {
+		return formatHexCharacter(char)
+	}
+
+	return string(char)
+}
+
+// isHexDigit returns whether the character is a hexadecimal digit.
+func isHexDigit(char rune) bool {
+	_, ok := hexDigit(char)
+
+	return ok
+}
+
+// formatHexCharacter returns a character as a hex character (#xN).
+func formatHexCharacter(char rune) string {
+	return fmt.Sprintf("#x%X", char)
+}
+
+// formatNumber returns a production number wrapped in brackets, or nothing if there is none.
+func formatNumber(number string) string {
+	if number == "" {
+		return ""
+	}
+
+	return "[" + number + "]"
+}
+
+// formatComment returns a comment wrapped in comment symbols.
+func formatComment(comment string) string {
+	if comment == "" {
+		return "/* */"
+	}
+
+	return "/* " + comment + " */"
+}
+
+// pad returns the text followed by spaces to make it the given width.
+func pad(text string, width int) string {
+	return text + strings.Repeat(" ", max(0, width-utf8.RuneCountInString(text)))
+}
@@ -0,0 +1,237 @@
+package w3c_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alec-w/ebnf-go/w3c"
+)
+
+func TestPrinterFormat(t *testing.T) {
+	t.Parallel()
+	testCases := map[string]struct {
+		grammar  string
+		opts     []w3c.PrinterOption
+		expected string
+	}{
+		"literals": {
+			grammar:  `a ::= "a" | "'" | '"'`,
+			expected: "a ::= 'a' | \"'\" | '\"'\n",
+		},
+		"redundant parentheses": {
+			grammar:  "a ::= (b) ((c | d)) (e - f) | ((g h))",
+			expected: "a ::= b (c | d) e - f | g h\n",
+		},
+		"parenthesised lists": {
+			grammar:  "a ::= b (c d) e ((f g))",
+			expected: "a ::= b c d e f g\n",
+		},
+		"repetitions": {
+			grammar:  "a ::= b? (c d)* (e | f)+ ((g)?)* (h - i)?",
+			expected: "a ::= b? (c d)* (e | f)+ (g?)* (h - i)?\n",
+		},
+		"exceptions": {
+			grammar:  "a ::= (b c) - d | (e - (f | g)) | (h - i) - j",
+			expected: "a ::= (b c) - d | (e - (f | g)) | (h - i) - j\n",
+		},
+		"exception within a list": {
+			grammar:  "a ::= (b - (c d)) e f - g",
+			expected: "a ::= (b - (c d)) e f - g\n",
+		},
+		"character sets": {
+			grammar:  "a ::= [^a-z#x20#x2D] | [#x5E^] | [#x5D-#x10FFFF] | #x9 | [#x23x] | [#xD800-#xDFFF]",
+			opts:     []w3c.PrinterOption{w3c.WithLineWidth(0)},
+			expected: "a ::= [^#x20#x2D#x61-z] | [#x5E^] | [#x5D-#x10FFFF] | #x9 | [#x23x] | [#xD800-#xDFFF]\n",
+		},
+		"single characters": {
+			grammar:  "a ::= #x9* [a] [#x20] [^#xA] #xA - [#xD] [#xD800]",
+			expected: "a ::= #x9* [a] #x20 [^#xA] #xA - #xD [#xD800]\n",
+		},
+		"non-ASCII characters": {
+			grammar:  "a ::= [é#x300] [#xE000-#xFFFD] [#x0300-#x036F]",
+			expected: "a ::= [#xE9#x300] [#xE000-#xFFFD] [#x300-#x36F]\n",
+		},
+		"character sets that look like other syntax": {
+			grammar:  "a ::= [#x31] [#x77#x66#x63:] [#x68ttp://]",
+			expected: "a ::= [#x31] [#x77#x66#x63:] [#x68ttp://]\n",
+		},
+		"external references": {
+			grammar:  "a ::= [http://www.w3.org/TR/xml/#NT-Char]+",
+			expected: "a ::= [http://www.w3.org/TR/xml/#NT-Char]+\n",
+		},
+		"numbers, constraints and comments": {
+			grammar: "/* first */ [1] a ::= b /* second */ [ WFC: Some Name ] [vc:Other]\n" +
+				"/* third */\n[22b] cd ::= 'e'\n/* trailing */",
+			expected: "/* first */\n/* second */\n[1] a ::= b [ wfc: Some Name ] [ vc: Other ]\n" +
+				"/* third */\n[22b] cd ::= 'e'\n/* trailing */\n",
+		},
+		"aligned defining symbols": {
+			grammar:  "[1] a ::= b\n[10] long ::= c\nd ::= e",
+			opts:     []w3c.PrinterOption{w3c.WithAlignedDefiningSymbols()},
+			expected: "[1]  a    ::= b\n[10] long ::= c\n     d    ::= e\n",
+		},
+		"broken alternatives": {
+			grammar:  "rule ::= 'first' | 'second' 'third' | 'fourth' [ vc: Constraint ]",
+			opts:     []w3c.PrinterOption{w3c.WithLineWidth(30)},
+			expected: "rule ::= 'first'\n       | 'second' 'third'\n       | 'fourth' [ vc: Constraint ]\n",
+		},
+		"unlimited line width": {
+			grammar:  "rule ::= 'first' | 'second' 'third' | 'fourth'",
+			opts:     []w3c.PrinterOption{w3c.WithLineWidth(0)},
+			expected: "rule ::= 'first' | 'second' 'third' | 'fourth'\n",
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			syntax, err := w3c.New().Parse(tc.grammar)
+			if err != nil {
+				t.Fatalf("Expected no error but got %s.", err)
+			}
+			if actual := w3c.NewPrinter(tc.opts...).Format(syntax); actual != tc.expected {
+				t.Errorf("Expected grammar:\n%s\nGot:\n%s", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestPrinterPrint(t *testing.T) {
+	t.Parallel()
+	syntax, err := w3c.New().Parse("a ::= 'a'")
+	if err != nil {
+		t.Fatalf("Expected no error but got %s.", err)
+	}
+	out := new(bytes.Buffer)
+	if err := w3c.NewPrinter().Print(out, syntax); err != nil {
+		t.Fatalf("Expected no error but got %s.", err)
+	}
+	if out.String() != "a ::= 'a'\n" {
+		t.Errorf("Expected grammar %q. Got %q.", "a ::= 'a'\n", out.String())
+	}
+	writeErr := errors.New("write failed")
+	err = w3c.NewPrinter().Print(errorWriter{err: writeErr}, syntax)
+	var printErr *w3c.PrintError
+	if !errors.As(err, &printErr) {
+		t.Fatalf("Expected a print error. Got %v.", err)
+	}
+	if !errors.Is(err, writeErr) {
+		t.Errorf("Expected error to wrap %s. Got %s.", writeErr, err)
+	}
+}
+
+// errorWriter is an io.Writer that always fails.
+type errorWriter struct {
+	err error
+}
+
+func (e errorWriter) Write([]byte) (int, error) {
+	return 0, e.err
+}
+
+func TestPrinterRoundTrip(t *testing.T) {
+	t.Parallel()
+	grammars, err := filepath.Glob(filepath.Join("testdata", "corpus", "*.ebnf"))
+	if err != nil {
+		t.Fatalf("Expected no error finding corpus grammars but got %s.", err)
+	}
+	for _, grammar := range grammars {
+		content, err := os.ReadFile(grammar)
+		if err != nil {
+			t.Fatalf("Expected no error reading grammar but got %s.", err)
+		}
+		for name, printer := range map[string]*w3c.Printer{
+			"default": w3c.NewPrinter(),
+			"aligned": w3c.NewPrinter(w3c.WithAlignedDefiningSymbols(), w3c.WithLineWidth(40)),
+		} {
+			t.Run(filepath.Base(grammar)+"/"+name, func(t *testing.T) {
+				t.Parallel()
+				assertRoundTrip(t, printer, string(content))
+			})
+		}
+	}
+}
+
+// assertRoundTrip checks that printing the syntax of a grammar and parsing it again gives the same syntax, and that
+// printing that gives the same grammar again.
+func assertRoundTrip(t *testing.T, printer *w3c.Printer, grammar string) {
+	t.Helper()
+	syntax, err := w3c.New().Parse(grammar)
+	if err != nil {
+		return
+	}
+	printed := printer.Format(syntax)
+	reparsed, err := w3c.New().Parse(printed)
+	if err != nil {
+		t.Fatalf("Expected no error parsing printed grammar but got %s. Printed:\n%s", err, printed)
+	}
+	if expected, actual := marshalWithoutLines(t, syntax), marshalWithoutLines(t, reparsed); expected != actual {
+		t.Errorf("Expected printed grammar to parse as:\n%s\nGot:\n%s\nPrinted:\n%s", expected, actual, printed)
+	}
+	if reprinted := printer.Format(reparsed); reprinted != printed {
+		t.Errorf("Expected printing to be stable. Printed:\n%s\nReprinted:\n%s", printed, reprinted)
+	}
+}
+
+// marshalWithoutLines returns the syntax as JSON without the lines of its rules, which change when printed.
+func marshalWithoutLines(t *testing.T, syntax w3c.Syntax) string {
+	t.Helper()
+	rules := make([]w3c.Rule, 0, len(syntax.Rules))
+	for _, rule := range syntax.Rules {
+		rule.Line = 0
+		rules = append(rules, rule)
+	}
+	syntax.Rules = rules
+	marshalled, err := json.Marshal(syntax)
+	if err != nil {
+		t.Fatalf("Expected no error marshalling syntax but got %s.", err)
+	}
+
+	return string(marshalled)
+}
+
+func TestPrinterXML10(t *testing.T) {
+	t.Parallel()
+	content, err := os.ReadFile(filepath.Join("testdata", "corpus", "xml10.ebnf"))
+	if err != nil {
+		t.Fatalf("Expected no error reading grammar but got %s.", err)
+	}
+	syntax, err := w3c.New().Parse(string(content))
+	if err != nil {
+		t.Fatalf("Expected no error but got %s.", err)
+	}
+	printer := w3c.NewPrinter()
+	assertRoundTrip(t, printer, string(content))
+	printed := printer.Format(syntax)
+	if i := strings.IndexFunc(printed, func(char rune) bool { return char > '~' }); i >= 0 {
+		t.Errorf("Expected printed grammar to be ASCII but got %q at offset %d.", printed[i:], i)
+	}
+	for _, set := range []string{"[#xE000-#xFFFD]", "[#x300-#x36F]", "[#xC0-#xD6]"} {
+		if !strings.Contains(printed, set) {
+			t.Errorf("Expected printed grammar to contain %s. Printed:\n%s", set, printed)
+		}
+	}
+}
+
+func FuzzPrinterRoundTrip(f *testing.F) {
+	for _, seed := range []string{
+		"testRule ::= 'word'",
+		"testRule ::= ('one' | 'two')* - 'three' | (a - (b c)) d",
+		"testRule ::= [^#x20a-z] #x31 [1] [http://] ((a?)+)*",
+		"/* c */ [1] testRule ::= 'one' /* comment */ [ wfc: Constraint ]\nb ::= c /* d */",
+		"testRule ::= [http://www.w3.org/TR/xml/#NT-Char] - ('a' 'b')",
+		"a ::= [#xD800]",
+	} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, grammar string) {
+		if strings.ContainsRune(grammar, '\x00') {
+			return
+		}
+		assertRoundTrip(t, w3c.NewPrinter(w3c.WithLineWidth(10)), grammar)
+	})
+}
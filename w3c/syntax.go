@@ -1,17 +1,46 @@
 package w3c
 
-import "encoding/json"
+import (
+	"encoding/json"
+
+	"github.com/alec-w/ebnf-go/cst"
+	"github.com/alec-w/ebnf-go/source"
+)
 
 // Syntax is a top level EBNF grammar.
 type Syntax struct {
-	Rules []Rule `json:"rules"`
+	Rules            []Rule   `json:"rules"`
+	TrailingComments []string `json:"trailingComments,omitempty"`
+	// CST is the concrete syntax tree of the grammar, which is only built by a Parser configured WithCST.
+	CST *cst.Node `json:"-"`
 }
 
 // Rule is a single rule from an EBNF grammar.
 type Rule struct {
-	Line       int        `json:"line"`
-	Symbol     string     `json:"symbol"`
-	Expression Expression `json:"expression"`
+	Line        int          `json:"line"`
+	Comments    []string     `json:"comments,omitempty"`
+	Number      string       `json:"number,omitempty"`
+	Symbol      string       `json:"symbol"`
+	Expression  Expression   `json:"expression"`
+	Constraints []Constraint `json:"constraints,omitempty"`
+	Span        source.Span  `json:"-"`
+}
+
+// ConstraintKind is the kind of constraint annotating a rule.
+type ConstraintKind string
+
+const (
+	// WellFormednessConstraint is a constraint annotated with [ wfc: ... ].
+	WellFormednessConstraint ConstraintKind = "wfc"
+	// ValidityConstraint is a constraint annotated with [ vc: ... ].
+	ValidityConstraint ConstraintKind = "vc"
+)
+
+// Constraint is a well-formedness or validity constraint annotating a rule, as used by XML family specifications.
+type Constraint struct {
+	Kind ConstraintKind `json:"kind"`
+	Name string         `json:"name"`
+	Span source.Span    `json:"-"`
 }
 
 // Expression is fulfilled by every expression type.
@@ -22,23 +51,33 @@ type Expression interface {
 	SymbolExpression() *SymbolExpression
 	CharacterSetExpression() *CharacterSetExpression
 	LiteralExpression() *LiteralExpression
+	ExternalReferenceExpression() *ExternalReferenceExpression
 	Optional() bool
 	OneOrMore() bool
 	ZeroOrMore() bool
+	Span() source.Span
+	setSpan(span source.Span)
 	setOptional(optional bool)
 	setOneOrMore(oneOrMore bool)
 	setZeroOrMore(zeroOrMore bool)
 	isParenthesised() bool
 	setParenthesised(parenthesised bool)
 	hasRepetitions() bool
+	// Repeats returns the repetitions of the expression in the order they were written, which is only recorded when it
+	// is parsed.
+	Repeats() []Repetition
+	addRepeat(repeat Repetition)
 }
 
-// baseExpression is used to give every expression the option of being parenthesised.
+// baseExpression is used to give every expression the option of being parenthesised and a span of the source it was
+// parsed from.
 //
 // Whether an expression is parenthesised does not need to be exposed externally, this is only used when parsing a
 // grammar.
 type baseExpression struct {
 	parenthesised bool
+	span          source.Span
+	repeats       []Repetition
 }
 
 // Repetitions records whether an expression is repeated and in what fashion, a maximum of one field in this struct
@@ -69,6 +108,15 @@ func repetitionsEmpty(r Repetitions) bool {
 	return !r.Optional && !r.OneOrMore && !r.ZeroOrMore
 }
 
+// Repetition is a repetition symbol applied to an expression, e.g. the ? or the * of "(b?)*".
+type Repetition struct {
+	// Symbol is the repetition symbol, one of ?, * and +.
+	Symbol rune
+	// Operand is the span of the expression the symbol repeats, and Span is the span of the operand and the symbol.
+	Operand source.Span
+	Span    source.Span
+}
+
 // ListExpression fulfils the Expression interface.
 func (b *baseExpression) ListExpression() *ListExpression {
 	return nil
@@ -99,6 +147,23 @@ func (b *baseExpression) LiteralExpression() *LiteralExpression {
 	return nil
 }
 
+// ExternalReferenceExpression fulfils the Expression interface.
+func (b *baseExpression) ExternalReferenceExpression() *ExternalReferenceExpression {
+	return nil
+}
+
+// Span fulfils the Expression interface.
+//
+// The span of a parenthesised expression includes its parentheses, and the span of an expression with repetitions
+// includes its repetition symbol.
+func (b *baseExpression) Span() source.Span {
+	return b.span
+}
+
+func (b *baseExpression) setSpan(span source.Span) {
+	b.span = span
+}
+
 func (b *baseExpression) isParenthesised() bool {
 	return b.parenthesised
 }
@@ -107,6 +172,15 @@ func (b *baseExpression) setParenthesised(parenthesised bool) {
 	b.parenthesised = parenthesised
 }
 
+// Repeats fulfils the Expression interface.
+func (b *baseExpression) Repeats() []Repetition {
+	return b.repeats
+}
+
+func (b *baseExpression) addRepeat(repeat Repetition) {
+	b.repeats = append(b.repeats, repeat)
+}
+
 var _ Expression = &ListExpression{}
 
 // ListExpression represents a list of expressions concatenated together to form a larger expression.
@@ -117,6 +191,13 @@ type ListExpression struct {
 	Expressions []Expression
 }
 
+func newListExpression(expressions []Expression) *ListExpression {
+	list := &ListExpression{Expressions: expressions}
+	list.setSpan(spanExpressions(expressions))
+
+	return list
+}
+
 // Optional fulfils the Expression interface.
 func (l *ListExpression) Optional() bool {
 	return l.Repetitions.Optional
@@ -170,6 +251,13 @@ type AlternateExpression struct {
 	Expressions []Expression
 }
 
+func newAlternateExpression(expressions []Expression) *AlternateExpression {
+	alternate := &AlternateExpression{Expressions: expressions}
+	alternate.setSpan(spanExpressions(expressions))
+
+	return alternate
+}
+
 // Optional fulfils the Expression interface.
 func (a *AlternateExpression) Optional() bool {
 	return a.Repetitions.Optional
@@ -339,8 +427,48 @@ func (l *LiteralExpression) LiteralExpression() *LiteralExpression {
 	return l
 }
 
+var _ Expression = &ExternalReferenceExpression{}
+
+// ExternalReferenceExpression represents an expression that references a production defined in another specification,
+// written as the URI of the production wrapped in [...], e.g. [http://www.w3.org/TR/REC-xml#NT-Char].
+type ExternalReferenceExpression struct {
+	baseExpression
+	Repetitions
+
+	URI string `json:"externalReference"`
+}
+
+// Optional fulfils the Expression interface.
+func (e *ExternalReferenceExpression) Optional() bool {
+	return e.Repetitions.Optional
+}
+
+// OneOrMore fulfils the Expression interface.
+func (e *ExternalReferenceExpression) OneOrMore() bool {
+	return e.Repetitions.OneOrMore
+}
+
+// ZeroOrMore fulfils the Expression interface.
+func (e *ExternalReferenceExpression) ZeroOrMore() bool {
+	return e.Repetitions.ZeroOrMore
+}
+
+// ExternalReferenceExpression exposes the underlying ExternalReferenceExpression.
+func (e *ExternalReferenceExpression) ExternalReferenceExpression() *ExternalReferenceExpression {
+	return e
+}
+
 // Range represents a UTF8 character range.
 type Range struct {
 	Low  rune `json:"low"`
 	High rune `json:"high"`
 }
+
+// spanExpressions returns the span covering the given expressions.
+func spanExpressions(expressions []Expression) source.Span {
+	if len(expressions) == 0 {
+		return source.Span{}
+	}
+
+	return source.Span{Start: expressions[0].Span().Start, End: expressions[len(expressions)-1].Span().End}
+}
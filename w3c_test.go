@@ -0,0 +1,141 @@
+package ebnf_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/alec-w/ebnf-go"
+	"github.com/alec-w/ebnf-go/w3c"
+)
+
+func TestFromW3C(t *testing.T) {
+	t.Parallel()
+	testCases := map[string]struct {
+		grammar  string
+		expected string
+	}{
+		"literal":   {grammar: "a ::= 'a'", expected: `{"literal":"a"}`},
+		"reference": {grammar: "a ::= b", expected: `{"reference":{"name":"b"}}`},
+		"external reference": {
+			grammar:  "a ::= [http://www.w3.org/TR/xml/#NT-Char]",
+			expected: `{"reference":{"uri":"http://www.w3.org/TR/xml/#NT-Char"}}`,
+		},
+		"sequence": {grammar: "a ::= 'a' b", expected: `{"sequence":[{"literal":"a"},{"reference":{"name":"b"}}]}`},
+		"choice": {
+			grammar:  "a ::= 'a' | 'b' c",
+			expected: `{"choice":[{"literal":"a"},{"sequence":[{"literal":"b"},{"reference":{"name":"c"}}]}]}`,
+		},
+		"optional": {grammar: "a ::= 'a'?", expected: `{"optional":{"literal":"a"}}`},
+		"star":     {grammar: "a ::= ('a' | 'b')*", expected: `{"star":{"choice":[{"literal":"a"},{"literal":"b"}]}}`},
+		"plus":     {grammar: "a ::= b+", expected: `{"plus":{"reference":{"name":"b"}}}`},
+		"stacked repetitions": {
+			grammar:  "a ::= (b?)*",
+			expected: `{"star":{"optional":{"reference":{"name":"b"}}}}`,
+		},
+		"stacked repetitions of a sequence": {
+			grammar:  "a ::= ((b c)?)+",
+			expected: `{"plus":{"optional":{"sequence":[{"reference":{"name":"b"}},{"reference":{"name":"c"}}]}}}`,
+		},
+		"every repetition": {
+			grammar:  "a ::= ((b+)*)?",
+			expected: `{"optional":{"star":{"plus":{"reference":{"name":"b"}}}}}`,
+		},
+		"repeated repetition": {
+			grammar:  "a ::= (b?)?",
+			expected: `{"optional":{"optional":{"reference":{"name":"b"}}}}`,
+		},
+		"exception": {
+			grammar:  "a ::= b - 'c'",
+			expected: `{"exception":{"match":{"reference":{"name":"b"}},"except":{"literal":"c"}}}`,
+		},
+		"character set": {
+			grammar:  "a ::= [a-z_]",
+			expected: `{"charClass":{"chars":[95],"ranges":[{"low":97,"high":122}]}}`,
+		},
+		"forbidden character set": {
+			grammar:  "a ::= [^#x0A]",
+			expected: `{"charClass":{"chars":[10],"negated":true}}`,
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			syntax, err := w3c.New().Parse(tc.grammar)
+			if err != nil {
+				t.Fatalf("Expected no error but got %s.", err)
+			}
+			grammar := ebnf.FromW3C(syntax)
+			actual, err := json.Marshal(grammar.Rules[0].Expression)
+			if err != nil {
+				t.Fatalf("Expected no error marshalling expression but got %s.", err)
+			}
+			if string(actual) != tc.expected {
+				t.Errorf("Expected expression %s. Got %s.", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestFromW3CSpans(t *testing.T) {
+	t.Parallel()
+	grammar := "a ::= 'a'\nb ::= (a | 'c')+ [a-z] - d (e?)*"
+	syntax, err := w3c.New().Parse(grammar)
+	if err != nil {
+		t.Fatalf("Expected no error but got %s.", err)
+	}
+	rule := ebnf.FromW3C(syntax).Rules[1]
+	if rule.Span != syntax.Rules[1].Span {
+		t.Errorf("Expected rule span %#v. Got %#v.", syntax.Rules[1].Span, rule.Span)
+	}
+	sequence, ok := rule.Expression.(*ebnf.Sequence)
+	if !ok {
+		t.Fatalf("Expected a sequence. Got %T.", rule.Expression)
+	}
+	plus, ok := sequence.Items[0].(*ebnf.Plus)
+	if !ok {
+		t.Fatalf("Expected a plus. Got %T.", sequence.Items[0])
+	}
+	testCases := map[string]struct {
+		expression ebnf.Expression
+		expected   string
+	}{
+		"sequence":   {expression: sequence, expected: "(a | 'c')+ [a-z] - d (e?)*"},
+		"plus":       {expression: plus, expected: "(a | 'c')+"},
+		"choice":     {expression: plus.Expression, expected: "(a | 'c')"},
+		"alternate":  {expression: plus.Expression.(*ebnf.Choice).Alternatives[1], expected: "'c'"},
+		"exception":  {expression: sequence.Items[1], expected: "[a-z] - d"},
+		"char class": {expression: sequence.Items[1].(*ebnf.Exception).Match, expected: "[a-z]"},
+		"star":       {expression: sequence.Items[2], expected: "(e?)*"},
+		"optional":   {expression: sequence.Items[2].(*ebnf.Star).Expression, expected: "e?"},
+		"reference": {
+			expression: sequence.Items[2].(*ebnf.Star).Expression.(*ebnf.Optional).Expression,
+			expected:   "e",
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			span := tc.expression.Span()
+			if actual := grammar[span.Start.Offset:span.End.Offset]; actual != tc.expected {
+				t.Errorf("Expected span of %q. Got %q.", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestFromW3CRule(t *testing.T) {
+	t.Parallel()
+	syntax, err := w3c.New().Parse("/* A. */ [1] a ::= b [ wfc: Legal ] [ vc: Valid ]")
+	if err != nil {
+		t.Fatalf("Expected no error but got %s.", err)
+	}
+	actual, err := json.Marshal(ebnf.FromW3C(syntax).Rules[0])
+	if err != nil {
+		t.Fatalf("Expected no error marshalling rule but got %s.", err)
+	}
+	expected := `{"number":"1","name":"a","comments":["A."],"expression":{"reference":{"name":"b"}},` +
+		`"constraints":[{"kind":"wfc","name":"Legal"},{"kind":"vc","name":"Valid"}]}`
+	if string(actual) != expected {
+		t.Errorf("Expected rule %s. Got %s.", expected, actual)
+	}
+}